@@ -32,6 +32,7 @@ type ABI struct {
 	Constructor Method
 	Methods     map[string]Method
 	Events      map[string]Event
+	Errors      map[string]Error
 }
 
 // JSON returns a parsed ABI interface and error if it failed.
@@ -126,6 +127,7 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 	}
 	abi.Methods = make(map[string]Method)
 	abi.Events = make(map[string]Event)
+	abi.Errors = make(map[string]Error)
 	for _, field := range fields {
 		switch field.Type {
 		case "constructor":
@@ -160,6 +162,18 @@ func (abi *ABI) UnmarshalJSON(data []byte) error {
 				Anonymous: field.Anonymous,
 				Inputs:    field.Inputs,
 			}
+		case "error":
+			name := field.Name
+			_, ok := abi.Errors[name]
+			for idx := 0; ok; idx++ {
+				name = fmt.Sprintf("%s%d", field.Name, idx)
+				_, ok = abi.Errors[name]
+			}
+			abi.Errors[name] = Error{
+				Name:    name,
+				RawName: field.Name,
+				Inputs:  field.Inputs,
+			}
 		}
 	}
 
@@ -180,6 +194,35 @@ func (abi *ABI) MethodById(sigdata []byte) (*Method, error) {
 	return nil, fmt.Errorf("no method with id: %#x", sigdata[:4])
 }
 
+// ErrorByID looks a custom error up by the selector prefixing its revert data.
+// returns nil if none found
+func (abi *ABI) ErrorByID(sigdata []byte) (*Error, error) {
+	if len(sigdata) < 4 {
+		return nil, fmt.Errorf("data too short (%d bytes) for abi error lookup", len(sigdata))
+	}
+	for _, e := range abi.Errors {
+		if bytes.Equal(e.ID(), sigdata[:4]) {
+			return &e, nil
+		}
+	}
+	return nil, fmt.Errorf("no error with id: %#x", sigdata[:4])
+}
+
+// UnpackError unpacks the revert data raising the named custom error into v.
+func (abi ABI) UnpackError(v interface{}, name string, data []byte) error {
+	e, ok := abi.Errors[name]
+	if !ok {
+		return fmt.Errorf("abi: could not locate named error")
+	}
+	if len(data) < 4 || !bytes.Equal(data[:4], e.ID()) {
+		return fmt.Errorf("abi: revert data does not match error %s", e.Sig())
+	}
+	if len(e.Inputs) == 0 {
+		return nil
+	}
+	return e.Inputs.Unpack(v, data[4:])
+}
+
 // EventByID looks an event up by its topic hash in the
 // ABI and returns nil if none found.
 func (abi *ABI) EventByID(topic common.Hash) (*Event, error) {
@@ -1010,6 +1010,73 @@ func TestABI_EventById(t *testing.T) {
 	}
 }
 
+func TestABI_ErrorByID(t *testing.T) {
+	const abiJSON = `[
+		{"type":"error","name":"Unauthorized","inputs":[{"name":"caller","type":"address"},{"name":"code","type":"uint256"}]},
+		{"type":"error","name":"Unauthorized","inputs":[]},
+		{"type":"error","name":"Paused","inputs":[]}
+	]`
+	abi, err := JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(abi.Errors) != 3 {
+		t.Fatalf("error count mismatch: have %d, want 3", len(abi.Errors))
+	}
+	for name, e := range abi.Errors {
+		if e.Name != name {
+			t.Errorf("error %s: name mismatch: have %s", name, e.Name)
+		}
+		found, err := abi.ErrorByID(crypto.Keccak256([]byte(e.Sig())))
+		if err != nil {
+			t.Fatalf("error %s: failed to look up by id: %v", name, err)
+		}
+		if found.Name != name {
+			t.Errorf("error %s: id resolved to %s", name, found.Name)
+		}
+	}
+	if e := abi.Errors["Unauthorized0"]; e.RawName != "Unauthorized" || e.Sig() != "Unauthorized()" {
+		t.Errorf("overloaded error mismatch: have %s (%s)", e.RawName, e.Sig())
+	}
+	if _, err := abi.ErrorByID(crypto.Keccak256([]byte("Unknown()"))); err == nil {
+		t.Errorf("ErrorByID should return an error if the selector is not found")
+	}
+	if _, err := abi.ErrorByID([]byte{0x01}); err == nil {
+		t.Errorf("ErrorByID should return an error on short data")
+	}
+}
+
+func TestUnpackError(t *testing.T) {
+	const abiJSON = `[
+		{"type":"error","name":"Unauthorized","inputs":[{"name":"caller","type":"address"},{"name":"code","type":"uint256"}]}
+	]`
+	abi, err := JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	data, err := abi.Errors["Unauthorized"].Inputs.Pack(caller, big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(common.FromHex("0xda472023"), data...)
+
+	var out struct {
+		Caller common.Address
+		Code   *big.Int
+	}
+	if err := abi.UnpackError(&out, "Unauthorized", data); err != nil {
+		t.Fatalf("failed to unpack error: %v", err)
+	}
+	if out.Caller != caller || out.Code.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("unpacked error mismatch: have %x %v", out.Caller, out.Code)
+	}
+	data[0] ^= 0xff
+	if err := abi.UnpackError(&out, "Unauthorized", data); err == nil {
+		t.Errorf("UnpackError should fail on mismatching selector")
+	}
+}
+
 func TestDuplicateMethodNames(t *testing.T) {
 	abiJSON := `[{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"name":"transfer","outputs":[{"name":"ok","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"},{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"name":"transfer","outputs":[{"name":"ok","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"},{"constant":false,"inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"customFallback","type":"string"}],"name":"transfer","outputs":[{"name":"ok","type":"bool"}],"payable":false,"stateMutability":"nonpayable","type":"function"}]`
 	contractAbi, err := JSON(strings.NewReader(abiJSON))
@@ -39,6 +39,10 @@ var (
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
+
+	// This error is raised when paginated log filtering needs the chain head from
+	// a backend that doesn't implement HeaderReader.
+	ErrNoHeaderReader = errors.New("backend does not support header retrieval")
 )
 
 // ContractCaller defines the methods needed to allow operating with contract on a read
@@ -98,6 +102,15 @@ type ContractFilterer interface {
 	SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
 }
 
+// HeaderReader defines the methods needed to retrieve the chain head. Paginated
+// log filtering will try to discover this interface on the ContractFilterer to
+// find the end of ranges which are open to the latest block.
+type HeaderReader interface {
+	// HeaderByNumber returns a block header from the current canonical chain. If
+	// number is nil, the latest known header is returned.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
 // DeployBackend wraps the operations needed by WaitMined and WaitDeployed.
 type DeployBackend interface {
 	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
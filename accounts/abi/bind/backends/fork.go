@@ -0,0 +1,510 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package backends
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/core/rawdb"
+	"github.com/Fantom-foundation/go-ethereum/core/state"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/ethdb"
+	"github.com/Fantom-foundation/go-ethereum/params"
+	"github.com/Fantom-foundation/go-ethereum/rlp"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+	"github.com/Fantom-foundation/go-ethereum/trie"
+)
+
+const (
+	// forkFetchTimeout is the maximum time allowed for a single state retrieval
+	// from the remote node.
+	forkFetchTimeout = 30 * time.Second
+
+	// forkMaxSearchDepth is the maximum depth of a trie node that is retrieved by
+	// searching for a key leading to it. Such searches are needed when a deletion
+	// collapses a branch into a sibling that was never accessed, the cost grows
+	// 16-fold with every level.
+	forkMaxSearchDepth = 6
+
+	// forkAncestors is the number of headers preceding the forked block that are
+	// retrieved, as many as the BLOCKHASH opcode can reach.
+	forkAncestors = 256
+)
+
+// NewForkedBackend creates a simulated backend whose state is forked from the
+// given block of a remote chain, or its latest block if blockNumber is nil.
+//
+// The remote state is not copied upfront. Accounts, storage slots and code are
+// retrieved on first access via eth_getProof and eth_getCode, so the remote node
+// must be able to serve state at the requested block. Retrieved trie nodes are
+// stored by hash, so only those matching the forked state root are ever used.
+//
+// The simulated chain continues the remote one from the forked block, so block
+// numbers, timestamps and the hashes of the recent blocks match the remote chain.
+// Only the headers of the recent blocks and the genesis are retrieved, the rest
+// of the remote history is not accessible. A gasLimit other than zero overrides
+// the one of the forked block, which changes its hash.
+func NewForkedBackend(client *rpc.Client, blockNumber *big.Int, gasLimit uint64) (*SimulatedBackend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), forkFetchTimeout)
+	defer cancel()
+
+	number := "latest"
+	if blockNumber != nil {
+		number = hexutil.EncodeBig(blockNumber)
+	}
+	var head *types.Header
+	if err := client.CallContext(ctx, &head, "eth_getBlockByNumber", number, false); err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("fork block %s not found", number)
+	}
+	database := rawdb.NewMemoryDatabase()
+	source := &forkSource{
+		client: client,
+		block:  hexutil.EncodeBig(head.Number),
+		db:     database,
+	}
+	// Make the state root available before the chain opens it
+	if err := source.fetchAccount(common.Address{}); err != nil {
+		return nil, err
+	}
+	ancestors, err := fetchAncestors(ctx, client, head)
+	if err != nil {
+		return nil, err
+	}
+	if gasLimit != 0 && gasLimit != head.GasLimit {
+		head = types.CopyHeader(head)
+		head.GasLimit = gasLimit
+	}
+	// Assemble the local chain from the retrieved headers. Transactions and
+	// receipts of the remote blocks are not retrieved.
+	genesis := head
+	if len(ancestors) > 0 {
+		genesis = ancestors[0]
+	}
+	for _, header := range append(ancestors, head) {
+		hash, number := header.Hash(), header.Number.Uint64()
+		rawdb.WriteHeader(database, header)
+		rawdb.WriteBody(database, hash, number, new(types.Body))
+		rawdb.WriteReceipts(database, hash, number, nil)
+		rawdb.WriteTd(database, hash, number, header.Difficulty)
+		rawdb.WriteCanonicalHash(database, hash, number)
+	}
+	config := params.AllEthashProtocolChanges
+
+	rawdb.WriteHeadBlockHash(database, head.Hash())
+	rawdb.WriteHeadFastBlockHash(database, head.Hash())
+	rawdb.WriteHeadHeaderHash(database, head.Hash())
+	rawdb.WriteChainConfig(database, genesis.Hash(), config)
+
+	return newSimulatedBackend(database, config, source), nil
+}
+
+// fetchAncestors retrieves the genesis header of the remote chain and the ones
+// preceding the forked block, in ascending order. The recent headers are verified
+// to link up to the forked block.
+func fetchAncestors(ctx context.Context, client *rpc.Client, head *types.Header) ([]*types.Header, error) {
+	var (
+		last  = head.Number.Uint64()
+		first uint64
+	)
+	if last > forkAncestors {
+		first = last - forkAncestors
+	}
+	var numbers []uint64
+	if first > 0 {
+		numbers = append(numbers, 0)
+	}
+	for number := first; number < last; number++ {
+		numbers = append(numbers, number)
+	}
+	headers := make([]*types.Header, len(numbers))
+	batch := make([]rpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{hexutil.EncodeUint64(number), false},
+			Result: &headers[i],
+		}
+	}
+	if len(batch) > 0 {
+		if err := client.BatchCallContext(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("failed to retrieve block %d: %v", numbers[i], elem.Error)
+		}
+		if headers[i] == nil || headers[i].Number.Uint64() != numbers[i] {
+			return nil, fmt.Errorf("block %d not found", numbers[i])
+		}
+	}
+	// Check the recent headers against the forked block, the genesis can't be
+	child := head
+	for i := len(headers) - 1; i >= 0 && numbers[i] >= first; i-- {
+		if headers[i].Hash() != child.ParentHash {
+			return nil, fmt.Errorf("block %d doesn't link to the forked block", numbers[i])
+		}
+		child = headers[i]
+	}
+	return headers, nil
+}
+
+// forkSource retrieves the state of the forked block from the remote node,
+// storing the retrieved trie nodes and code into the local database.
+type forkSource struct {
+	client *rpc.Client
+	block  string // Hex number of the forked block
+	db     ethdb.Database
+
+	lock      sync.Mutex
+	addresses map[common.Hash]common.Address // Accounts accessed so far, keyed by hash
+	err       error                          // First retrieval failure not reported yet
+}
+
+// accountProof is the subset of an eth_getProof response used by the fork.
+type accountProof struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	StorageProof []struct {
+		Proof []hexutil.Bytes `json:"proof"`
+	} `json:"storageProof"`
+}
+
+// fetchAccount retrieves the trie nodes leading to an account.
+func (s *forkSource) fetchAccount(addr common.Address) error {
+	return s.fetchProof(addr, nil)
+}
+
+// fetchStorage retrieves the trie nodes leading to the account and the given
+// storage slot of it.
+func (s *forkSource) fetchStorage(addr common.Address, key common.Hash) error {
+	return s.fetchProof(addr, []common.Hash{key})
+}
+
+// fetchProof retrieves the proof of an account and some of its storage slots,
+// storing all the contained trie nodes.
+func (s *forkSource) fetchProof(addr common.Address, keys []common.Hash) error {
+	ctx, cancel := context.WithTimeout(context.Background(), forkFetchTimeout)
+	defer cancel()
+
+	if keys == nil {
+		keys = []common.Hash{}
+	}
+	var proof accountProof
+	if err := s.client.CallContext(ctx, &proof, "eth_getProof", addr, keys, s.block); err != nil {
+		return s.fail(fmt.Errorf("failed to retrieve state of %x: %v", addr, err))
+	}
+	batch := s.db.NewBatch()
+	for _, node := range proof.AccountProof {
+		batch.Put(crypto.Keccak256(node), node)
+	}
+	for _, storage := range proof.StorageProof {
+		for _, node := range storage.Proof {
+			batch.Put(crypto.Keccak256(node), node)
+		}
+	}
+	return s.fail(batch.Write())
+}
+
+// fetchCode retrieves the code of an account, verifying it against its hash.
+func (s *forkSource) fetchCode(addr common.Address, codeHash common.Hash) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), forkFetchTimeout)
+	defer cancel()
+
+	var code hexutil.Bytes
+	if err := s.client.CallContext(ctx, &code, "eth_getCode", addr, s.block); err != nil {
+		return nil, s.fail(fmt.Errorf("failed to retrieve code of %x: %v", addr, err))
+	}
+	if crypto.Keccak256Hash(code) != codeHash {
+		return nil, s.fail(fmt.Errorf("code hash mismatch for %x", addr))
+	}
+	if err := s.db.Put(codeHash[:], code); err != nil {
+		return nil, s.fail(err)
+	}
+	return code, nil
+}
+
+// fail records a retrieval failure, so it can be reported even if the state
+// database swallows it. The error is returned as is.
+func (s *forkSource) fail(err error) error {
+	if err != nil {
+		s.lock.Lock()
+		if s.err == nil {
+			s.err = err
+		}
+		s.lock.Unlock()
+	}
+	return err
+}
+
+// takeError returns and clears the first retrieval failure since the last call.
+func (s *forkSource) takeError() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// track remembers the address of an accessed account, so its storage and code
+// can be retrieved later on.
+func (s *forkSource) track(addr common.Address) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.addresses == nil {
+		s.addresses = make(map[common.Hash]common.Address)
+	}
+	s.addresses[crypto.Keccak256Hash(addr[:])] = addr
+}
+
+// address returns the address of a previously accessed account.
+func (s *forkSource) address(addrHash common.Hash) (common.Address, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	addr, ok := s.addresses[addrHash]
+	return addr, ok
+}
+
+// searchKey looks for a key whose hash starts with the given nibble path, so that
+// its proof contains the trie node at that path.
+func searchKey(path []byte, size int) ([]byte, error) {
+	if len(path) > forkMaxSearchDepth {
+		return nil, fmt.Errorf("can't retrieve trie node at depth %d", len(path))
+	}
+	key := make([]byte, size)
+	for i := uint64(0); ; i++ {
+		binary.BigEndian.PutUint64(key[size-8:], i)
+		if hasNibblePrefix(crypto.Keccak256(key), path) {
+			return key, nil
+		}
+	}
+}
+
+// hasNibblePrefix reports whether the nibbles of the hash start with the path.
+func hasNibblePrefix(hash []byte, path []byte) bool {
+	for i, nibble := range path {
+		have := hash[i/2] >> 4
+		if i%2 == 1 {
+			have = hash[i/2] & 0x0f
+		}
+		if have != nibble {
+			return false
+		}
+	}
+	return true
+}
+
+// forkDatabase is a state.Database retrieving the missing parts of the state from
+// the remote node of a forked chain.
+type forkDatabase struct {
+	state.Database
+	source *forkSource
+}
+
+// OpenTrie implements state.Database, opening an account trie retrieving missing
+// nodes on demand.
+func (db *forkDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := db.Database.OpenTrie(root)
+	if _, ok := err.(*trie.MissingNodeError); ok {
+		if err = db.source.fetchAccount(common.Address{}); err == nil {
+			tr, err = db.Database.OpenTrie(root)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, source: db.source}, nil
+}
+
+// OpenStorageTrie implements state.Database, opening a storage trie retrieving
+// missing nodes on demand.
+func (db *forkDatabase) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	addr, ok := db.source.address(addrHash)
+	if !ok {
+		return db.Database.OpenStorageTrie(addrHash, root)
+	}
+	tr, err := db.Database.OpenStorageTrie(addrHash, root)
+	if _, ok := err.(*trie.MissingNodeError); ok {
+		if err = db.source.fetchStorage(addr, common.Hash{}); err == nil {
+			tr, err = db.Database.OpenStorageTrie(addrHash, root)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &forkTrie{Trie: tr, source: db.source, account: &addr}, nil
+}
+
+// CopyTrie implements state.Database, copying the wrapped trie.
+func (db *forkDatabase) CopyTrie(t state.Trie) state.Trie {
+	if t, ok := t.(*forkTrie); ok {
+		return &forkTrie{Trie: db.Database.CopyTrie(t.Trie), source: t.source, account: t.account}
+	}
+	return db.Database.CopyTrie(t)
+}
+
+// ContractCode implements state.Database, retrieving missing code from the
+// remote node.
+func (db *forkDatabase) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.Database.ContractCode(addrHash, codeHash)
+	if err == nil {
+		return code, nil
+	}
+	addr, ok := db.source.address(addrHash)
+	if !ok {
+		return nil, err
+	}
+	return db.source.fetchCode(addr, codeHash)
+}
+
+// ContractCodeSize implements state.Database, retrieving missing code from the
+// remote node.
+func (db *forkDatabase) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// forkTrie is an account or storage trie retrieving its missing nodes from the
+// remote node of a forked chain.
+type forkTrie struct {
+	state.Trie
+	source  *forkSource
+	account *common.Address // Owner of the storage trie, nil for the account trie
+}
+
+// TryGet implements state.Trie, retrieving the proof of the key if the path to
+// it is missing.
+func (t *forkTrie) TryGet(key []byte) ([]byte, error) {
+	var value []byte
+	err := t.retry(key, func() (err error) {
+		value, err = t.Trie.TryGet(key)
+		return err
+	})
+	return value, err
+}
+
+// TryUpdate implements state.Trie, retrieving the proof of the key if the path
+// to it is missing.
+func (t *forkTrie) TryUpdate(key, value []byte) error {
+	return t.retry(key, func() error { return t.Trie.TryUpdate(key, value) })
+}
+
+// TryDelete implements state.Trie, retrieving the proof of the key if the path
+// to it is missing.
+func (t *forkTrie) TryDelete(key []byte) error {
+	return t.retry(key, func() error { return t.Trie.TryDelete(key) })
+}
+
+// retry runs a trie operation, retrieving the missing nodes it runs into. The
+// path to the key itself is retrieved first. A node still missing afterwards is
+// the sibling a deletion collapses a branch into, retrieved via a key leading
+// to it.
+func (t *forkTrie) retry(key []byte, op func() error) error {
+	if t.account == nil {
+		t.source.track(common.BytesToAddress(key))
+	}
+	for attempt := 0; ; attempt++ {
+		err := op()
+		missing, ok := err.(*trie.MissingNodeError)
+		if !ok || attempt == 2 {
+			return err
+		}
+		target := key
+		if attempt == 1 {
+			if target, err = t.siblingKey(key, missing); err != nil {
+				return t.source.fail(err)
+			}
+		}
+		if err := t.fetch(target); err != nil {
+			return err
+		}
+	}
+}
+
+// siblingKey finds a key leading to a missing node referenced by a branch on the
+// path to the given key.
+func (t *forkTrie) siblingKey(key []byte, missing *trie.MissingNodeError) ([]byte, error) {
+	proof := new(proofList)
+	if err := t.Trie.Prove(key, 0, proof); err != nil {
+		return nil, err
+	}
+	for _, node := range *proof {
+		if index := childIndex(node, missing.NodeHash); index >= 0 {
+			size := common.HashLength
+			if t.account == nil {
+				size = common.AddressLength
+			}
+			path := append(append([]byte{}, missing.Path...), byte(index))
+			return searchKey(path, size)
+		}
+	}
+	return nil, missing
+}
+
+// childIndex returns the position of a child in an encoded branch node, or -1 if
+// the node is not a branch referencing the child.
+func childIndex(node []byte, child common.Hash) int {
+	elems, _, err := rlp.SplitList(node)
+	if err != nil {
+		return -1
+	}
+	for i := 0; i < 16 && len(elems) > 0; i++ {
+		kind, content, rest, err := rlp.Split(elems)
+		if err != nil {
+			return -1
+		}
+		if kind == rlp.String && bytes.Equal(content, child[:]) {
+			return i
+		}
+		elems = rest
+	}
+	return -1
+}
+
+// proofList collects the nodes of a trie proof.
+type proofList [][]byte
+
+func (l *proofList) Put(key []byte, value []byte) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// fetch retrieves the proof of a key of the trie.
+func (t *forkTrie) fetch(key []byte) error {
+	if t.account == nil {
+		return t.source.fetchAccount(common.BytesToAddress(key))
+	}
+	return t.source.fetchStorage(*t.account, common.BytesToHash(key))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
 	"github.com/Fantom-foundation/go-ethereum/common"
 	"github.com/Fantom-foundation/go-ethereum/common/math"
+	"github.com/Fantom-foundation/go-ethereum/consensus"
 	"github.com/Fantom-foundation/go-ethereum/consensus/ethash"
 	"github.com/Fantom-foundation/go-ethereum/core"
 	"github.com/Fantom-foundation/go-ethereum/core/bloombits"
@@ -35,6 +36,7 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/core/state"
 	"github.com/Fantom-foundation/go-ethereum/core/types"
 	"github.com/Fantom-foundation/go-ethereum/core/vm"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
 	"github.com/Fantom-foundation/go-ethereum/eth/filters"
 	"github.com/Fantom-foundation/go-ethereum/ethdb"
 	"github.com/Fantom-foundation/go-ethereum/event"
@@ -55,11 +57,16 @@ var (
 type SimulatedBackend struct {
 	database   ethdb.Database   // In memory database to store our testing data
 	blockchain *core.BlockChain // Ethereum blockchain to handle the consensus
+	engine     *simEngine       // Consensus engine applying direct state modifications
+	fork       *forkDatabase    // State database retrieving forked state, nil if not forked
 
 	mu           sync.Mutex
 	pendingBlock *types.Block   // Currently pending block that will be imported on request
 	pendingState *state.StateDB // Currently pending state that will be the active on on request
 
+	snapshots    map[string]*types.Header       // Named checkpoints of the chain head
+	impersonated map[common.Hash]common.Address // Senders of impersonated transactions
+
 	events *filters.EventSystem // Event system for filtering log events live
 
 	config *params.ChainConfig
@@ -70,13 +77,26 @@ type SimulatedBackend struct {
 func NewSimulatedBackendWithDatabase(database ethdb.Database, alloc core.GenesisAlloc, gasLimit uint64) *SimulatedBackend {
 	genesis := core.Genesis{Config: params.AllEthashProtocolChanges, GasLimit: gasLimit, Alloc: alloc}
 	genesis.MustCommit(database)
-	blockchain, _ := core.NewBlockChain(database, nil, genesis.Config, ethash.NewFaker(), vm.Config{}, nil)
+	return newSimulatedBackend(database, genesis.Config, nil)
+}
+
+// newSimulatedBackend creates a binding backend on top of a database containing
+// an already committed genesis, optionally retrieving state from a forked chain.
+func newSimulatedBackend(database ethdb.Database, config *params.ChainConfig, source *forkSource) *SimulatedBackend {
+	engine := &simEngine{Engine: ethash.NewFaker()}
+	blockchain, _ := core.NewBlockChain(database, nil, config, engine, vm.Config{}, nil)
 
 	backend := &SimulatedBackend{
-		database:   database,
-		blockchain: blockchain,
-		config:     genesis.Config,
-		events:     filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		database:     database,
+		blockchain:   blockchain,
+		engine:       engine,
+		config:       config,
+		events:       filters.NewEventSystem(new(event.TypeMux), &filterBackend{database, blockchain}, false),
+		snapshots:    make(map[string]*types.Header),
+		impersonated: make(map[common.Hash]common.Address),
+	}
+	if source != nil {
+		backend.fork = &forkDatabase{Database: blockchain.StateCache(), source: source}
 	}
 	backend.rollback()
 	return backend
@@ -115,11 +135,33 @@ func (b *SimulatedBackend) Rollback() {
 }
 
 func (b *SimulatedBackend) rollback() {
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(int, *core.BlockGen) {})
-	statedb, _ := b.blockchain.State()
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.engine, b.database, 1, func(int, *core.BlockGen) {})
 
 	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.stateDatabase())
+}
+
+// stateDatabase returns the database to open states on, which retrieves missing
+// state from the remote chain if the backend is forked.
+func (b *SimulatedBackend) stateDatabase() state.Database {
+	if b.fork != nil {
+		return b.fork
+	}
+	return b.blockchain.StateCache()
+}
+
+// state returns the state of the current head of the chain.
+func (b *SimulatedBackend) state() (*state.StateDB, error) {
+	return state.New(b.blockchain.CurrentBlock().Root(), b.stateDatabase())
+}
+
+// stateError returns the failure to retrieve forked state, if any occurred since
+// the last call.
+func (b *SimulatedBackend) stateError() error {
+	if b.fork != nil {
+		return b.fork.source.takeError()
+	}
+	return nil
 }
 
 // CodeAt returns the code associated with a certain account in the blockchain.
@@ -130,8 +172,9 @@ func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address,
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetCode(contract), nil
+	statedb, _ := b.state()
+	code := statedb.GetCode(contract)
+	return code, b.stateError()
 }
 
 // BalanceAt returns the wei balance of a certain account in the blockchain.
@@ -142,8 +185,9 @@ func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Addres
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetBalance(contract), nil
+	statedb, _ := b.state()
+	balance := statedb.GetBalance(contract)
+	return balance, b.stateError()
 }
 
 // NonceAt returns the nonce of a certain account in the blockchain.
@@ -154,8 +198,9 @@ func (b *SimulatedBackend) NonceAt(ctx context.Context, contract common.Address,
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return 0, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
-	return statedb.GetNonce(contract), nil
+	statedb, _ := b.state()
+	nonce := statedb.GetNonce(contract)
+	return nonce, b.stateError()
 }
 
 // StorageAt returns the value of key in the storage of an account in the blockchain.
@@ -166,14 +211,28 @@ func (b *SimulatedBackend) StorageAt(ctx context.Context, contract common.Addres
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	statedb, _ := b.blockchain.State()
+	statedb, _ := b.state()
 	val := statedb.GetState(contract, key)
-	return val[:], nil
+	return val[:], b.stateError()
 }
 
 // TransactionReceipt returns the receipt of a transaction.
 func (b *SimulatedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	receipt, _, _, _ := rawdb.ReadReceipt(b.database, txHash, b.config)
+	if receipt == nil {
+		return nil, nil
+	}
+	// Contract addresses are derived from the transaction signature, which is
+	// fake for impersonated senders
+	b.mu.Lock()
+	sender, ok := b.impersonated[txHash]
+	b.mu.Unlock()
+
+	if ok {
+		if tx, _, _, _ := rawdb.ReadTransaction(b.database, txHash); tx != nil && tx.To() == nil {
+			receipt.ContractAddress = crypto.CreateAddress(sender, tx.Nonce())
+		}
+	}
 	return receipt, nil
 }
 
@@ -201,7 +260,8 @@ func (b *SimulatedBackend) PendingCodeAt(ctx context.Context, contract common.Ad
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	return b.pendingState.GetCode(contract), nil
+	code := b.pendingState.GetCode(contract)
+	return code, b.stateError()
 }
 
 // CallContract executes a contract call.
@@ -212,11 +272,14 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallM
 	if blockNumber != nil && blockNumber.Cmp(b.blockchain.CurrentBlock().Number()) != 0 {
 		return nil, errBlockNumberUnsupported
 	}
-	state, err := b.blockchain.State()
+	state, err := b.state()
 	if err != nil {
 		return nil, err
 	}
 	rval, _, _, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), state)
+	if err == nil {
+		err = b.stateError()
+	}
 	return rval, err
 }
 
@@ -227,6 +290,9 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call ethereu
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
 	rval, _, _, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	if err == nil {
+		err = b.stateError()
+	}
 	return rval, err
 }
 
@@ -236,7 +302,8 @@ func (b *SimulatedBackend) PendingNonceAt(ctx context.Context, account common.Ad
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	return b.pendingState.GetOrNewStateObject(account).Nonce(), nil
+	nonce := b.pendingState.GetOrNewStateObject(account).Nonce()
+	return nonce, b.stateError()
 }
 
 // SuggestGasPrice implements ContractTransactor.SuggestGasPrice. Since the simulated
@@ -289,10 +356,13 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
 		if !executable(hi) {
+			if err := b.stateError(); err != nil {
+				return 0, err
+			}
 			return 0, errGasEstimationFailed
 		}
 	}
-	return hi, nil
+	return hi, b.stateError()
 }
 
 // callContract implements common code between normal and pending contract calls.
@@ -337,20 +407,90 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 	if tx.Nonce() != nonce {
 		panic(fmt.Errorf("invalid transaction nonce: got %d, want %d", tx.Nonce(), nonce))
 	}
-
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	if b.fork != nil {
+		// Execute the transaction on a copy of the pending state first, retrieving
+		// all the forked state it touches before the chain has to process it
+		var (
+			statedb = b.pendingState.Copy()
+			header  = b.pendingBlock.Header()
+			gaspool = new(core.GasPool).AddGas(math.MaxUint64)
+			used    uint64
+		)
+		if _, err := core.ApplyTransaction(b.config, b.blockchain, nil, gaspool, statedb, header, tx, &used, vm.Config{}); err == nil {
+			statedb.IntermediateRoot(b.config.IsEIP158(header.Number))
+		}
+		if err := b.stateError(); err != nil {
+			return err
+		}
+	}
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.engine, b.database, 1, func(number int, block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTxWithChain(b.blockchain, tx)
 		}
 		block.AddTxWithChain(b.blockchain, tx)
 	})
-	statedb, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.stateDatabase())
 	return nil
 }
 
+// HeaderByHash returns the header of a block with the given hash from the
+// simulated blockchain.
+func (b *SimulatedBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	header := b.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+// HeaderByNumber returns the header of a canonical block from the simulated
+// blockchain. If number is nil, the latest known header is returned.
+func (b *SimulatedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if number == nil {
+		return b.blockchain.CurrentHeader(), nil
+	}
+	header := b.blockchain.GetHeaderByNumber(number.Uint64())
+	if header == nil {
+		return nil, ethereum.NotFound
+	}
+	return header, nil
+}
+
+// SubscribeNewHead returns an event subscription for a new header imported as
+// the head of the simulated blockchain.
+func (b *SimulatedBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	sink := make(chan *types.Header)
+	sub := b.events.SubscribeNewHeads(sink)
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case head := <-sink:
+				select {
+				case ch <- head:
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}), nil
+}
+
 // FilterLogs executes a log filter operation, blocking during execution and
 // returning all the results in one batch.
 //
@@ -423,25 +563,222 @@ func (b *SimulatedBackend) SubscribeFilterLogs(ctx context.Context, query ethere
 func (b *SimulatedBackend) AdjustTime(adjustment time.Duration) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), ethash.NewFaker(), b.database, 1, func(number int, block *core.BlockGen) {
+	blocks, _ := core.GenerateChain(b.config, b.blockchain.CurrentBlock(), b.engine, b.database, 1, func(number int, block *core.BlockGen) {
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTx(tx)
 		}
 		block.OffsetTime(int64(adjustment.Seconds()))
 	})
-	statedb, _ := b.blockchain.State()
 
 	b.pendingBlock = blocks[0]
-	b.pendingState, _ = state.New(b.pendingBlock.Root(), statedb.Database())
+	b.pendingState, _ = state.New(b.pendingBlock.Root(), b.stateDatabase())
+
+	return nil
+}
+
+// SetBalance sets the balance of an account directly. The change is committed in
+// a new block, along with any pending transactions executed before it.
+func (b *SimulatedBackend) SetBalance(account common.Address, balance *big.Int) error {
+	return b.modifyState(func(statedb *state.StateDB) {
+		statedb.SetBalance(account, balance)
+	})
+}
+
+// SetNonce sets the nonce of an account directly. The change is committed in a
+// new block, along with any pending transactions executed before it.
+func (b *SimulatedBackend) SetNonce(account common.Address, nonce uint64) error {
+	return b.modifyState(func(statedb *state.StateDB) {
+		statedb.SetNonce(account, nonce)
+	})
+}
+
+// SetCode sets the code of an account directly. The change is committed in a new
+// block, along with any pending transactions executed before it.
+func (b *SimulatedBackend) SetCode(account common.Address, code []byte) error {
+	return b.modifyState(func(statedb *state.StateDB) {
+		statedb.SetCode(account, code)
+	})
+}
+
+// SetStorage sets a storage slot of an account directly. The change is committed
+// in a new block, along with any pending transactions executed before it.
+func (b *SimulatedBackend) SetStorage(account common.Address, key, value common.Hash) error {
+	return b.modifyState(func(statedb *state.StateDB) {
+		statedb.SetState(account, key, value)
+	})
+}
 
+// modifyState commits the pending transactions in a new block, applying the given
+// state modification after them.
+func (b *SimulatedBackend) modifyState(modify func(*state.StateDB)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.fork != nil {
+		// Apply the modification on a copy of the pending state first, retrieving
+		// all the forked state it touches before the chain has to process it
+		statedb := b.pendingState.Copy()
+		modify(statedb)
+		statedb.IntermediateRoot(b.config.IsEIP158(b.pendingBlock.Number()))
+		if err := b.stateError(); err != nil {
+			return err
+		}
+	}
+	parent := b.blockchain.CurrentBlock()
+
+	b.engine.setModifier(parent.Hash(), modify)
+	defer b.engine.setModifier(parent.Hash(), nil)
+
+	blocks, _ := core.GenerateChain(b.config, parent, b.engine, b.database, 1, func(number int, block *core.BlockGen) {
+		for _, tx := range b.pendingBlock.Transactions() {
+			block.AddTxWithChain(b.blockchain, tx)
+		}
+	})
+	if _, err := b.blockchain.InsertChain(blocks); err != nil {
+		return err
+	}
+	b.rollback()
 	return nil
 }
 
+// Snapshot records the current head of the chain as a named checkpoint, which
+// can be returned to via RevertToSnapshot. Pending transactions are not part of
+// the checkpoint. An existing checkpoint with the same name is replaced.
+func (b *SimulatedBackend) Snapshot(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.snapshots[name] = b.blockchain.CurrentHeader()
+}
+
+// RevertToSnapshot rewinds the chain to a named checkpoint, discarding all the
+// blocks and pending transactions since. Checkpoints taken after the reverted
+// one are dropped.
+func (b *SimulatedBackend) RevertToSnapshot(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	head, ok := b.snapshots[name]
+	if !ok {
+		return fmt.Errorf("unknown snapshot %q", name)
+	}
+	if b.blockchain.GetCanonicalHash(head.Number.Uint64()) != head.Hash() {
+		return fmt.Errorf("snapshot %q is no longer part of the chain", name)
+	}
+	if err := b.blockchain.SetHead(head.Number.Uint64()); err != nil {
+		return err
+	}
+	for name, snapshot := range b.snapshots {
+		if snapshot.Number.Cmp(head.Number) > 0 {
+			delete(b.snapshots, name)
+		}
+	}
+	b.rollback()
+	return nil
+}
+
+// Impersonate returns a transactor sending transactions on behalf of the given
+// account, without access to its key. The transactions carry a placeholder
+// signature, so they are only accepted by this backend and must be sent in the
+// form returned by the signer.
+func (b *SimulatedBackend) Impersonate(account common.Address) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: account,
+		Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != account {
+				return nil, errors.New("not authorized to sign this account")
+			}
+			eip155 := types.NewEIP155Signer(b.config.ChainID)
+			signed, err := tx.WithSignature(eip155, impersonationSignature)
+			if err != nil {
+				return nil, err
+			}
+			// Cache the sender, sparing the recovery from the fake signature
+			types.Sender(impersonatedSigner{EIP155Signer: eip155, sender: account}, signed)
+
+			b.mu.Lock()
+			b.impersonated[signed.Hash()] = account
+			b.mu.Unlock()
+
+			return signed, nil
+		},
+	}
+}
+
 // Blockchain returns the underlying blockchain.
 func (b *SimulatedBackend) Blockchain() *core.BlockChain {
 	return b.blockchain
 }
 
+// impersonationSignature is the placeholder signature of impersonated transactions.
+var impersonationSignature = append(make([]byte, 64), 0)
+
+// impersonatedSigner is a signer reporting a fixed sender, used to populate the
+// sender cache of impersonated transactions. It's considered equal to the signer
+// it embeds, so the cached sender is used whenever that one is asked.
+type impersonatedSigner struct {
+	types.EIP155Signer
+	sender common.Address
+}
+
+func (s impersonatedSigner) Sender(tx *types.Transaction) (common.Address, error) {
+	return s.sender, nil
+}
+
+func (s impersonatedSigner) Equal(s2 types.Signer) bool {
+	return s.EIP155Signer.Equal(s2)
+}
+
+// simEngine is a consensus engine applying direct state modifications when
+// finalizing the child blocks of specific parents.
+type simEngine struct {
+	consensus.Engine
+
+	lock      sync.Mutex
+	modifiers map[common.Hash]func(*state.StateDB) // State modifications keyed by parent hash
+}
+
+// setModifier sets the state modification of the children of a block, removing
+// it if modify is nil.
+func (e *simEngine) setModifier(parent common.Hash, modify func(*state.StateDB)) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if modify == nil {
+		delete(e.modifiers, parent)
+		return
+	}
+	if e.modifiers == nil {
+		e.modifiers = make(map[common.Hash]func(*state.StateDB))
+	}
+	e.modifiers[parent] = modify
+}
+
+// modify applies the state modification registered for the block, if any.
+func (e *simEngine) modify(header *types.Header, statedb *state.StateDB) {
+	e.lock.Lock()
+	modify := e.modifiers[header.ParentHash]
+	e.lock.Unlock()
+
+	if modify != nil {
+		modify(statedb)
+	}
+}
+
+// Finalize implements consensus.Engine, applying the state modification of the
+// block before the wrapped engine finalizes it.
+func (e *simEngine) Finalize(chain consensus.ChainReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	e.modify(header, statedb)
+	e.Engine.Finalize(chain, header, statedb, txs, uncles)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, applying the state modification
+// of the block before the wrapped engine assembles it.
+func (e *simEngine) FinalizeAndAssemble(chain consensus.ChainReader, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	e.modify(header, statedb)
+	return e.Engine.FinalizeAndAssemble(chain, header, statedb, txs, uncles, receipts)
+}
+
 // callmsg implements core.Message to allow passing it as a transaction simulator.
 type callmsg struct {
 	ethereum.CallMsg
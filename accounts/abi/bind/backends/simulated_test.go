@@ -17,17 +17,22 @@
 package backends_test
 
 import (
+	"bytes"
 	"context"
 	"math/big"
+	"sync"
 	"testing"
 
 	ethereum "github.com/Fantom-foundation/go-ethereum"
 	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
 	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
 	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
 	"github.com/Fantom-foundation/go-ethereum/core"
 	"github.com/Fantom-foundation/go-ethereum/core/types"
 	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/params"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
 )
 
 func TestSimulatedBackend(t *testing.T) {
@@ -81,3 +86,305 @@ func TestSimulatedBackend(t *testing.T) {
 	}
 
 }
+
+// storageCode is the runtime code of a contract returning the storage slot given
+// as call data, or storing the value given after the slot.
+var storageCode = common.FromHex("366040146013576000355460005260206000f35b6020356000355500")
+
+// storageDeployCode is the deployment code of the storage contract.
+var storageDeployCode = append(common.FromHex("601c80600b6000396000f3"), storageCode...)
+
+func checkStorage(t *testing.T, sim *backends.SimulatedBackend, contract common.Address, slot, want common.Hash) {
+	t.Helper()
+
+	value, err := sim.StorageAt(context.Background(), contract, slot, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve slot %x: %v", slot, err)
+	}
+	if common.BytesToHash(value) != want {
+		t.Fatalf("slot %x mismatch: have %x, want %x", slot, value, want)
+	}
+}
+
+// Tests that state can be modified directly, after any pending transactions.
+func TestSimulatedBackendSetState(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	auth := bind.NewKeyedTransactor(key)
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(params.Ether)}}, 8000000)
+	defer sim.Close()
+
+	var (
+		ctx      = context.Background()
+		account  = common.HexToAddress("0x1234")
+		contract = common.HexToAddress("0x5678")
+	)
+	tx, _ := types.SignTx(types.NewTransaction(0, account, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	// The balance should be overridden after the pending transfer
+	if err := sim.SetBalance(account, big.NewInt(100)); err != nil {
+		t.Fatalf("failed to set balance: %v", err)
+	}
+	if receipt, _ := sim.TransactionReceipt(ctx, tx.Hash()); receipt == nil {
+		t.Fatalf("pending transaction not committed")
+	}
+	if balance, _ := sim.BalanceAt(ctx, account, nil); balance.Int64() != 100 {
+		t.Fatalf("balance mismatch: have %v, want 100", balance)
+	}
+	if err := sim.SetNonce(account, 5); err != nil {
+		t.Fatalf("failed to set nonce: %v", err)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, account); nonce != 5 {
+		t.Fatalf("nonce mismatch: have %d, want 5", nonce)
+	}
+	if err := sim.SetCode(contract, storageCode); err != nil {
+		t.Fatalf("failed to set code: %v", err)
+	}
+	if err := sim.SetStorage(contract, common.HexToHash("0x1"), common.HexToHash("0x42")); err != nil {
+		t.Fatalf("failed to set storage: %v", err)
+	}
+	output, err := sim.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: common.HexToHash("0x1").Bytes()}, nil)
+	if err != nil {
+		t.Fatalf("failed to call contract: %v", err)
+	}
+	if common.BytesToHash(output) != common.HexToHash("0x42") {
+		t.Fatalf("call output mismatch: have %x, want 0x42", output)
+	}
+}
+
+// Tests that the chain can be reverted to named checkpoints.
+func TestSimulatedBackendSnapshot(t *testing.T) {
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{}, 8000000)
+	defer sim.Close()
+
+	account := common.HexToAddress("0x1234")
+	balance := func() int64 {
+		balance, _ := sim.BalanceAt(context.Background(), account, nil)
+		return balance.Int64()
+	}
+	sim.SetBalance(account, big.NewInt(1))
+	sim.Snapshot("first")
+	sim.SetBalance(account, big.NewInt(2))
+	sim.Snapshot("second")
+	sim.SetBalance(account, big.NewInt(3))
+
+	if err := sim.RevertToSnapshot("second"); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if have := balance(); have != 2 {
+		t.Fatalf("balance mismatch: have %d, want 2", have)
+	}
+	if err := sim.RevertToSnapshot("first"); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+	if have := balance(); have != 1 {
+		t.Fatalf("balance mismatch: have %d, want 1", have)
+	}
+	if err := sim.RevertToSnapshot("second"); err == nil {
+		t.Fatalf("reverted to a checkpoint taken after the current head")
+	}
+	if err := sim.RevertToSnapshot("unknown"); err == nil {
+		t.Fatalf("reverted to an unknown checkpoint")
+	}
+}
+
+// Tests that transactions can be sent on behalf of accounts without their keys.
+func TestSimulatedBackendImpersonate(t *testing.T) {
+	whale := common.HexToAddress("0x1234")
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{whale: {Balance: big.NewInt(params.Ether)}}, 8000000)
+	defer sim.Close()
+
+	var (
+		ctx    = context.Background()
+		opts   = sim.Impersonate(whale)
+		signer = types.NewEIP155Signer(params.AllEthashProtocolChanges.ChainID)
+		to     = common.HexToAddress("0x5678")
+	)
+	transfer, err := opts.Signer(signer, whale, types.NewTransaction(0, to, big.NewInt(1000), params.TxGas, big.NewInt(1), nil))
+	if err != nil {
+		t.Fatalf("failed to sign transfer: %v", err)
+	}
+	deploy, _ := opts.Signer(signer, whale, types.NewContractCreation(1, new(big.Int), 100000, big.NewInt(1), storageDeployCode))
+	for _, tx := range []*types.Transaction{transfer, deploy} {
+		if err := sim.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+	}
+	sim.Commit()
+
+	if balance, _ := sim.BalanceAt(ctx, to, nil); balance.Int64() != 1000 {
+		t.Fatalf("balance mismatch: have %v, want 1000", balance)
+	}
+	receipt, _ := sim.TransactionReceipt(ctx, deploy.Hash())
+	if receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("deployment failed: %+v", receipt)
+	}
+	if want := crypto.CreateAddress(whale, 1); receipt.ContractAddress != want {
+		t.Fatalf("contract address mismatch: have %x, want %x", receipt.ContractAddress, want)
+	}
+	if code, _ := sim.CodeAt(ctx, receipt.ContractAddress, nil); !bytes.Equal(code, storageCode) {
+		t.Fatalf("deployed code mismatch: have %x", code)
+	}
+	if _, err := opts.Signer(signer, to, transfer); err == nil {
+		t.Fatalf("signed on behalf of a different account")
+	}
+}
+
+// forkService serves the state of a simulated backend over RPC, like a remote
+// node a backend can be forked from.
+type forkService struct {
+	sim *backends.SimulatedBackend
+
+	lock   sync.Mutex
+	proofs int // Number of proofs served
+}
+
+type ForkStorageProof struct {
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type ForkAccountProof struct {
+	AccountProof []hexutil.Bytes    `json:"accountProof"`
+	StorageProof []ForkStorageProof `json:"storageProof"`
+}
+
+func toHexBytes(nodes [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, len(nodes))
+	for i, node := range nodes {
+		res[i] = node
+	}
+	return res
+}
+
+func (s *forkService) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	if number == "latest" {
+		return s.sim.Blockchain().CurrentHeader(), nil
+	}
+	n, err := hexutil.DecodeUint64(number)
+	if err != nil {
+		return nil, err
+	}
+	return s.sim.Blockchain().GetHeaderByNumber(n), nil
+}
+
+func (s *forkService) GetProof(addr common.Address, keys []common.Hash, number string) (*ForkAccountProof, error) {
+	s.lock.Lock()
+	s.proofs++
+	s.lock.Unlock()
+
+	statedb, err := s.sim.Blockchain().State()
+	if err != nil {
+		return nil, err
+	}
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	res := &ForkAccountProof{AccountProof: toHexBytes(proof)}
+	for _, key := range keys {
+		proof, err := statedb.GetStorageProof(addr, key)
+		if err != nil {
+			return nil, err
+		}
+		res.StorageProof = append(res.StorageProof, ForkStorageProof{Proof: toHexBytes(proof)})
+	}
+	return res, nil
+}
+
+func (s *forkService) GetCode(addr common.Address, number string) (hexutil.Bytes, error) {
+	statedb, err := s.sim.Blockchain().State()
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(addr), nil
+}
+
+// Tests that a backend forked from a remote chain retrieves the state it accesses
+// on demand, and modifies it without affecting the remote chain.
+func TestForkedBackend(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		key, _   = crypto.GenerateKey()
+		auth     = bind.NewKeyedTransactor(key)
+		contract = common.HexToAddress("0x5678")
+		slot1    = common.HexToHash("0x1")
+		slot2    = common.HexToHash("0x2")
+	)
+	source := backends.NewSimulatedBackend(core.GenesisAlloc{
+		auth.From: {Balance: big.NewInt(params.Ether)},
+		contract:  {Balance: new(big.Int), Code: storageCode, Storage: map[common.Hash]common.Hash{slot1: common.HexToHash("0x11"), slot2: common.HexToHash("0x22")}},
+	}, 8000000)
+	defer source.Close()
+
+	// Extend the remote chain beyond the ancestors the fork retrieves
+	for i := 0; i < 300; i++ {
+		source.Commit()
+	}
+	service := &forkService{sim: source}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	sim, err := backends.NewForkedBackend(client, nil, 8000000)
+	if err != nil {
+		t.Fatalf("failed to fork: %v", err)
+	}
+	defer sim.Close()
+
+	// The fork should continue the remote chain, with the recent history intact
+	remote := source.Blockchain().CurrentHeader()
+	if head, _ := sim.HeaderByNumber(ctx, nil); head.Hash() != remote.Hash() {
+		t.Fatalf("forked head mismatch: have %d %x, want %d %x", head.Number, head.Hash(), remote.Number, remote.Hash())
+	}
+	ancestor := new(big.Int).Sub(remote.Number, big.NewInt(256))
+	if header, _ := sim.HeaderByNumber(ctx, ancestor); header == nil || header.Hash() != source.Blockchain().GetHeaderByNumber(ancestor.Uint64()).Hash() {
+		t.Fatalf("ancestor %d missing or mismatching: %v", ancestor, header)
+	}
+	if balance, _ := sim.BalanceAt(ctx, auth.From, nil); balance.Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Fatalf("forked balance mismatch: have %v, want %v", balance, params.Ether)
+	}
+	checkStorage(t, sim, contract, slot1, common.HexToHash("0x11"))
+
+	// Clear a slot through a transaction, collapsing the storage trie into the
+	// sibling slot that was never accessed
+	input := append(slot1.Bytes(), make([]byte, 32)...)
+	tx, _ := types.SignTx(types.NewTransaction(0, contract, new(big.Int), 100000, big.NewInt(1), input), types.HomesteadSigner{}, key)
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("failed to send transaction: %v", err)
+	}
+	sim.Commit()
+
+	if receipt, _ := sim.TransactionReceipt(ctx, tx.Hash()); receipt == nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction failed: %+v", receipt)
+	}
+	checkStorage(t, sim, contract, slot1, common.Hash{})
+	checkStorage(t, sim, contract, slot2, common.HexToHash("0x22"))
+
+	if err := sim.SetStorage(contract, slot2, common.HexToHash("0x33")); err != nil {
+		t.Fatalf("failed to set storage: %v", err)
+	}
+	checkStorage(t, sim, contract, slot2, common.HexToHash("0x33"))
+
+	// The remote chain should be unaffected
+	checkStorage(t, source, contract, slot1, common.HexToHash("0x11"))
+	checkStorage(t, source, contract, slot2, common.HexToHash("0x22"))
+
+	// Retrieval failures should be reported rather than yielding empty state
+	fresh, err := backends.NewForkedBackend(client, nil, 8000000)
+	if err != nil {
+		t.Fatalf("failed to fork: %v", err)
+	}
+	defer fresh.Close()
+
+	server.Stop()
+	if _, err := fresh.StorageAt(ctx, contract, slot1, nil); err == nil {
+		t.Fatalf("missing state reported without error")
+	}
+}
@@ -59,8 +59,9 @@ type TransactOpts struct {
 // FilterOpts is the collection of options to fine tune filtering for events
 // within a bound contract.
 type FilterOpts struct {
-	Start uint64  // Start of the queried range
-	End   *uint64 // End of the range (nil = latest)
+	Start      uint64  // Start of the queried range
+	End        *uint64 // End of the range (nil = latest)
+	BlockRange uint64  // Maximum number of blocks queried at once (0 = whole range)
 
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
@@ -70,6 +71,24 @@ type FilterOpts struct {
 type WatchOpts struct {
 	Start   *uint64         // Start of the queried range (nil = latest)
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
+
+	Resume     bool   // Whether to resubscribe on failures, backfilling the missed logs
+	BlockRange uint64 // Maximum number of blocks queried at once when backfilling (0 = whole range)
+}
+
+// RevertError is returned by calls reverting with a custom error declared in the
+// contract ABI, unless the binding decodes it into a typed error. Batch callers
+// also return it for reverted calls, leaving the signature empty.
+type RevertError struct {
+	Sig  string // Signature of the raised error, if known
+	Data []byte // Revert data, including the error selector
+}
+
+func (e *RevertError) Error() string {
+	if e.Sig == "" {
+		return "execution reverted"
+	}
+	return "execution reverted: " + e.Sig
 }
 
 // BoundContract is the base wrapper object that reflects a contract on the
@@ -81,6 +100,8 @@ type BoundContract struct {
 	caller     ContractCaller     // Read interface to interact with the blockchain
 	transactor ContractTransactor // Write interface to interact with the blockchain
 	filterer   ContractFilterer   // Event filtering to interact with the blockchain
+
+	errorDecoder func([]byte) error // Optional decoder of custom errors into typed ones
 }
 
 // NewBoundContract creates a low level contract interface through which calls
@@ -159,11 +180,46 @@ func (c *BoundContract) Call(opts *CallOpts, result interface{}, method string,
 		}
 	}
 	if err != nil {
+		// Calls reverting inside an aggregated batch carry their revert data
+		if revert, ok := err.(*RevertError); ok && revert.Sig == "" {
+			if err := c.unpackRevert(revert.Data); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	if err := c.unpackRevert(output); err != nil {
 		return err
 	}
 	return c.abi.Unpack(result, method, output)
 }
 
+// SetErrorDecoder sets the function converting the revert data of custom errors
+// declared in the ABI into typed errors. If the decoder returns nil, calls fail
+// with a RevertError instead.
+func (c *BoundContract) SetErrorDecoder(decoder func(data []byte) error) {
+	c.errorDecoder = decoder
+}
+
+// unpackRevert checks whether the output of a call is the revert data of a custom
+// error declared in the ABI, returning the error if so. Regular outputs consist
+// of 32 byte words, revert data is prefixed with a 4 byte selector.
+func (c *BoundContract) unpackRevert(output []byte) error {
+	if len(output)%32 != 4 {
+		return nil
+	}
+	e, err := c.abi.ErrorByID(output)
+	if err != nil {
+		return nil
+	}
+	if c.errorDecoder != nil {
+		if err := c.errorDecoder(output); err != nil {
+			return err
+		}
+	}
+	return &RevertError{Sig: e.Sig(), Data: output}
+}
+
 // Transact invokes the (paid) contract method with params as input values.
 func (c *BoundContract) Transact(opts *TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
 	// Otherwise pack up the parameters and invoke the contract
@@ -269,6 +325,24 @@ func (c *BoundContract) FilterLogs(opts *FilterOpts, name string, query ...[]int
 	if opts.End != nil {
 		config.ToBlock = new(big.Int).SetUint64(*opts.End)
 	}
+	if opts.BlockRange > 0 {
+		// Paginated filtering requested, query the range window by window
+		end, err := c.filterEnd(ensureContext(opts.Context), opts.End)
+		if err != nil {
+			return nil, nil, err
+		}
+		sub := event.NewSubscription(func(quit <-chan struct{}) error {
+			return c.filterRange(ensureContext(opts.Context), config, opts.Start, end, opts.BlockRange, func(log types.Log) bool {
+				select {
+				case logs <- log:
+					return true
+				case <-quit:
+					return false
+				}
+			})
+		})
+		return logs, sub, nil
+	}
 	/* TODO(karalabe): Replace the rest of the method below with this when supported
 	sub, err := c.filterer.SubscribeFilterLogs(ensureContext(opts.Context), config, logs)
 	*/
@@ -314,6 +388,9 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 		Addresses: []common.Address{c.address},
 		Topics:    topics,
 	}
+	if opts.Resume {
+		return logs, c.watchLogsResumable(opts, config, logs), nil
+	}
 	if opts.Start != nil {
 		config.FromBlock = new(big.Int).SetUint64(*opts.Start)
 	}
@@ -326,13 +403,18 @@ func (c *BoundContract) WatchLogs(opts *WatchOpts, name string, query ...[]inter
 
 // UnpackLog unpacks a retrieved log into the provided output structure.
 func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log) error {
+	// Unpack through the event directly, its name may collide with a method's
+	ev, ok := c.abi.Events[event]
+	if !ok {
+		return fmt.Errorf("abi: could not locate named event %q", event)
+	}
 	if len(log.Data) > 0 {
-		if err := c.abi.Unpack(out, event, log.Data); err != nil {
+		if err := ev.Inputs.Unpack(out, log.Data); err != nil {
 			return err
 		}
 	}
 	var indexed abi.Arguments
-	for _, arg := range c.abi.Events[event].Inputs {
+	for _, arg := range ev.Inputs {
 		if arg.Indexed {
 			indexed = append(indexed, arg)
 		}
@@ -342,13 +424,17 @@ func (c *BoundContract) UnpackLog(out interface{}, event string, log types.Log)
 
 // UnpackLogIntoMap unpacks a retrieved log into the provided map.
 func (c *BoundContract) UnpackLogIntoMap(out map[string]interface{}, event string, log types.Log) error {
+	ev, ok := c.abi.Events[event]
+	if !ok {
+		return fmt.Errorf("abi: could not locate named event %q", event)
+	}
 	if len(log.Data) > 0 {
-		if err := c.abi.UnpackIntoMap(out, event, log.Data); err != nil {
+		if err := ev.Inputs.UnpackIntoMap(out, log.Data); err != nil {
 			return err
 		}
 	}
 	var indexed abi.Arguments
-	for _, arg := range c.abi.Events[event].Inputs {
+	for _, arg := range ev.Inputs {
 		if arg.Indexed {
 			indexed = append(indexed, arg)
 		}
@@ -135,6 +135,24 @@ func TestUnpackIndexedStringTyLogIntoMap(t *testing.T) {
 	}
 }
 
+func TestUnpackLogUnknownEvent(t *testing.T) {
+	mockLog := types.Log{
+		Topics: []common.Hash{common.HexToHash("0x0")},
+		Data:   hexutil.MustDecode(hexData),
+	}
+	abiString := `[{"anonymous":false,"inputs":[{"indexed":false,"name":"sender","type":"address"}],"name":"received","type":"event"}]`
+	parsedAbi, _ := abi.JSON(strings.NewReader(abiString))
+	bc := bind.NewBoundContract(common.HexToAddress("0x0"), parsedAbi, nil, nil, nil)
+
+	var out struct{ Sender common.Address }
+	if err := bc.UnpackLog(&out, "recieved", mockLog); err == nil {
+		t.Error("UnpackLog succeeded for unknown event")
+	}
+	if err := bc.UnpackLogIntoMap(make(map[string]interface{}), "recieved", mockLog); err == nil {
+		t.Error("UnpackLogIntoMap succeeded for unknown event")
+	}
+}
+
 func TestUnpackIndexedSliceTyLogIntoMap(t *testing.T) {
 	sliceBytes, err := rlp.EncodeToBytes([]string{"name1", "name2", "name3", "name4"})
 	if err != nil {
@@ -26,6 +26,7 @@ import (
 	"fmt"
 	"go/format"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"unicode"
@@ -73,7 +74,13 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			calls     = make(map[string]*tmplMethod)
 			transacts = make(map[string]*tmplMethod)
 			events    = make(map[string]*tmplEvent)
+			errs      = make(map[string]*tmplError)
 			structs   = make(map[string]*tmplStruct)
+
+			// identifiers are used to detect name collisions of normalized events
+			// and errors, e.g. overloads differing only in capitalisation
+			eventIdentifiers = make(map[string]bool)
+			errorIdentifiers = make(map[string]bool)
 		)
 		for _, original := range evmABI.Methods {
 			// Normalize the method for capital cases and non-anonymous inputs/outputs
@@ -107,14 +114,16 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 				transacts[original.Name] = &tmplMethod{Original: original, Normalized: normalized, Structured: structured(original.Outputs)}
 			}
 		}
-		for _, original := range evmABI.Events {
+		for _, name := range sortedEventNames(evmABI.Events) {
+			original := evmABI.Events[name]
+
 			// Skip anonymous events as they don't support explicit filtering
 			if original.Anonymous {
 				continue
 			}
 			// Normalize the event for capital cases and non-anonymous outputs
 			normalized := original
-			normalized.Name = methodNormalizer[lang](original.Name)
+			normalized.Name = uniqueIdentifier(methodNormalizer[lang](original.Name), eventIdentifiers)
 
 			normalized.Inputs = make([]abi.Argument, len(original.Inputs))
 			copy(normalized.Inputs, original.Inputs)
@@ -132,6 +141,25 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			// Append the event to the accumulator list
 			events[original.Name] = &tmplEvent{Original: original, Normalized: normalized}
 		}
+		for _, name := range sortedErrorNames(evmABI.Errors) {
+			original := evmABI.Errors[name]
+
+			// Normalize the error for capital cases and non-anonymous inputs
+			normalized := original
+			normalized.Name = uniqueIdentifier(methodNormalizer[lang](original.Name), errorIdentifiers)
+
+			normalized.Inputs = make([]abi.Argument, len(original.Inputs))
+			copy(normalized.Inputs, original.Inputs)
+			for j, input := range normalized.Inputs {
+				if input.Name == "" {
+					normalized.Inputs[j].Name = fmt.Sprintf("arg%d", j)
+				}
+				if _, exist := structs[input.Type.String()]; input.Type.T == abi.TupleTy && !exist {
+					bindStructType[lang](input.Type, structs)
+				}
+			}
+			errs[original.Name] = &tmplError{Original: original, Normalized: normalized}
+		}
 
 		// There is no easy way to pass arbitrary java objects to the Go side.
 		if len(structs) > 0 && lang == LangJava {
@@ -146,6 +174,7 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 			Calls:       calls,
 			Transacts:   transacts,
 			Events:      events,
+			Errors:      errs,
 			Libraries:   make(map[string]string),
 			Structs:     structs,
 		}
@@ -207,6 +236,38 @@ func Bind(types []string, abis []string, bytecodes []string, fsigs []map[string]
 	return buffer.String(), nil
 }
 
+// sortedEventNames returns the names of the events in alphabetical order, which
+// makes the resolution of colliding normalized names deterministic.
+func sortedEventNames(events map[string]abi.Event) []string {
+	names := make([]string, 0, len(events))
+	for name := range events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedErrorNames returns the names of the errors in alphabetical order.
+func sortedErrorNames(errs map[string]abi.Error) []string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// uniqueIdentifier returns the identifier, suffixed with a number if it is already
+// taken, and marks the result as taken.
+func uniqueIdentifier(identifier string, taken map[string]bool) string {
+	unique := identifier
+	for idx := 0; taken[unique]; idx++ {
+		unique = fmt.Sprintf("%s%d", identifier, idx)
+	}
+	taken[unique] = true
+	return unique
+}
+
 // bindType is a set of type binders that convert Solidity types to some supported
 // programming language types.
 var bindType = map[Lang]func(kind abi.Type, structs map[string]*tmplStruct) string{
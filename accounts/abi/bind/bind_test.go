@@ -951,6 +951,52 @@ var bindTests = []struct {
 				t.Fatalf("unsubscribed simple event arrived: %v", event)
 			case <-time.After(250 * time.Millisecond):
 			}
+			// Test that paginated filtering returns the same events as a single query
+			var all []*EventerSimpleEvent
+			ait, err := eventer.FilterSimpleEvent(nil, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to filter for simple events: %v", err)
+			}
+			for ait.Next() {
+				all = append(all, ait.Event)
+			}
+			ait.Close()
+
+			pit, err := eventer.FilterSimpleEvent(&bind.FilterOpts{BlockRange: 1}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to filter for paginated simple events: %v", err)
+			}
+			for i := 0; pit.Next(); i++ {
+				if i >= len(all) || pit.Event.Raw.TxHash != all[i].Raw.TxHash {
+					t.Fatalf("paginated simple event %d mismatch: have %v", i, pit.Event)
+				}
+			}
+			if err = pit.Error(); err != nil {
+				t.Fatalf("paginated simple event iteration failed: %v", err)
+			}
+			pit.Close()
+
+			// Test that a resumable iterator backfills past events before live ones
+			start := uint64(0)
+			rit, err := eventer.IterateSimpleEvent(&bind.WatchOpts{Start: &start, Resume: true, BlockRange: 2}, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to iterate simple events: %v", err)
+			}
+			defer rit.Close()
+
+			for i := range all {
+				if !rit.Next() || rit.Event.Raw.TxHash != all[i].Raw.TxHash {
+					t.Fatalf("backfilled simple event %d mismatch: have %v", i, rit.Event)
+				}
+			}
+			if _, err := eventer.RaiseSimpleEvent(auth, common.Address{253}, [32]byte{253}, true, big.NewInt(253)); err != nil {
+				t.Fatalf("failed to raise iterated simple event: %v", err)
+			}
+			sim.Commit()
+
+			if !rit.Next() || rit.Event.Value.Uint64() != 253 || rit.Event.Raw.Removed {
+				t.Fatalf("live simple event mismatch: have %v", rit.Event)
+			}
 		`,
 		nil,
 		nil,
@@ -1238,6 +1284,8 @@ var bindTests = []struct {
 			`[{"constant":true,"inputs":[{"name":"a","type":"uint256"},{"name":"b","type":"uint256"}],"name":"add","outputs":[{"name":"","type":"uint256"}],"payable":false,"stateMutability":"view","type":"function"}]`,
 		},
 		`
+			"bytes"
+			"context"
 			"math/big"
 
 			"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
@@ -1274,6 +1322,33 @@ var bindTests = []struct {
 			if res.Cmp(big.NewInt(3)) != 0 {
 				t.Fatalf("Add did not return the correct result: %d != %d", res, 3)
 			}
+
+			// Link another instance against an already deployed library
+			mathAddr, _, _, err := DeployMath(auth, sim)
+			if err != nil {
+				t.Fatalf("Failed to deploy library: %v", err)
+			}
+			sim.Commit()
+
+			_, tx, linkedContract, err := DeployUseLibraryWithLibraries(auth, sim, UseLibraryLibraries{Math: mathAddr})
+			if err != nil {
+				t.Fatalf("Failed to deploy linked contract: %v", err)
+			}
+			sim.Commit()
+
+			if code, err := sim.CodeAt(context.Background(), mathAddr, nil); err != nil || len(code) == 0 {
+				t.Fatalf("Library missing: %v", err)
+			}
+			if !bytes.Contains(tx.Data(), mathAddr.Bytes()) {
+				t.Fatalf("Contract not linked against the supplied library")
+			}
+			res, err = linkedContract.Add(&bind.CallOpts{From: auth.From}, big.NewInt(2), big.NewInt(3))
+			if err != nil {
+				t.Fatalf("Failed to call linked contract: %v", err)
+			}
+			if res.Cmp(big.NewInt(5)) != 0 {
+				t.Fatalf("Add did not return the correct result: %d != %d", res, 5)
+			}
 		`,
 		nil,
 		map[string]string{
@@ -1373,6 +1448,76 @@ var bindTests = []struct {
 		nil,
 		nil,
 		nil,
+	}, {
+		"CustomErrors",
+		`
+		contract CustomErrors {
+		  error Unauthorized(address caller, uint256 code);
+		  error Unauthorized();
+
+		  event Transfer(uint256 value);
+		  event transfer(uint256 value);
+
+		  // Hand assembled: reverts every call with Unauthorized(msg.sender, 42)
+		  function owner() public view returns (address);
+		}
+		`,
+		[]string{`603280600b6000396000f37fda4720230000000000000000000000000000000000000000000000000000000060005233600452602a60245260446000fd`},
+		[]string{`[{"constant":true,"inputs":[],"name":"owner","outputs":[{"name":"","type":"address"}],"payable":false,"stateMutability":"view","type":"function"},{"inputs":[{"name":"caller","type":"address"},{"name":"code","type":"uint256"}],"name":"Unauthorized","type":"error"},{"inputs":[],"name":"Unauthorized","type":"error"},{"anonymous":false,"inputs":[{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"},{"anonymous":false,"inputs":[{"indexed":false,"name":"value","type":"uint256"}],"name":"transfer","type":"event"}]`},
+		`
+		"math/big"
+
+		"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
+		"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
+		"github.com/Fantom-foundation/go-ethereum/common"
+		"github.com/Fantom-foundation/go-ethereum/core"
+		"github.com/Fantom-foundation/go-ethereum/crypto"
+		`,
+		`
+		// Initialize test accounts
+		key, _ := crypto.GenerateKey()
+		auth := bind.NewKeyedTransactor(key)
+		sim := backends.NewSimulatedBackend(core.GenesisAlloc{auth.From: {Balance: big.NewInt(10000000000)}}, 10000000)
+		defer sim.Close()
+
+		_, _, contract, err := DeployCustomErrors(auth, sim)
+		if err != nil {
+			t.Fatalf("Failed to deploy contract: %v", err)
+		}
+		sim.Commit()
+
+		// Calls reverting with a custom error should return it typed
+		_, err = contract.Owner(&bind.CallOpts{From: auth.From})
+		unauthorized, ok := err.(*CustomErrorsUnauthorizedError)
+		if !ok {
+			t.Fatalf("Error type mismatch: have %T (%v), want *CustomErrorsUnauthorizedError", err, err)
+		}
+		if unauthorized.Caller != auth.From || unauthorized.Code.Cmp(big.NewInt(42)) != 0 {
+			t.Fatalf("Error fields mismatch: have %v", unauthorized)
+		}
+		if want := "Unauthorized(" + auth.From.String() + ", 42)"; err.Error() != want {
+			t.Fatalf("Error message mismatch: have %q, want %q", err.Error(), want)
+		}
+		// Overloaded errors should be bound separately
+		if err := UnpackCustomErrorsError(common.FromHex("0x82b42900")); err == nil {
+			t.Fatalf("Overloaded error not decoded")
+		} else if _, ok := err.(*CustomErrorsUnauthorized0Error); !ok {
+			t.Fatalf("Overloaded error type mismatch: have %T", err)
+		}
+		if err := UnpackCustomErrorsError(common.FromHex("0xdeadbeef")); err != nil {
+			t.Fatalf("Unknown error decoded: %v", err)
+		}
+		// Events differing only in capitalisation should both be bound
+		if _, err := contract.FilterTransfer(nil); err != nil {
+			t.Fatalf("Failed to filter Transfer: %v", err)
+		}
+		if _, err := contract.FilterTransfer0(nil); err != nil {
+			t.Fatalf("Failed to filter transfer: %v", err)
+		}
+		`,
+		nil,
+		nil,
+		nil,
 	},
 }
 
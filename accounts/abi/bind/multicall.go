@@ -0,0 +1,341 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+const (
+	defaultMaxBatchSize = 100                   // Default number of calls executed in a single batch
+	defaultBatchDelay   = 10 * time.Millisecond // Default time to wait for more calls before executing a batch
+)
+
+// multicallABI is the interface of the tryAggregate method shared by the widely
+// deployed Multicall2 and Multicall3 aggregator contracts.
+const multicallABI = `[{"inputs":[{"name":"requireSuccess","type":"bool"},{"components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"tryAggregate","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"nonpayable","type":"function"}]`
+
+// errBatchCallerClosed is returned by calls issued after the batch caller is closed.
+var errBatchCallerClosed = errors.New("batch caller closed")
+
+// CallResult is the outcome of a single call executed as part of a batch.
+type CallResult struct {
+	Output []byte // Return data of the call
+	Err    error  // Error of this particular call, if it failed
+}
+
+// CallBatcher executes a batch of contract calls at once against the state of the
+// same block. Errors of individual calls are reported in their results, an error
+// is returned only if the batch as a whole could not be executed.
+type CallBatcher interface {
+	BatchCall(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error)
+}
+
+// rpcBatcher is a CallBatcher sending the calls as a single JSON-RPC batch.
+type rpcBatcher struct {
+	client *rpc.Client
+}
+
+// NewRPCBatcher creates a CallBatcher which sends all calls of a batch in a single
+// JSON-RPC batch request. Every call is executed individually by the node, so they
+// keep their sender, value and gas allowance.
+func NewRPCBatcher(client *rpc.Client) CallBatcher {
+	return &rpcBatcher{client: client}
+}
+
+// BatchCall implements CallBatcher, executing the calls as a JSON-RPC batch.
+func (b *rpcBatcher) BatchCall(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error) {
+	block := "latest"
+	if blockNumber != nil {
+		block = hexutil.EncodeBig(blockNumber)
+	}
+	var (
+		outputs = make([]hexutil.Bytes, len(calls))
+		reqs    = make([]rpc.BatchElem, len(calls))
+	)
+	for i, call := range calls {
+		reqs[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{toCallArg(call), block},
+			Result: &outputs[i],
+		}
+	}
+	if err := b.client.BatchCallContext(ctx, reqs); err != nil {
+		return nil, err
+	}
+	results := make([]CallResult, len(calls))
+	for i := range reqs {
+		results[i] = CallResult{Output: outputs[i], Err: reqs[i].Error}
+	}
+	return results, nil
+}
+
+// toCallArg converts a call message into the argument format of eth_call.
+func toCallArg(msg ethereum.CallMsg) interface{} {
+	arg := map[string]interface{}{
+		"from": msg.From,
+		"to":   msg.To,
+	}
+	if len(msg.Data) > 0 {
+		arg["data"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+	return arg
+}
+
+// multicallBatcher is a CallBatcher executing the calls through an on-chain
+// aggregator contract.
+type multicallBatcher struct {
+	caller     ContractCaller
+	aggregator common.Address
+	abi        abi.ABI
+}
+
+// multicallCall is a single call passed to the aggregator contract.
+type multicallCall struct {
+	Target   common.Address
+	CallData []byte
+}
+
+// multicallResult is the outcome of a single call reported by the aggregator.
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// NewMulticallBatcher creates a CallBatcher which executes all calls of a batch in
+// a single eth_call to the tryAggregate method of a Multicall2 or Multicall3
+// aggregator contract deployed at the given address.
+//
+// The aggregator is the sender of all calls, any sender, value or gas allowance
+// set in the individual calls is ignored. Reverted calls fail with a RevertError
+// carrying their revert data.
+func NewMulticallBatcher(caller ContractCaller, aggregator common.Address) CallBatcher {
+	parsed, err := abi.JSON(strings.NewReader(multicallABI))
+	if err != nil {
+		panic(err)
+	}
+	return &multicallBatcher{caller: caller, aggregator: aggregator, abi: parsed}
+}
+
+// BatchCall implements CallBatcher, executing the calls through the aggregator.
+func (b *multicallBatcher) BatchCall(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error) {
+	aggregated := make([]multicallCall, len(calls))
+	for i, call := range calls {
+		if call.To == nil {
+			return nil, fmt.Errorf("call %d: contract creation can't be aggregated", i)
+		}
+		aggregated[i] = multicallCall{Target: *call.To, CallData: call.Data}
+	}
+	input, err := b.abi.Pack("tryAggregate", false, aggregated)
+	if err != nil {
+		return nil, err
+	}
+	output, err := b.caller.CallContract(ctx, ethereum.CallMsg{To: &b.aggregator, Data: input}, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 {
+		return nil, ErrNoCode
+	}
+	var outcomes []multicallResult
+	if err := b.abi.Unpack(&outcomes, "tryAggregate", output); err != nil {
+		return nil, err
+	}
+	if len(outcomes) != len(calls) {
+		return nil, fmt.Errorf("aggregator returned %d results for %d calls", len(outcomes), len(calls))
+	}
+	results := make([]CallResult, len(calls))
+	for i, outcome := range outcomes {
+		if outcome.Success {
+			results[i] = CallResult{Output: outcome.ReturnData}
+		} else {
+			results[i] = CallResult{Err: &RevertError{Data: outcome.ReturnData}}
+		}
+	}
+	return results, nil
+}
+
+// BatchCallerConfig contains the settings of a BatchCaller.
+type BatchCallerConfig struct {
+	MaxBatchSize int           // Maximum number of calls executed in a single batch (0 = 100)
+	Delay        time.Duration // Time to wait for more calls before executing a batch (0 = 10ms)
+	Timeout      time.Duration // Maximum execution time of a batch (0 = unlimited)
+}
+
+// BatchCaller is a ContractCaller which collects the calls issued concurrently
+// through it, e.g. by generated bindings running in separate goroutines, and
+// executes them in batches via a CallBatcher.
+//
+// A call blocks until its batch is executed, which happens once MaxBatchSize calls
+// against the same block are pending or Delay elapsed since the first of them.
+type BatchCaller struct {
+	backend ContractCaller // Backend serving code retrievals
+	batcher CallBatcher    // Executor of the collected call batches
+	config  BatchCallerConfig
+
+	lock    sync.Mutex
+	pending map[string]*pendingBatch // Batches being collected, keyed by block number
+	closed  bool
+}
+
+// pendingBatch is a batch of calls against the same block awaiting execution.
+type pendingBatch struct {
+	block *big.Int
+	calls []ethereum.CallMsg
+	done  []chan CallResult
+	timer *time.Timer
+}
+
+// NewBatchCaller creates a ContractCaller collecting calls into batches executed
+// by the given batcher. Code retrievals are forwarded to the backend directly.
+func NewBatchCaller(backend ContractCaller, batcher CallBatcher, config BatchCallerConfig) *BatchCaller {
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = defaultMaxBatchSize
+	}
+	if config.Delay <= 0 {
+		config.Delay = defaultBatchDelay
+	}
+	return &BatchCaller{
+		backend: backend,
+		batcher: batcher,
+		config:  config,
+		pending: make(map[string]*pendingBatch),
+	}
+}
+
+// CodeAt implements ContractCaller, retrieving the code from the backend.
+func (b *BatchCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return b.backend.CodeAt(ctx, contract, blockNumber)
+}
+
+// CallContract implements ContractCaller, adding the call to the batch of its
+// block and waiting for the batch to be executed.
+func (b *BatchCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	done := make(chan CallResult, 1)
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return nil, errBatchCallerClosed
+	}
+	key := "latest"
+	if blockNumber != nil {
+		key = blockNumber.String()
+	}
+	batch := b.pending[key]
+	if batch == nil {
+		batch = &pendingBatch{block: blockNumber}
+		batch.timer = time.AfterFunc(b.config.Delay, func() { b.flush(key, batch) })
+		b.pending[key] = batch
+	}
+	batch.calls = append(batch.calls, call)
+	batch.done = append(batch.done, done)
+	if len(batch.calls) >= b.config.MaxBatchSize {
+		delete(b.pending, key)
+		batch.timer.Stop()
+		go b.execute(batch)
+	}
+	b.lock.Unlock()
+
+	select {
+	case res := <-done:
+		return res.Output, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Flush executes all pending batches immediately, without waiting for them to
+// fill up or their delay to elapse.
+func (b *BatchCaller) Flush() {
+	b.lock.Lock()
+	batches := make(map[string]*pendingBatch, len(b.pending))
+	for key, batch := range b.pending {
+		batches[key] = batch
+	}
+	b.lock.Unlock()
+
+	for key, batch := range batches {
+		go b.flush(key, batch)
+	}
+}
+
+// Close executes the pending batches and rejects any further calls.
+func (b *BatchCaller) Close() {
+	b.lock.Lock()
+	b.closed = true
+	b.lock.Unlock()
+
+	b.Flush()
+}
+
+// flush executes the batch if it's still pending.
+func (b *BatchCaller) flush(key string, batch *pendingBatch) {
+	b.lock.Lock()
+	if b.pending[key] != batch {
+		b.lock.Unlock()
+		return // Already executed
+	}
+	delete(b.pending, key)
+	batch.timer.Stop()
+	b.lock.Unlock()
+
+	b.execute(batch)
+}
+
+// execute runs a batch detached from the pending ones, delivering the results to
+// the waiting calls.
+func (b *BatchCaller) execute(batch *pendingBatch) {
+	ctx := context.Background()
+	if b.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.config.Timeout)
+		defer cancel()
+	}
+	results, err := b.batcher.BatchCall(ctx, batch.calls, batch.block)
+	if err == nil && len(results) != len(batch.calls) {
+		err = fmt.Errorf("batcher returned %d results for %d calls", len(results), len(batch.calls))
+	}
+	for i, done := range batch.done {
+		if err != nil {
+			done <- CallResult{Err: err}
+		} else {
+			done <- results[i]
+		}
+	}
+}
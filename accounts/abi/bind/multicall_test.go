@@ -0,0 +1,248 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+// echoBatcher is a CallBatcher returning the call data as output, failing calls
+// without data.
+type echoBatcher struct {
+	lock    sync.Mutex
+	batches [][]ethereum.CallMsg
+}
+
+func (b *echoBatcher) BatchCall(ctx context.Context, calls []ethereum.CallMsg, blockNumber *big.Int) ([]CallResult, error) {
+	b.lock.Lock()
+	b.batches = append(b.batches, calls)
+	b.lock.Unlock()
+
+	results := make([]CallResult, len(calls))
+	for i, call := range calls {
+		if len(call.Data) == 0 {
+			results[i].Err = errors.New("no data")
+		} else {
+			results[i].Output = call.Data
+		}
+	}
+	return results, nil
+}
+
+func (b *echoBatcher) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x1}, nil
+}
+
+func (b *echoBatcher) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	panic("not batched")
+}
+
+// Tests that concurrent calls are collected into batches of bounded size, each
+// receiving its own result.
+func TestBatchCaller(t *testing.T) {
+	batcher := new(echoBatcher)
+	caller := NewBatchCaller(batcher, batcher, BatchCallerConfig{MaxBatchSize: 5, Delay: time.Minute})
+	defer caller.Close()
+
+	var (
+		wg   sync.WaitGroup
+		errc = make(chan error, 10)
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := []byte{byte(i + 1)}
+			output, err := caller.CallContract(context.Background(), ethereum.CallMsg{Data: data}, nil)
+			if err != nil {
+				errc <- err
+			} else if !bytes.Equal(output, data) {
+				errc <- errors.New("output mismatch")
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Fatalf("call failed: %v", err)
+	}
+	if len(batcher.batches) != 2 {
+		t.Fatalf("batch count mismatch: have %d, want 2", len(batcher.batches))
+	}
+	// Individual failures shouldn't affect the rest of the batch
+	var failed, succeeded error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, failed = caller.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	}()
+	go func() {
+		defer wg.Done()
+		_, succeeded = caller.CallContract(context.Background(), ethereum.CallMsg{Data: []byte{1}}, nil)
+	}()
+	for {
+		caller.lock.Lock()
+		n := 0
+		if batch := caller.pending["latest"]; batch != nil {
+			n = len(batch.calls)
+		}
+		caller.lock.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	caller.Flush()
+	wg.Wait()
+
+	if failed == nil || succeeded != nil {
+		t.Fatalf("per-call errors mismatch: have %v and %v", failed, succeeded)
+	}
+	if len(batcher.batches) != 3 {
+		t.Fatalf("batch count mismatch: have %d, want 3", len(batcher.batches))
+	}
+}
+
+// Tests that calls against different blocks are executed in separate batches.
+func TestBatchCallerBlocks(t *testing.T) {
+	batcher := new(echoBatcher)
+	caller := NewBatchCaller(batcher, batcher, BatchCallerConfig{Delay: 10 * time.Millisecond})
+	defer caller.Close()
+
+	var wg sync.WaitGroup
+	for _, number := range []*big.Int{nil, big.NewInt(1), nil, big.NewInt(1), big.NewInt(2)} {
+		wg.Add(1)
+		go func(number *big.Int) {
+			defer wg.Done()
+			caller.CallContract(context.Background(), ethereum.CallMsg{Data: []byte{1}}, number)
+		}(number)
+	}
+	wg.Wait()
+
+	if len(batcher.batches) != 3 {
+		t.Fatalf("batch count mismatch: have %d, want 3", len(batcher.batches))
+	}
+}
+
+// callService is an RPC service answering eth_call with the call data.
+type callService struct{}
+
+func (s *callService) Call(args map[string]interface{}, block string) (hexutil.Bytes, error) {
+	data, _ := args["data"].(string)
+	if data == "" {
+		return nil, errors.New("no data")
+	}
+	return hexutil.Decode(data)
+}
+
+// Tests that the RPC batcher executes calls in a JSON-RPC batch, reporting the
+// errors of individual calls.
+func TestRPCBatcher(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", new(callService)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	to := common.HexToAddress("0x01")
+	results, err := NewRPCBatcher(client).BatchCall(context.Background(), []ethereum.CallMsg{
+		{To: &to, Data: []byte{1, 2}},
+		{To: &to},
+		{To: &to, Data: []byte{3}},
+	}, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if !bytes.Equal(results[0].Output, []byte{1, 2}) || results[0].Err != nil {
+		t.Errorf("result 0 mismatch: %x, %v", results[0].Output, results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Errorf("result 1 should fail")
+	}
+	if !bytes.Equal(results[2].Output, []byte{3}) || results[2].Err != nil {
+		t.Errorf("result 2 mismatch: %x, %v", results[2].Output, results[2].Err)
+	}
+}
+
+// mockAggregator is a ContractCaller emulating the tryAggregate method of a
+// Multicall contract, succeeding calls by echoing their data.
+type mockAggregator struct {
+	abi abi.ABI
+}
+
+func (m *mockAggregator) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x1}, nil
+}
+
+func (m *mockAggregator) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var input struct {
+		RequireSuccess bool
+		Calls          []multicallCall
+	}
+	if err := m.abi.Methods["tryAggregate"].Inputs.Unpack(&input, call.Data[4:]); err != nil {
+		return nil, err
+	}
+	results := make([]multicallResult, len(input.Calls))
+	for i, sub := range input.Calls {
+		results[i] = multicallResult{Success: len(sub.CallData) > 0, ReturnData: sub.CallData}
+		if !results[i].Success {
+			results[i].ReturnData = []byte{0xde, 0xad, 0xbe, 0xef}
+		}
+	}
+	return m.abi.Methods["tryAggregate"].Outputs.Pack(results)
+}
+
+// Tests that the multicall batcher executes calls through the aggregator, failing
+// reverted calls with their revert data.
+func TestMulticallBatcher(t *testing.T) {
+	parsed, _ := abi.JSON(strings.NewReader(multicallABI))
+	batcher := NewMulticallBatcher(&mockAggregator{abi: parsed}, common.HexToAddress("0xca11"))
+
+	to := common.HexToAddress("0x01")
+	results, err := batcher.BatchCall(context.Background(), []ethereum.CallMsg{
+		{To: &to, Data: []byte{1, 2}},
+		{To: &to},
+	}, nil)
+	if err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if !bytes.Equal(results[0].Output, []byte{1, 2}) || results[0].Err != nil {
+		t.Errorf("result 0 mismatch: %x, %v", results[0].Output, results[0].Err)
+	}
+	revert, ok := results[1].Err.(*RevertError)
+	if !ok || !bytes.Equal(revert.Data, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("result 1 mismatch: %v", results[1].Err)
+	}
+	if _, err := batcher.BatchCall(context.Background(), []ethereum.CallMsg{{}}, nil); err == nil {
+		t.Errorf("contract creation should not be aggregated")
+	}
+}
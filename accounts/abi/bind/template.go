@@ -35,6 +35,7 @@ type tmplContract struct {
 	Calls       map[string]*tmplMethod // Contract calls that only read state data
 	Transacts   map[string]*tmplMethod // Contract calls that write state data
 	Events      map[string]*tmplEvent  // Contract events accessors
+	Errors      map[string]*tmplError  // Contract custom errors raised through reverts
 	Libraries   map[string]string      // Same as tmplData, but filtered to only keep what the contract needs
 	Structs     map[string]*tmplStruct // Contract struct type definitions
 	Library     bool
@@ -54,6 +55,13 @@ type tmplEvent struct {
 	Normalized abi.Event // Normalized version of the parsed fields
 }
 
+// tmplError is a wrapper around an abi.Error that contains the normalized name
+// and inputs of the error.
+type tmplError struct {
+	Original   abi.Error // Original error as parsed by the abi package
+	Normalized abi.Error // Normalized version of the parsed fields
+}
+
 // tmplField is a wrapper around a struct field with binding language
 // struct type definition and relative filed name.
 type tmplField struct {
@@ -85,6 +93,7 @@ const tmplSourceGo = `
 package {{.Package}}
 
 import (
+	"fmt"
 	"math/big"
 	"strings"
 
@@ -98,6 +107,7 @@ import (
 
 // Reference imports to suppress errors if they are not otherwise used.
 var (
+	_ = fmt.Sprintf
 	_ = big.NewInt
 	_ = strings.NewReader
 	_ = ethereum.NotFound
@@ -125,20 +135,52 @@ var (
 		// {{.Type}}Bin is the compiled bytecode used for deploying new contracts.
 		var {{.Type}}Bin = "0x{{.InputBin}}"
 
+		{{if .Libraries}}
+			// {{.Type}}Libraries contains the addresses of the libraries {{.Type}} is linked
+			// against. Libraries left at the zero address are deployed along with the contract.
+			type {{.Type}}Libraries struct {
+				{{range $pattern, $name := .Libraries}}{{capitalise $name}} common.Address
+				{{end}}
+			}
+		{{end}}
+
 		// Deploy{{.Type}} deploys a new Ethereum contract, binding an instance of {{.Type}} to it.
 		func Deploy{{.Type}}(auth *bind.TransactOpts, backend bind.ContractBackend {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type $structs}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+		  {{if .Libraries}}
+		    return Deploy{{.Type}}WithLibraries(auth, backend, {{.Type}}Libraries{} {{range .Constructor.Inputs}}, {{.Name}}{{end}})
+		  }
+
+		  // Deploy{{.Type}}WithLibraries deploys a new Ethereum contract linked against the given libraries,
+		  // deploying the missing ones first, and binds an instance of {{.Type}} to it.
+		  func Deploy{{.Type}}WithLibraries(auth *bind.TransactOpts, backend bind.ContractBackend, libs {{.Type}}Libraries {{range .Constructor.Inputs}}, {{.Name}} {{bindtype .Type $structs}}{{end}}) (common.Address, *types.Transaction, *{{.Type}}, error) {
+		  {{end}}
 		  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
 		  }
+		  bin := {{.Type}}Bin
 		  {{range $pattern, $name := .Libraries}}
-			{{decapitalise $name}}Addr, _, _, _ := Deploy{{capitalise $name}}(auth, backend)
-			{{$contract.Type}}Bin = strings.Replace({{$contract.Type}}Bin, "__${{$pattern}}$__", {{decapitalise $name}}Addr.String()[2:], -1)
+		    if libs.{{capitalise $name}} == (common.Address{}) {
+		      addr, tx, _, err := Deploy{{capitalise $name}}(auth, backend)
+		      if err != nil {
+		        return common.Address{}, nil, nil, err
+		      }
+		      libs.{{capitalise $name}} = addr
+
+		      // Don't reuse an explicitly set nonce for the contract itself
+		      if auth.Nonce != nil {
+		        next := *auth
+		        next.Nonce = new(big.Int).SetUint64(tx.Nonce() + 1)
+		        auth = &next
+		      }
+		    }
+		    bin = strings.Replace(bin, "__${{$pattern}}$__", libs.{{capitalise $name}}.String()[2:], -1)
 		  {{end}}
-		  address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex({{.Type}}Bin), backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
+		  address, tx, contract, err := bind.DeployContract(auth, parsed, common.FromHex(bin), backend {{range .Constructor.Inputs}}, {{.Name}}{{end}})
 		  if err != nil {
 		    return common.Address{}, nil, nil, err
 		  }
+		  {{if .Errors}}contract.SetErrorDecoder(Unpack{{.Type}}Error){{end}}
 		  return address, tx, &{{.Type}}{ {{.Type}}Caller: {{.Type}}Caller{contract: contract}, {{.Type}}Transactor: {{.Type}}Transactor{contract: contract}, {{.Type}}Filterer: {{.Type}}Filterer{contract: contract} }, nil
 		}
 	{{end}}
@@ -244,9 +286,37 @@ var (
 	  if err != nil {
 	    return nil, err
 	  }
-	  return bind.NewBoundContract(address, parsed, caller, transactor, filterer), nil
+	  contract := bind.NewBoundContract(address, parsed, caller, transactor, filterer)
+	  {{if .Errors}}contract.SetErrorDecoder(Unpack{{.Type}}Error){{end}}
+	  return contract, nil
 	}
 
+	{{if .Errors}}
+		// Unpack{{.Type}}Error decodes revert data raised by the {{.Type}} contract into the matching
+		// typed error, returning nil if the data doesn't match any of its errors.
+		func Unpack{{.Type}}Error(data []byte) error {
+		  parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
+		  if err != nil {
+		    return err
+		  }
+		  e, err := parsed.ErrorByID(data)
+		  if err != nil {
+		    return nil
+		  }
+		  switch e.Name {
+		  {{range .Errors}}
+		    case "{{.Original.Name}}":
+		      out := new({{$contract.Type}}{{.Normalized.Name}}Error)
+		      if err := parsed.UnpackError(out, "{{.Original.Name}}", data); err != nil {
+		        return err
+		      }
+		      return out
+		  {{end}}
+		  }
+		  return nil
+		}
+	{{end}}
+
 	// Call invokes the (constant) contract method with params as input values and
 	// sets the output to result. The result type might be a single field for simple
 	// returns, a slice of interfaces for anonymous returns and a struct for named
@@ -352,7 +422,8 @@ var (
 	{{end}}
 
 	{{range .Events}}
-		// {{$contract.Type}}{{.Normalized.Name}}Iterator is returned from Filter{{.Normalized.Name}} and is used to iterate over the raw logs and unpacked data for {{.Normalized.Name}} events raised by the {{$contract.Type}} contract.
+		// {{$contract.Type}}{{.Normalized.Name}}Iterator is returned from Filter{{.Normalized.Name}} and Iterate{{.Normalized.Name}} and is used to iterate over the raw logs and unpacked data for {{.Normalized.Name}} events raised by the {{$contract.Type}} contract.
+		// Events reorged out of the chain are delivered again with Raw.Removed set.
 		type {{$contract.Type}}{{.Normalized.Name}}Iterator struct {
 			Event *{{$contract.Type}}{{.Normalized.Name}} // Event containing the contract specifics and raw log
 
@@ -481,6 +552,24 @@ var (
 			}), nil
 		}
 
+		// Iterate{{.Normalized.Name}} is a free log subscription operation binding the contract event 0x{{printf "%x" .Original.ID}},
+		// returning an iterator which blocks until further events arrive.
+		//
+		// Solidity: {{formatevent .Original $structs}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Filterer) Iterate{{.Normalized.Name}}(opts *bind.WatchOpts{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}} []{{bindtype .Type $structs}}{{end}}{{end}}) (*{{$contract.Type}}{{.Normalized.Name}}Iterator, error) {
+			{{range .Normalized.Inputs}}
+			{{if .Indexed}}var {{.Name}}Rule []interface{}
+			for _, {{.Name}}Item := range {{.Name}} {
+				{{.Name}}Rule = append({{.Name}}Rule, {{.Name}}Item)
+			}{{end}}{{end}}
+
+			logs, sub, err := _{{$contract.Type}}.contract.WatchLogs(opts, "{{.Original.Name}}"{{range .Normalized.Inputs}}{{if .Indexed}}, {{.Name}}Rule{{end}}{{end}})
+			if err != nil {
+				return nil, err
+			}
+			return &{{$contract.Type}}{{.Normalized.Name}}Iterator{contract: _{{$contract.Type}}.contract, event: "{{.Original.Name}}", logs: logs, sub: sub}, nil
+		}
+
 		// Parse{{.Normalized.Name}} is a log parse operation binding the contract event 0x{{printf "%x" .Original.ID}}.
 		//
 		// Solidity: {{.Original.String}}
@@ -493,6 +582,20 @@ var (
 		}
 
  	{{end}}
+
+	{{range .Errors}}
+		// {{$contract.Type}}{{.Normalized.Name}}Error represents a {{.Original.RawName}} error raised by the {{$contract.Type}} contract.
+		//
+		// Solidity: {{.Original.String}}
+		type {{$contract.Type}}{{.Normalized.Name}}Error struct { {{range .Normalized.Inputs}}
+			{{capitalise .Name}} {{bindtype .Type $structs}}; {{end}}
+		}
+
+		// Error implements the error interface, formatting the error like a Solidity call.
+		func (e *{{$contract.Type}}{{.Normalized.Name}}Error) Error() string {
+			return fmt.Sprintf("{{.Original.RawName}}({{range $i, $_ := .Normalized.Inputs}}{{if $i}}, {{end}}%v{{end}})"{{range .Normalized.Inputs}}, e.{{capitalise .Name}}{{if eq (bindtype .Type $structs) "common.Address"}}.Hex(){{end}}{{end}})
+		}
+	{{end}}
 {{end}}
 `
 
@@ -0,0 +1,120 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+)
+
+// Record is an in-flight transaction tracked by a manager, along with all the
+// versions of it broadcast so far.
+type Record struct {
+	Nonce    uint64               `json:"nonce"`
+	Sent     []*types.Transaction `json:"sent"`     // Broadcast versions, the latest last
+	LastSent time.Time            `json:"lastSent"` // Time of the last broadcast
+}
+
+// latest returns the most recently broadcast version of the transaction.
+func (r *Record) latest() *types.Transaction {
+	return r.Sent[len(r.Sent)-1]
+}
+
+// Store persists the in-flight transactions of a manager, allowing them to be
+// tracked across restarts.
+type Store interface {
+	// Load returns the stored records, or nil if none are stored.
+	Load() ([]*Record, error)
+
+	// Save replaces the stored records.
+	Save(records []*Record) error
+}
+
+// MemoryStore is a Store keeping the records in memory.
+type MemoryStore struct {
+	records []*Record
+}
+
+// Load implements Store, returning the last saved records.
+func (s *MemoryStore) Load() ([]*Record, error) {
+	return s.records, nil
+}
+
+// Save implements Store, retaining a copy of the record list.
+func (s *MemoryStore) Save(records []*Record) error {
+	s.records = make([]*Record, len(records))
+	for i, record := range records {
+		cpy := *record
+		cpy.Sent = append([]*types.Transaction(nil), record.Sent...)
+		s.records[i] = &cpy
+	}
+	return nil
+}
+
+// FileStore is a Store keeping the records in a JSON file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store persisting records into the file at the given path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements Store, reading the records from the file. A missing file is
+// treated as an empty store.
+func (s *FileStore) Load() ([]*Record, error) {
+	blob, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	if err := json.Unmarshal(blob, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Save implements Store, atomically replacing the file contents.
+func (s *FileStore) Save(records []*Record) error {
+	blob, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,416 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package txmgr implements a transaction manager which sends transactions from a
+// single account, allocating nonces locally and making sure every transaction
+// eventually gets mined by resubmitting stuck ones with a higher gas price.
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/log"
+)
+
+const (
+	defaultResubmitInterval = time.Minute
+	defaultPollInterval     = 5 * time.Second
+	defaultPriceBump        = 10 // Minimum price bump required by the transaction pool for replacements
+)
+
+var (
+	// ErrReplaced is reported for transactions whose nonce was used by a transaction
+	// not sent through the manager.
+	ErrReplaced = errors.New("nonce used by another transaction")
+
+	// ErrGasPriceTooHigh is returned if a transaction is requested with a gas price
+	// above the configured cap.
+	ErrGasPriceTooHigh = errors.New("gas price above cap")
+)
+
+// Backend wraps the methods needed to send and track transactions.
+type Backend interface {
+	bind.ContractTransactor
+
+	// NonceAt returns the account nonce at the given block, the latest if nil.
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+
+	// TransactionReceipt returns the receipt of a mined transaction.
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+
+	// HeaderByNumber returns a block header from the current canonical chain. If
+	// number is nil, the latest known header is returned.
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Config contains the settings of a transaction manager.
+type Config struct {
+	Signer           types.Signer  // Signer used for transactions (nil = homestead, like bindings)
+	Confirmations    uint64        // Number of blocks on top of the including one before a transaction is final
+	ResubmitInterval time.Duration // Time to wait for inclusion before replacing a transaction (0 = 1 minute)
+	PriceBump        uint64        // Percentage the gas price is raised by on replacement (0 = 10%)
+	MaxGasPrice      *big.Int      // Cap of the gas price of sent transactions (nil = uncapped)
+	PollInterval     time.Duration // Interval of checking in-flight transactions (0 = 5 seconds)
+	Store            Store         // Storage of the in-flight transactions (nil = in memory)
+
+	// Notify is called with the final status of every transaction, including ones
+	// restored from the store after a restart.
+	Notify func(*Result)
+}
+
+// Request describes a transaction to be sent by the manager.
+type Request struct {
+	To       *common.Address // Recipient of the transaction, nil for contract creation
+	Value    *big.Int        // Funds to transfer along the transaction (nil = 0)
+	GasLimit uint64          // Gas limit of the transaction (0 = estimate)
+	GasPrice *big.Int        // Initial gas price of the transaction, at most the cap (nil = gas price oracle)
+	Data     []byte          // Input data of the transaction
+
+	// Callback is called with the final status of the transaction. It is not
+	// retained across restarts.
+	Callback func(*Result)
+}
+
+// Result is the final status of a transaction sent by the manager.
+type Result struct {
+	Tx      *types.Transaction // Version of the transaction mined, or the last one sent
+	Receipt *types.Receipt     // Receipt of the mined transaction, nil on failure
+	Err     error              // Reason the transaction didn't get mined
+}
+
+// tracked is an in-flight transaction awaiting its final status.
+type tracked struct {
+	record   *Record
+	callback func(*Result)
+	consumed *big.Int // Head at which the nonce was found used by an unknown transaction
+}
+
+// Manager sends transactions from a single account and tracks them until they
+// are final. Nonces are allocated locally, so the manager must be the only one
+// sending transactions from the account.
+type Manager struct {
+	backend Backend
+	opts    *bind.TransactOpts // Sending account and its signer
+	config  Config
+
+	lock    sync.Mutex
+	nonce   uint64              // Next nonce to allocate
+	synced  bool                // Whether the next nonce is known
+	tracked map[uint64]*tracked // In-flight transactions keyed by nonce
+}
+
+// New creates a transaction manager sending transactions from the account of the
+// given transactor, restoring the in-flight transactions from the configured store.
+func New(backend Backend, opts *bind.TransactOpts, config Config) (*Manager, error) {
+	if config.Signer == nil {
+		config.Signer = types.HomesteadSigner{}
+	}
+	if config.ResubmitInterval <= 0 {
+		config.ResubmitInterval = defaultResubmitInterval
+	}
+	if config.PriceBump == 0 {
+		config.PriceBump = defaultPriceBump
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Store == nil {
+		config.Store = new(MemoryStore)
+	}
+	records, err := config.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		backend: backend,
+		opts:    opts,
+		config:  config,
+		tracked: make(map[uint64]*tracked),
+	}
+	for _, record := range records {
+		if len(record.Sent) > 0 {
+			m.tracked[record.Nonce] = &tracked{record: record}
+		}
+	}
+	return m, nil
+}
+
+// Pending returns the number of in-flight transactions.
+func (m *Manager) Pending() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.tracked)
+}
+
+// Send creates, signs and broadcasts a transaction with the next nonce of the
+// account, tracking it until it's final. Sends are serialised, the nonce is only
+// consumed if the transaction was accepted by the backend.
+func (m *Manager) Send(ctx context.Context, req Request) (*types.Transaction, error) {
+	if req.GasPrice != nil && m.config.MaxGasPrice != nil && req.GasPrice.Cmp(m.config.MaxGasPrice) > 0 {
+		return nil, ErrGasPriceTooHigh
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if !m.synced {
+		nonce, err := m.backend.PendingNonceAt(ctx, m.opts.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve account nonce: %v", err)
+		}
+		// Transactions dropped by the pool still hold their nonces
+		for n := range m.tracked {
+			if n >= nonce {
+				nonce = n + 1
+			}
+		}
+		m.nonce, m.synced = nonce, true
+	}
+	gasPrice := req.GasPrice
+	if gasPrice == nil {
+		price, err := m.backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+		}
+		gasPrice = m.capPrice(price)
+	}
+	gasLimit := req.GasLimit
+	if gasLimit == 0 {
+		msg := ethereum.CallMsg{From: m.opts.From, To: req.To, GasPrice: gasPrice, Value: req.Value, Data: req.Data}
+		estimate, err := m.backend.EstimateGas(ctx, msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate gas needed: %v", err)
+		}
+		gasLimit = estimate
+	}
+	value := req.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	tx, err := m.sign(newTransaction(m.nonce, req.To, value, gasLimit, gasPrice, req.Data))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.backend.SendTransaction(ctx, tx); err != nil {
+		m.synced = false // The local nonce might be stale, resync on the next send
+		return nil, err
+	}
+	m.nonce++
+	m.tracked[tx.Nonce()] = &tracked{
+		record:   &Record{Nonce: tx.Nonce(), Sent: []*types.Transaction{tx}, LastSent: time.Now()},
+		callback: req.Callback,
+	}
+	m.save()
+	return tx, nil
+}
+
+// Run tracks the in-flight transactions until the context is cancelled, reporting
+// the final ones and replacing the ones not mined in time.
+func (m *Manager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// check updates the status of all in-flight transactions.
+func (m *Manager) check(ctx context.Context) {
+	m.lock.Lock()
+	pending := make([]*tracked, 0, len(m.tracked))
+	for _, t := range m.tracked {
+		pending = append(pending, t)
+	}
+	m.lock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].record.Nonce < pending[j].record.Nonce })
+
+	head, err := m.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Debug("Failed to retrieve chain head", "err", err)
+		return
+	}
+	var confirmed *uint64 // Nonce of the account at the head, retrieved on demand
+	for _, t := range pending {
+		receipt, tx, err := m.receipt(ctx, t.record)
+		if err != nil {
+			log.Debug("Failed to retrieve transaction receipt", "nonce", t.record.Nonce, "err", err)
+			continue
+		}
+		if receipt != nil {
+			t.consumed = nil
+			if head.Number.Uint64() >= receipt.BlockNumber.Uint64()+m.config.Confirmations {
+				m.finish(t, &Result{Tx: tx, Receipt: receipt})
+			}
+			continue
+		}
+		if confirmed == nil {
+			nonce, err := m.backend.NonceAt(ctx, m.opts.From, nil)
+			if err != nil {
+				log.Debug("Failed to retrieve account nonce", "err", err)
+				return
+			}
+			confirmed = &nonce
+		}
+		if *confirmed > t.record.Nonce {
+			// The nonce is used, but not by any of our versions. Give the receipt a
+			// block to show up in case it wasn't indexed yet.
+			if t.consumed == nil {
+				t.consumed = head.Number
+			}
+			if head.Number.Uint64() > t.consumed.Uint64()+m.config.Confirmations {
+				m.finish(t, &Result{Tx: t.record.latest(), Err: ErrReplaced})
+			}
+			continue
+		}
+		t.consumed = nil
+		if time.Since(t.record.LastSent) >= m.config.ResubmitInterval {
+			m.resubmit(ctx, t)
+		}
+	}
+}
+
+// receipt looks up the receipt of any version of the transaction, preferring the
+// latest ones.
+func (m *Manager) receipt(ctx context.Context, record *Record) (*types.Receipt, *types.Transaction, error) {
+	for i := len(record.Sent) - 1; i >= 0; i-- {
+		tx := record.Sent[i]
+		receipt, err := m.backend.TransactionReceipt(ctx, tx.Hash())
+		if err == ethereum.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if receipt != nil {
+			return receipt, tx, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// resubmit replaces a transaction not mined in time with one paying a higher gas
+// price, or rebroadcasts it if the price can't be raised any further.
+func (m *Manager) resubmit(ctx context.Context, t *tracked) {
+	last := t.record.latest()
+
+	price := new(big.Int).Mul(last.GasPrice(), new(big.Int).SetUint64(100+m.config.PriceBump))
+	price.Div(price, big.NewInt(100))
+	if price.Cmp(last.GasPrice()) <= 0 {
+		price.Add(last.GasPrice(), common.Big1)
+	}
+	if suggested, err := m.backend.SuggestGasPrice(ctx); err == nil && suggested.Cmp(price) > 0 {
+		price = suggested
+	}
+	price = m.capPrice(price)
+
+	tx := last
+	if price.Cmp(last.GasPrice()) > 0 {
+		replacement, err := m.sign(newTransaction(last.Nonce(), last.To(), last.Value(), last.Gas(), price, last.Data()))
+		if err != nil {
+			log.Warn("Failed to sign replacement transaction", "nonce", last.Nonce(), "err", err)
+			return
+		}
+		tx = replacement
+	}
+	if err := m.backend.SendTransaction(ctx, tx); err != nil {
+		log.Debug("Failed to resubmit transaction", "hash", tx.Hash(), "nonce", tx.Nonce(), "err", err)
+	} else {
+		log.Info("Resubmitted transaction", "hash", tx.Hash(), "nonce", tx.Nonce(), "gasprice", tx.GasPrice())
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// Track the replacement even if the broadcast failed, it might still get mined
+	// and bumping further from it is needed either way.
+	if tx != last {
+		t.record.Sent = append(t.record.Sent, tx)
+	}
+	t.record.LastSent = time.Now()
+	m.save()
+}
+
+// finish stops tracking a transaction and reports its final status.
+func (m *Manager) finish(t *tracked, result *Result) {
+	m.lock.Lock()
+	delete(m.tracked, t.record.Nonce)
+	m.save()
+	m.lock.Unlock()
+
+	if t.callback != nil {
+		t.callback(result)
+	}
+	if m.config.Notify != nil {
+		m.config.Notify(result)
+	}
+}
+
+// save persists the in-flight transactions. The caller must hold the lock.
+func (m *Manager) save() {
+	records := make([]*Record, 0, len(m.tracked))
+	for _, t := range m.tracked {
+		records = append(records, t.record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Nonce < records[j].Nonce })
+
+	if err := m.config.Store.Save(records); err != nil {
+		log.Warn("Failed to persist in-flight transactions", "err", err)
+	}
+}
+
+// sign signs the transaction with the key of the sending account.
+func (m *Manager) sign(tx *types.Transaction) (*types.Transaction, error) {
+	if m.opts.Signer == nil {
+		return nil, errors.New("no signer to authorize the transaction with")
+	}
+	return m.opts.Signer(m.config.Signer, m.opts.From, tx)
+}
+
+// capPrice limits the gas price to the configured maximum.
+func (m *Manager) capPrice(price *big.Int) *big.Int {
+	if m.config.MaxGasPrice != nil && price.Cmp(m.config.MaxGasPrice) > 0 {
+		return new(big.Int).Set(m.config.MaxGasPrice)
+	}
+	return price
+}
+
+// newTransaction creates an unsigned transaction, a contract creation if there's
+// no recipient.
+func newTransaction(nonce uint64, to *common.Address, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *types.Transaction {
+	if to == nil {
+		return types.NewContractCreation(nonce, value, gasLimit, gasPrice, data)
+	}
+	return types.NewTransaction(nonce, *to, value, gasLimit, gasPrice, data)
+}
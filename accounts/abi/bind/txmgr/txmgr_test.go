@@ -0,0 +1,345 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txmgr
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// testBackend is a Backend emulating a node whose transaction pool only mines
+// transactions paying a minimum gas price, and whose pending nonce doesn't
+// account for pooled transactions.
+type testBackend struct {
+	lock     sync.Mutex
+	head     uint64
+	nonce    uint64                         // Nonce of the account at the head
+	minPrice *big.Int                       // Minimum gas price of mined transactions
+	pool     map[uint64]*types.Transaction  // Pooled transactions keyed by nonce
+	receipts map[common.Hash]*types.Receipt // Receipts of mined transactions
+}
+
+func newTestBackend(minPrice int64) *testBackend {
+	return &testBackend{
+		minPrice: big.NewInt(minPrice),
+		pool:     make(map[uint64]*types.Transaction),
+		receipts: make(map[common.Hash]*types.Receipt),
+	}
+}
+
+func (b *testBackend) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *testBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return b.NonceAt(ctx, account, nil)
+}
+
+func (b *testBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(10), nil
+}
+
+func (b *testBackend) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 21000, nil
+}
+
+func (b *testBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if tx.Nonce() < b.nonce {
+		return errors.New("nonce too low")
+	}
+	if old := b.pool[tx.Nonce()]; old != nil && old.Hash() != tx.Hash() && old.GasPrice().Cmp(tx.GasPrice()) >= 0 {
+		return errors.New("replacement transaction underpriced")
+	}
+	b.pool[tx.Nonce()] = tx
+	return nil
+}
+
+func (b *testBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.nonce, nil
+}
+
+func (b *testBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if receipt := b.receipts[txHash]; receipt != nil {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return &types.Header{Number: new(big.Int).SetUint64(b.head)}, nil
+}
+
+// mine creates a new block including all minable transactions.
+func (b *testBackend) mine() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.head++
+	for {
+		tx := b.pool[b.nonce]
+		if tx == nil || tx.GasPrice().Cmp(b.minPrice) < 0 {
+			return
+		}
+		b.receipts[tx.Hash()] = &types.Receipt{
+			Status:      types.ReceiptStatusSuccessful,
+			TxHash:      tx.Hash(),
+			BlockNumber: new(big.Int).SetUint64(b.head),
+		}
+		delete(b.pool, b.nonce)
+		b.nonce++
+	}
+}
+
+// resultSink collects reported transaction results.
+type resultSink struct {
+	lock    sync.Mutex
+	results []*Result
+}
+
+func (s *resultSink) add(result *Result) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.results = append(s.results, result)
+}
+
+func newTestTransactor() *bind.TransactOpts {
+	key, _ := crypto.GenerateKey()
+	return bind.NewKeyedTransactor(key)
+}
+
+// Tests that nonces are allocated locally and transactions are reported once
+// they have enough confirmations.
+func TestSend(t *testing.T) {
+	var (
+		backend = newTestBackend(0)
+		sink    = new(resultSink)
+		to      = common.HexToAddress("0x01")
+	)
+	m, err := New(backend, newTestTransactor(), Config{Confirmations: 1})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		tx, err := m.Send(context.Background(), Request{To: &to, Callback: sink.add})
+		if err != nil {
+			t.Fatalf("send %d: failed: %v", i, err)
+		}
+		if tx.Nonce() != uint64(i) {
+			t.Fatalf("send %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), i)
+		}
+	}
+	backend.mine()
+	m.check(context.Background())
+	if len(sink.results) != 0 {
+		t.Fatalf("transactions reported before confirmation")
+	}
+	backend.mine()
+	m.check(context.Background())
+	if len(sink.results) != 3 {
+		t.Fatalf("reported transaction count mismatch: have %d, want 3", len(sink.results))
+	}
+	for i, result := range sink.results {
+		if result.Err != nil || result.Receipt == nil || result.Receipt.TxHash != result.Tx.Hash() {
+			t.Errorf("result %d: mismatch: %+v", i, result)
+		}
+	}
+	if pending := m.Pending(); pending != 0 {
+		t.Errorf("pending transaction count mismatch: have %d, want 0", pending)
+	}
+}
+
+// Tests that explicit gas prices above the cap are refused.
+func TestSendPriceCap(t *testing.T) {
+	var (
+		backend = newTestBackend(0)
+		to      = common.HexToAddress("0x01")
+	)
+	m, err := New(backend, newTestTransactor(), Config{MaxGasPrice: big.NewInt(20)})
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if _, err := m.Send(context.Background(), Request{To: &to, GasPrice: big.NewInt(21)}); err != ErrGasPriceTooHigh {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrGasPriceTooHigh)
+	}
+	tx, err := m.Send(context.Background(), Request{To: &to, GasPrice: big.NewInt(20)})
+	if err != nil {
+		t.Fatalf("failed to send at the cap: %v", err)
+	}
+	if tx.Nonce() != 0 {
+		t.Errorf("nonce consumed by refused transaction: have %d, want 0", tx.Nonce())
+	}
+}
+
+// Tests that stuck transactions are replaced with increasing gas prices until
+// they get mined, respecting the price cap.
+func TestResubmit(t *testing.T) {
+	var (
+		backend = newTestBackend(15)
+		sink    = new(resultSink)
+		to      = common.HexToAddress("0x01")
+	)
+	m, _ := New(backend, newTestTransactor(), Config{ResubmitInterval: time.Nanosecond, MaxGasPrice: big.NewInt(13)})
+	first, err := m.Send(context.Background(), Request{To: &to, Callback: sink.add})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	// Bump the price until it hits the cap, rebroadcasting afterwards
+	for _, want := range []int64{11, 12, 13, 13} {
+		m.check(context.Background())
+		backend.mine()
+
+		if price := backend.pool[0].GasPrice(); price.Int64() != want {
+			t.Fatalf("gas price mismatch: have %v, want %d", price, want)
+		}
+	}
+	if sent := len(m.tracked[0].record.Sent); sent != 4 {
+		t.Fatalf("sent version count mismatch: have %d, want 4", sent)
+	}
+	// Lift the cap, the replacements should eventually get mined
+	m.config.MaxGasPrice = nil
+	for i := 0; i < 3; i++ {
+		m.check(context.Background())
+		backend.mine()
+	}
+
+	if len(sink.results) != 1 {
+		t.Fatalf("reported transaction count mismatch: have %d, want 1", len(sink.results))
+	}
+	result := sink.results[0]
+	if result.Receipt == nil || result.Tx.Hash() == first.Hash() || result.Tx.GasPrice().Int64() != 15 {
+		t.Fatalf("mined transaction mismatch: %+v", result)
+	}
+}
+
+// Tests that in-flight transactions are restored from the store and accounted
+// for when allocating nonces.
+func TestRestore(t *testing.T) {
+	var (
+		backend = newTestBackend(0)
+		store   = new(MemoryStore)
+		opts    = newTestTransactor()
+		to      = common.HexToAddress("0x01")
+	)
+	m, _ := New(backend, opts, Config{Store: store})
+	if _, err := m.Send(context.Background(), Request{To: &to}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	// Drop the transaction from the pool to check it's resubmitted after restart
+	delete(backend.pool, 0)
+
+	sink := new(resultSink)
+	m, _ = New(backend, opts, Config{Store: store, Notify: sink.add, ResubmitInterval: time.Nanosecond})
+	if pending := m.Pending(); pending != 1 {
+		t.Fatalf("restored transaction count mismatch: have %d, want 1", pending)
+	}
+	tx, err := m.Send(context.Background(), Request{To: &to})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if tx.Nonce() != 1 {
+		t.Fatalf("nonce mismatch: have %d, want 1", tx.Nonce())
+	}
+	m.check(context.Background())
+	backend.mine()
+	m.check(context.Background())
+
+	if len(sink.results) != 2 {
+		t.Fatalf("reported transaction count mismatch: have %d, want 2", len(sink.results))
+	}
+	if records, _ := store.Load(); len(records) != 0 {
+		t.Fatalf("stored record count mismatch: have %d, want 0", len(records))
+	}
+}
+
+// Tests that transactions whose nonce gets used by an unknown transaction are
+// reported as replaced.
+func TestReplaced(t *testing.T) {
+	var (
+		backend = newTestBackend(100)
+		sink    = new(resultSink)
+		to      = common.HexToAddress("0x01")
+	)
+	m, _ := New(backend, newTestTransactor(), Config{Notify: sink.add})
+	if _, err := m.Send(context.Background(), Request{To: &to}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	backend.nonce, backend.head = 1, 1
+
+	m.check(context.Background())
+	if len(sink.results) != 0 {
+		t.Fatalf("transaction reported replaced before receipt grace period")
+	}
+	backend.mine()
+	m.check(context.Background())
+	if len(sink.results) != 1 || sink.results[0].Err != ErrReplaced {
+		t.Fatalf("replacement not reported: %v", sink.results)
+	}
+}
+
+// Tests that the file store round trips records.
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "txmgr-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileStore(filepath.Join(dir, "txs.json"))
+	if records, err := store.Load(); err != nil || records != nil {
+		t.Fatalf("empty store mismatch: %v, %v", records, err)
+	}
+	opts := newTestTransactor()
+	tx, _ := opts.Signer(types.HomesteadSigner{}, opts.From, types.NewTransaction(3, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(10), nil))
+
+	sent := time.Unix(1000, 0).UTC()
+	if err := store.Save([]*Record{{Nonce: 3, Sent: []*types.Transaction{tx}, LastSent: sent}}); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	records, err := store.Load()
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if len(records) != 1 || records[0].Nonce != 3 || !records[0].LastSent.Equal(sent) || records[0].Sent[0].Hash() != tx.Hash() {
+		t.Fatalf("loaded records mismatch: %+v", records)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/event"
+)
+
+// resubscribeBackoff is the maximum time waited between attempts to reestablish
+// a failed log subscription.
+const resubscribeBackoff = 30 * time.Second
+
+// filterEnd resolves the end of a filtered block range, retrieving the chain head
+// if the range is open.
+func (c *BoundContract) filterEnd(ctx context.Context, end *uint64) (uint64, error) {
+	if end != nil {
+		return *end, nil
+	}
+	reader, ok := c.filterer.(HeaderReader)
+	if !ok {
+		return 0, ErrNoHeaderReader
+	}
+	head, err := reader.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return head.Number.Uint64(), nil
+}
+
+// filterRange queries the logs of the blocks from start to end inclusive in
+// windows of the given size, passing them in order to deliver. Filtering stops
+// early if deliver returns false.
+func (c *BoundContract) filterRange(ctx context.Context, query ethereum.FilterQuery, start, end, size uint64, deliver func(types.Log) bool) error {
+	for from := start; from <= end; {
+		to := from + size - 1
+		if to > end || to < from {
+			to = end
+		}
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = new(big.Int).SetUint64(to)
+
+		logs, err := c.filterer.FilterLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if !deliver(log) {
+				return nil
+			}
+		}
+		if to == end {
+			break
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// logPosition is the position of a log within the chain.
+type logPosition struct {
+	block uint64
+	index uint
+}
+
+// after checks whether the log is positioned after p.
+func (p logPosition) after(log types.Log) bool {
+	return log.BlockNumber > p.block || (log.BlockNumber == p.block && log.Index > p.index)
+}
+
+// before returns the position directly preceding the log.
+func before(log types.Log) logPosition {
+	if log.Index == 0 {
+		return logPosition{block: log.BlockNumber - 1, index: ^uint(0)}
+	}
+	return logPosition{block: log.BlockNumber, index: log.Index - 1}
+}
+
+// watchLogsResumable keeps a log subscription established, backfilling the logs
+// missed while it was down. Logs are delivered in chain order and at most once,
+// except when they are reorged out and in again. Logs reorged out are delivered
+// with their Removed flag set.
+//
+// The subscription is opened before backfilling, so no logs are lost between the
+// two. Reorgs happening while the subscription is down are not reported.
+func (c *BoundContract) watchLogsResumable(opts *WatchOpts, query ethereum.FilterQuery, sink chan<- types.Log) event.Subscription {
+	var (
+		next *uint64      // First block to backfill from, nil if unknown
+		last *logPosition // Position of the last delivered log
+	)
+	if opts.Start != nil {
+		start := *opts.Start
+		next = &start
+	}
+	return event.Resubscribe(resubscribeBackoff, func(ctx context.Context) (event.Subscription, error) {
+		live := make(chan types.Log, 128)
+		sub, err := c.filterer.SubscribeFilterLogs(ctx, query, live)
+		if err != nil {
+			return nil, err
+		}
+		// Determine the range to backfill, watching from the current head on the
+		// first subscription without a start block.
+		var head *uint64
+		if reader, ok := c.filterer.(HeaderReader); ok {
+			header, err := reader.HeaderByNumber(ctx, nil)
+			if err != nil {
+				sub.Unsubscribe()
+				return nil, err
+			}
+			number := header.Number.Uint64()
+			head = &number
+		}
+		if next == nil && head != nil {
+			start := *head + 1
+			next = &start
+		}
+		return event.NewSubscription(func(quit <-chan struct{}) error {
+			defer sub.Unsubscribe()
+
+			deliver := func(log types.Log) bool {
+				if !log.Removed && last != nil && !last.after(log) {
+					return true // Already delivered
+				}
+				select {
+				case sink <- log:
+				case <-quit:
+					return false
+				}
+				if log.Removed {
+					// Deliver the logs replacing the removed one again
+					pos := before(log)
+					if last != nil && !last.after(log) {
+						last = &pos
+					}
+					if next != nil && log.BlockNumber < *next {
+						block := log.BlockNumber
+						next = &block
+					}
+					return true
+				}
+				last = &logPosition{block: log.BlockNumber, index: log.Index}
+				if next == nil || log.BlockNumber > *next {
+					block := log.BlockNumber
+					next = &block
+				}
+				return true
+			}
+			if next != nil {
+				var err error
+				switch {
+				case opts.BlockRange > 0 && head != nil:
+					err = c.filterRange(ensureContext(opts.Context), query, *next, *head, opts.BlockRange, deliver)
+				default:
+					query := query
+					query.FromBlock = new(big.Int).SetUint64(*next)
+					var logs []types.Log
+					if logs, err = c.filterer.FilterLogs(ensureContext(opts.Context), query); err == nil {
+						for _, log := range logs {
+							if !deliver(log) {
+								return nil
+							}
+						}
+					}
+				}
+				if err != nil {
+					return err
+				}
+				if head != nil && *head+1 > *next {
+					block := *head + 1
+					next = &block
+				}
+			}
+			for {
+				select {
+				case log := <-live:
+					if !deliver(log) {
+						return nil
+					}
+				case err := <-sub.Err():
+					return err
+				case <-quit:
+					return nil
+				}
+			}
+		}), nil
+	})
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/event"
+)
+
+// mockFilterer is a ContractFilterer over a list of logs, whose subscriptions are
+// driven by the test.
+type mockFilterer struct {
+	lock    sync.Mutex
+	logs    []types.Log
+	head    uint64
+	queries int
+
+	subs chan *mockLogSub
+}
+
+type mockLogSub struct {
+	sink chan<- types.Log
+	fail chan error
+}
+
+func (f *mockFilterer) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.queries++
+	to := f.head
+	if query.ToBlock != nil {
+		to = query.ToBlock.Uint64()
+	}
+	var logs []types.Log
+	for _, log := range f.logs {
+		if log.BlockNumber >= query.FromBlock.Uint64() && log.BlockNumber <= to {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (f *mockFilterer) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub := &mockLogSub{sink: ch, fail: make(chan error, 1)}
+	f.subs <- sub
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		select {
+		case err := <-sub.fail:
+			return err
+		case <-quit:
+			return nil
+		}
+	}), nil
+}
+
+func (f *mockFilterer) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &types.Header{Number: new(big.Int).SetUint64(f.head)}, nil
+}
+
+// add appends a log to the chain, moving the head to its block.
+func (f *mockFilterer) add(log types.Log) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.logs = append(f.logs, log)
+	f.head = log.BlockNumber
+}
+
+func testLog(block uint64, index uint, tx byte) types.Log {
+	return types.Log{BlockNumber: block, Index: index, TxHash: common.Hash{tx}}
+}
+
+func TestFilterLogsPaginated(t *testing.T) {
+	filterer := &mockFilterer{}
+	for i := uint64(1); i <= 10; i++ {
+		filterer.add(testLog(i, 0, byte(i)))
+	}
+	contract := NewBoundContract(common.Address{}, abi.ABI{Events: map[string]abi.Event{"E": {Name: "E"}}}, nil, nil, filterer)
+
+	logs, sub, err := contract.FilterLogs(&FilterOpts{Start: 2, BlockRange: 3}, "E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	for want := uint64(2); want <= 10; want++ {
+		if log := <-logs; log.BlockNumber != want {
+			t.Fatalf("wrong log: got block %d, want %d", log.BlockNumber, want)
+		}
+	}
+	if err := <-sub.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if filterer.queries != 3 {
+		t.Errorf("wrong number of queries: got %d, want 3", filterer.queries)
+	}
+}
+
+func TestWatchLogsResumable(t *testing.T) {
+	filterer := &mockFilterer{subs: make(chan *mockLogSub)}
+	filterer.add(testLog(1, 0, 1))
+	filterer.add(testLog(2, 0, 2))
+	contract := NewBoundContract(common.Address{}, abi.ABI{Events: map[string]abi.Event{"E": {Name: "E"}}}, nil, nil, filterer)
+
+	start := uint64(0)
+	logs, sub, err := contract.WatchLogs(&WatchOpts{Start: &start, Resume: true}, "E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(want types.Log) {
+		t.Helper()
+		select {
+		case log := <-logs:
+			if log.BlockNumber != want.BlockNumber || log.TxHash != want.TxHash || log.Removed != want.Removed {
+				t.Fatalf("wrong log: got %+v, want %+v", log, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for log %+v", want)
+		}
+	}
+	// Past logs are backfilled, then live ones delivered.
+	live := <-filterer.subs
+	expect(testLog(1, 0, 1))
+	expect(testLog(2, 0, 2))
+	filterer.add(testLog(3, 0, 3))
+	live.sink <- testLog(3, 0, 3)
+	expect(testLog(3, 0, 3))
+
+	// Logs missed while the subscription is down are backfilled without duplicates.
+	filterer.add(testLog(4, 0, 4))
+	live.fail <- errors.New("connection lost")
+	live = <-filterer.subs
+	expect(testLog(4, 0, 4))
+
+	// Removed logs are delivered, and their replacements too.
+	removed := testLog(4, 0, 4)
+	removed.Removed = true
+	live.sink <- removed
+	expect(removed)
+	live.sink <- testLog(4, 0, 5)
+	expect(testLog(4, 0, 5))
+
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log: %+v", log)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package abi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// Error is a custom error declared in the ABI. Contracts raise it by reverting
+// with the error selector followed by the ABI encoded inputs, the same way a
+// method call is encoded.
+type Error struct {
+	// Name is the error name used for internal representation. It's derived from
+	// the raw name and a suffix will be added in the case of an error overload.
+	Name string
+	// RawName is the raw error name parsed from ABI.
+	RawName string
+	Inputs  Arguments
+}
+
+func (e Error) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+	}
+	return fmt.Sprintf("error %v(%v)", e.RawName, strings.Join(inputs, ", "))
+}
+
+// Sig returns the error string signature according to the ABI spec.
+//
+// Example
+//
+//     error InsufficientBalance(uint a, uint b) = "InsufficientBalance(uint256,uint256)"
+func (e Error) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.RawName, strings.Join(types, ","))
+}
+
+// ID returns the selector prefixing the revert data raising the error.
+func (e Error) ID() []byte {
+	return crypto.Keccak256([]byte(e.Sig()))[:4]
+}
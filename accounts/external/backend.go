@@ -23,6 +23,7 @@ import (
 
 	"github.com/Fantom-foundation/go-ethereum"
 	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/accounts/typeddata"
 	"github.com/Fantom-foundation/go-ethereum/common"
 	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
 	"github.com/Fantom-foundation/go-ethereum/core/types"
@@ -180,6 +181,25 @@ func (api *ExternalSigner) SignText(account accounts.Account, text []byte) ([]by
 	return res, nil
 }
 
+// SignTypedData implements typeddata.Signer, requesting the external signer to
+// sign the typed data so that it can display the individual fields.
+func (api *ExternalSigner) SignTypedData(account accounts.Account, data typeddata.TypedData) ([]byte, error) {
+	var res hexutil.Bytes
+	var signAddress = common.NewMixedcaseAddress(account.Address)
+	if err := api.client.Call(&res, "account_signTypedData",
+		&signAddress, // Need to use the pointer here, because of how MarshalJSON is defined
+		data); err != nil {
+		return nil, err
+	}
+	if len(res) != 65 {
+		return nil, fmt.Errorf("invalid signature length %d", len(res))
+	}
+	if res[64] == 27 || res[64] == 28 {
+		res[64] -= 27 // Transform V from 27/28 to 0/1 as with the other wallets
+	}
+	return res, nil
+}
+
 func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
 	res := ethapi.SignTransactionResult{}
 	data := hexutil.Bytes(tx.Data())
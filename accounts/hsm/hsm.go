@@ -0,0 +1,245 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hsm implements an accounts backend for keys that never leave a remote
+// key store, such as PKCS#11 hardware security modules or cloud key management
+// services. The key store only needs to produce ECDSA signatures over hashes,
+// which are normalized into Ethereum's [R || S || V] format locally.
+package hsm
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/event"
+)
+
+// ErrNotSupported is returned for operations that remote keys cannot support,
+// such as hierarchical derivation.
+var ErrNotSupported = errors.New("not supported by remote keys")
+
+// Key is a secp256k1 key held by a remote key store.
+type Key struct {
+	ID        string           // Identifier of the key within the key store
+	PublicKey *ecdsa.PublicKey // Public key, used to recover the signature's V value
+	Address   common.Address   // Ethereum address derived from the public key
+}
+
+// Signer is the interface of a key store able to sign hashes with secp256k1 keys
+// it holds. Signatures may be returned either ASN.1 DER encoded, or as 64 byte
+// [R || S] or 65 byte [R || S || V] values; the wallet normalizes them.
+type Signer interface {
+	// URL returns the location of the key store.
+	URL() accounts.URL
+
+	// Keys lists the secp256k1 keys available for signing.
+	Keys() ([]*Key, error)
+
+	// Sign signs the 32 byte digest with the given key.
+	Sign(key *Key, digest []byte) ([]byte, error)
+
+	// Close releases any resources held by the signer.
+	Close() error
+}
+
+// Backend is an accounts.Backend exposing each remote key store as a wallet.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates an accounts backend on top of the given key stores.
+func NewBackend(signers ...Signer) *Backend {
+	backend := new(Backend)
+	for _, signer := range signers {
+		backend.wallets = append(backend.wallets, &wallet{signer: signer})
+	}
+	return backend
+}
+
+// Wallets implements accounts.Backend, returning the wallets of all key stores.
+func (b *Backend) Wallets() []accounts.Wallet {
+	return b.wallets
+}
+
+// Subscribe implements accounts.Backend. The set of key stores is fixed, so no
+// events are ever delivered.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// Close releases all key stores of the backend.
+func (b *Backend) Close() error {
+	var failure error
+	for _, w := range b.wallets {
+		if err := w.(*wallet).signer.Close(); err != nil && failure == nil {
+			failure = err
+		}
+	}
+	return failure
+}
+
+// wallet implements accounts.Wallet on top of a remote key store. Remote keys
+// are authorized by the key store itself, so passphrases are ignored.
+type wallet struct {
+	signer Signer
+
+	keys map[common.Address]*Key // Keys seen at the last listing
+	fail error                   // Error of the last listing
+	lock sync.RWMutex
+}
+
+// URL implements accounts.Wallet, returning the location of the key store.
+func (w *wallet) URL() accounts.URL {
+	return w.signer.URL()
+}
+
+// Status implements accounts.Wallet, returning the failure of the last key listing.
+func (w *wallet) Status() (string, error) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.fail != nil {
+		return fmt.Sprintf("Failed: %v", w.fail), w.fail
+	}
+	return "Online", nil
+}
+
+// Open implements accounts.Wallet. The key store is connected on creation, so
+// this is a noop.
+func (w *wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet. The key store stays connected until the
+// backend is closed, so this is a noop.
+func (w *wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, listing the keys of the key store.
+func (w *wallet) Accounts() []accounts.Account {
+	keys, err := w.signer.Keys()
+
+	w.lock.Lock()
+	w.fail = err
+	if err == nil {
+		w.keys = make(map[common.Address]*Key, len(keys))
+		for _, key := range keys {
+			w.keys[key.Address] = key
+		}
+	}
+	w.lock.Unlock()
+
+	accs := make([]accounts.Account, 0, len(keys))
+	for _, key := range keys {
+		accs = append(accs, accounts.Account{Address: key.Address, URL: w.signer.URL()})
+	}
+	return accs
+}
+
+// Contains implements accounts.Wallet, returning whether the account was listed
+// by the key store.
+func (w *wallet) Contains(account accounts.Account) bool {
+	return w.key(account) != nil
+}
+
+// key returns the key of an account, listing the keys if it is not yet known.
+func (w *wallet) key(account accounts.Account) *Key {
+	if account.URL != (accounts.URL{}) && account.URL != w.signer.URL() {
+		return nil
+	}
+	w.lock.RLock()
+	key := w.keys[account.Address]
+	w.lock.RUnlock()
+
+	if key == nil {
+		w.Accounts()
+
+		w.lock.RLock()
+		key = w.keys[account.Address]
+		w.lock.RUnlock()
+	}
+	return key
+}
+
+// Derive implements accounts.Wallet, but is not supported by remote keys.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for remote keys.
+func (w *wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+// signHash signs a hash with the key of the given account, converting the key
+// store's signature into the [R || S || V] format with V being 0 or 1.
+func (w *wallet) signHash(account accounts.Account, hash []byte) ([]byte, error) {
+	key := w.key(account)
+	if key == nil {
+		return nil, accounts.ErrUnknownAccount
+	}
+	sig, err := w.signer.Sign(key, hash)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeSignature(hash, sig, key.PublicKey)
+}
+
+// SignData implements accounts.Wallet, signing keccak256(data).
+func (w *wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(account, crypto.Keccak256(data))
+}
+
+// SignDataWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignText implements accounts.Wallet, signing the hash of the text with the
+// Ethereum message prefix.
+func (w *wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.signHash(account, accounts.TextHash(text))
+}
+
+// SignTextWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with EIP155 replay
+// protection if a chain id is given.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	var signer types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		signer = types.NewEIP155Signer(chainID)
+	}
+	sig, err := w.signHash(account, signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(signer, sig)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, ignoring the passphrase.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
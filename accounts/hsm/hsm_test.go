@@ -0,0 +1,198 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// derSignature signs the digest, returning an ASN.1 DER signature with S in the
+// upper half of the curve order if high is set, as a key store might.
+func derSignature(t *testing.T, key *ecdsa.PrivateKey, digest []byte, high bool) ([]byte, []byte) {
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	s := new(big.Int).SetBytes(sig[32:64])
+	if high {
+		s.Sub(secp256k1N, s)
+	}
+	der, _ := asn1.Marshal(struct{ R, S *big.Int }{new(big.Int).SetBytes(sig[:32]), s})
+	return der, sig
+}
+
+func TestNormalizeSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	digest := crypto.Keccak256([]byte("hello"))
+
+	for _, high := range []bool{false, true} {
+		der, want := derSignature(t, key, digest, high)
+		if have, err := NormalizeSignature(digest, der, &key.PublicKey); err != nil || !bytes.Equal(have, want) {
+			t.Errorf("DER (high S %v): have %x, %v, want %x", high, have, err, want)
+		}
+		if _, err := NormalizeSignature(digest, der, &other.PublicKey); err != ErrInvalidSignature {
+			t.Errorf("DER (high S %v) of other key: have %v, want %v", high, err, ErrInvalidSignature)
+		}
+	}
+	_, want := derSignature(t, key, digest, false)
+	if have, err := NormalizeSignature(digest, want[:64], &key.PublicKey); err != nil || !bytes.Equal(have, want) {
+		t.Errorf("raw: have %x, %v, want %x", have, err, want)
+	}
+	if _, err := NormalizeSignature(digest, []byte{0x30, 0x01}, &key.PublicKey); err != ErrInvalidSignature {
+		t.Errorf("garbage: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	point := crypto.FromECDSAPub(&key.PublicKey)
+
+	octets, _ := asn1.Marshal(point)
+	params, _ := asn1.Marshal(oidSecp256k1)
+	spki, _ := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1},
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	for name, data := range map[string][]byte{
+		"uncompressed": point,
+		"compressed":   crypto.CompressPubkey(&key.PublicKey),
+		"octet string": octets,
+		"spki":         spki,
+	} {
+		pub, err := ParsePublicKey(data)
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", name, err)
+			continue
+		}
+		if crypto.PubkeyToAddress(*pub) != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("%s: key mismatch", name)
+		}
+	}
+	if _, err := ParsePublicKey(point[1:]); err == nil {
+		t.Errorf("truncated key accepted")
+	}
+}
+
+// newRemoteService starts a test signing service holding the given keys and
+// requiring the given bearer token.
+func newRemoteService(t *testing.T, token string, keys ...*ecdsa.PrivateKey) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/keys":
+			var list []remoteKey
+			for _, key := range keys {
+				list = append(list, remoteKey{
+					ID:        hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()),
+					PublicKey: crypto.CompressPubkey(&key.PublicKey),
+				})
+			}
+			json.NewEncoder(w).Encode(list)
+
+		case "/sign":
+			var req remoteSignRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, key := range keys {
+				if hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()) == req.ID {
+					der, _ := derSignature(t, key, req.Digest, true)
+					json.NewEncoder(w).Encode(remoteSignResponse{Signature: der})
+					return
+				}
+			}
+			http.Error(w, "unknown key", http.StatusNotFound)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	service := newRemoteService(t, "secret", key1, key2)
+	defer service.Close()
+
+	if _, err := NewRemoteSigner(service.URL, "wrong"); err == nil {
+		t.Fatalf("unauthorized signer created")
+	}
+	signer, err := NewRemoteSigner(service.URL, "secret")
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	backend := NewBackend(signer)
+	defer backend.Close()
+
+	wallet := backend.Wallets()[0]
+	accs := wallet.Accounts()
+	if len(accs) != 2 || accs[0].Address != crypto.PubkeyToAddress(key1.PublicKey) || accs[1].Address != crypto.PubkeyToAddress(key2.PublicKey) {
+		t.Fatalf("accounts mismatch: %v", accs)
+	}
+	if status, err := wallet.Status(); err != nil {
+		t.Fatalf("wallet failed: %s", status)
+	}
+	// Sign a transaction and make sure the sender is recovered correctly
+	chainID := big.NewInt(1337)
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := wallet.SignTx(accs[1], tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.NewEIP155Signer(chainID), signed); err != nil || sender != accs[1].Address {
+		t.Fatalf("sender mismatch: have %x, %v, want %x", sender, err, accs[1].Address)
+	}
+	// Sign a message and make sure it's recoverable
+	sig, err := wallet.SignText(accs[0], []byte("hello"))
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != accs[0].Address {
+		t.Fatalf("text signer mismatch: %v", err)
+	}
+	// Unknown accounts must be rejected
+	if _, err := wallet.SignText(accounts.Account{Address: common.Address{2}}, []byte("hello")); err != accounts.ErrUnknownAccount {
+		t.Fatalf("unknown account: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
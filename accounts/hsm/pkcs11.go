@@ -0,0 +1,396 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build cgo && !windows
+// +build cgo,!windows
+
+package hsm
+
+/*
+#cgo linux LDFLAGS: -ldl
+
+#include <dlfcn.h>
+#include <stdlib.h>
+
+// Minimal subset of the PKCS#11 v2.20 interface (the function list is truncated
+// after the last function used, which is fine as it's only accessed by pointer).
+typedef unsigned long CK_ULONG;
+typedef unsigned char CK_BYTE;
+typedef CK_ULONG CK_RV;
+
+typedef struct { CK_BYTE major, minor; } CK_VERSION;
+typedef struct { CK_ULONG type; void *pValue; CK_ULONG ulValueLen; } CK_ATTRIBUTE;
+typedef struct { CK_ULONG mechanism; void *pParameter; CK_ULONG ulParameterLen; } CK_MECHANISM;
+
+typedef struct {
+	CK_BYTE    label[32];
+	CK_BYTE    manufacturerID[32];
+	CK_BYTE    model[16];
+	CK_BYTE    serialNumber[16];
+	CK_ULONG   flags;
+	CK_ULONG   ulMaxSessionCount, ulSessionCount, ulMaxRwSessionCount, ulRwSessionCount;
+	CK_ULONG   ulMaxPinLen, ulMinPinLen;
+	CK_ULONG   ulTotalPublicMemory, ulFreePublicMemory, ulTotalPrivateMemory, ulFreePrivateMemory;
+	CK_VERSION hardwareVersion, firmwareVersion;
+	CK_BYTE    utcTime[16];
+} CK_TOKEN_INFO;
+
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo, *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(CK_BYTE, CK_ULONG *, CK_ULONG *);
+	void *C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_ULONG, CK_TOKEN_INFO *);
+	void *C_GetMechanismList, *C_GetMechanismInfo, *C_InitToken, *C_InitPIN, *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*C_CloseSession)(CK_ULONG);
+	void *C_CloseAllSessions, *C_GetSessionInfo, *C_GetOperationState, *C_SetOperationState;
+	CK_RV (*C_Login)(CK_ULONG, CK_ULONG, CK_BYTE *, CK_ULONG);
+	CK_RV (*C_Logout)(CK_ULONG);
+	void *C_CreateObject, *C_CopyObject, *C_DestroyObject, *C_GetObjectSize;
+	CK_RV (*C_GetAttributeValue)(CK_ULONG, CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_ULONG);
+	void *C_EncryptInit, *C_Encrypt, *C_EncryptUpdate, *C_EncryptFinal;
+	void *C_DecryptInit, *C_Decrypt, *C_DecryptUpdate, *C_DecryptFinal;
+	void *C_DigestInit, *C_Digest, *C_DigestUpdate, *C_DigestKey, *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*C_Sign)(CK_ULONG, CK_BYTE *, CK_ULONG, CK_BYTE *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+#define CKA_CLASS      0x000
+#define CKA_ID         0x102
+#define CKA_KEY_TYPE   0x100
+#define CKA_EC_PARAMS  0x180
+#define CKA_EC_POINT   0x181
+#define CKK_EC         0x003
+#define CKM_ECDSA      0x1041
+
+// p11_load opens the PKCS#11 module and retrieves its function list.
+static CK_FUNCTION_LIST *p11_load(const char *path, void **handle) {
+	*handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (*handle == NULL) {
+		return NULL;
+	}
+	CK_RV (*getFunctionList)(CK_FUNCTION_LIST **) = dlsym(*handle, "C_GetFunctionList");
+	CK_FUNCTION_LIST *list = NULL;
+	if (getFunctionList == NULL || getFunctionList(&list) != 0) {
+		dlclose(*handle);
+		return NULL;
+	}
+	return list;
+}
+
+static void p11_unload(void *handle) { dlclose(handle); }
+
+static CK_RV p11_initialize(CK_FUNCTION_LIST *f) { return f->C_Initialize(NULL); }
+static CK_RV p11_finalize(CK_FUNCTION_LIST *f) { return f->C_Finalize(NULL); }
+
+static CK_RV p11_slots(CK_FUNCTION_LIST *f, CK_ULONG *slots, CK_ULONG *count) {
+	return f->C_GetSlotList(1, slots, count);
+}
+
+static CK_RV p11_token_label(CK_FUNCTION_LIST *f, CK_ULONG slot, CK_BYTE *label) {
+	CK_TOKEN_INFO info;
+	CK_RV rv = f->C_GetTokenInfo(slot, &info);
+	if (rv == 0) {
+		for (int i = 0; i < 32; i++) {
+			label[i] = info.label[i];
+		}
+	}
+	return rv;
+}
+
+static CK_RV p11_open(CK_FUNCTION_LIST *f, CK_ULONG slot, CK_ULONG *session) {
+	return f->C_OpenSession(slot, 0x4, NULL, NULL, session); // CKF_SERIAL_SESSION
+}
+
+static CK_RV p11_close(CK_FUNCTION_LIST *f, CK_ULONG session) { return f->C_CloseSession(session); }
+
+static CK_RV p11_login(CK_FUNCTION_LIST *f, CK_ULONG session, CK_BYTE *pin, CK_ULONG len) {
+	return f->C_Login(session, 1, pin, len); // CKU_USER
+}
+
+static CK_RV p11_logout(CK_FUNCTION_LIST *f, CK_ULONG session) { return f->C_Logout(session); }
+
+// p11_find lists the EC keys of the given class, optionally filtered by id.
+static CK_RV p11_find(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG class, CK_BYTE *id, CK_ULONG idlen, CK_ULONG *objs, CK_ULONG max, CK_ULONG *count) {
+	CK_ULONG type = CKK_EC;
+	CK_ATTRIBUTE tmpl[3] = {
+		{CKA_CLASS, &class, sizeof(class)},
+		{CKA_KEY_TYPE, &type, sizeof(type)},
+		{CKA_ID, id, idlen},
+	};
+	CK_RV rv = f->C_FindObjectsInit(session, tmpl, id == NULL ? 2 : 3);
+	if (rv != 0) {
+		return rv;
+	}
+	rv = f->C_FindObjects(session, objs, max, count);
+	f->C_FindObjectsFinal(session);
+	return rv;
+}
+
+static CK_RV p11_attribute(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG obj, CK_ULONG type, CK_BYTE *value, CK_ULONG *len) {
+	CK_ATTRIBUTE attr = {type, value, *len};
+	CK_RV rv = f->C_GetAttributeValue(session, obj, &attr, 1);
+	*len = attr.ulValueLen;
+	return rv;
+}
+
+static CK_RV p11_sign(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG key, CK_BYTE *digest, CK_ULONG len, CK_BYTE *sig, CK_ULONG *siglen) {
+	CK_MECHANISM mech = {CKM_ECDSA, NULL, 0};
+	CK_RV rv = f->C_SignInit(session, &mech, key);
+	if (rv != 0) {
+		return rv;
+	}
+	return f->C_Sign(session, digest, len, sig, siglen);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// PKCS#11 constants used outside of the C helpers.
+const (
+	ckoPublicKey  = 2
+	ckoPrivateKey = 3
+	ckaEcParams   = 0x180
+	ckaEcPoint    = 0x181
+	ckaID         = 0x102
+
+	ckrOK                         = 0x000
+	ckrUserAlreadyLoggedIn        = 0x100
+	ckrCryptokiAlreadyInitialized = 0x191
+	ckUnavailableInformation      = ^uint64(0)
+	pkcs11MaxObjects              = 256
+	pkcs11MaxAttribute            = 256
+)
+
+// pkcs11Error is a PKCS#11 return value indicating a failure.
+type pkcs11Error uint64
+
+func (e pkcs11Error) Error() string {
+	return fmt.Sprintf("pkcs11: error 0x%08x", uint64(e))
+}
+
+// rv converts a PKCS#11 return value into an error.
+func rv(code C.CK_RV) error {
+	if code == ckrOK {
+		return nil
+	}
+	return pkcs11Error(code)
+}
+
+// ecParamsSecp256k1 is the DER encoded CKA_EC_PARAMS of the secp256k1 curve.
+var ecParamsSecp256k1 = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// PKCS11Signer is a Signer using the secp256k1 keys of a PKCS#11 token, such as
+// a hardware security module or SoftHSM. Keys are found as EC private keys whose
+// public key object with the same CKA_ID is on the secp256k1 curve.
+type PKCS11Signer struct {
+	module string
+	label  string
+
+	handle  unsafe.Pointer
+	funcs   *C.CK_FUNCTION_LIST
+	session C.CK_ULONG
+	keys    map[string]C.CK_ULONG // Private key objects by key id
+	lock    sync.Mutex            // PKCS#11 sessions are single threaded
+}
+
+// NewPKCS11Signer loads the PKCS#11 module, and logs into the token with the
+// given label (or the first token if empty) using the user PIN.
+func NewPKCS11Signer(module string, label string, pin string) (*PKCS11Signer, error) {
+	cmodule := C.CString(module)
+	defer C.free(unsafe.Pointer(cmodule))
+
+	s := &PKCS11Signer{module: module, label: label, keys: make(map[string]C.CK_ULONG)}
+	if s.funcs = C.p11_load(cmodule, &s.handle); s.funcs == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", module)
+	}
+	if err := rv(C.p11_initialize(s.funcs)); err != nil && err != pkcs11Error(ckrCryptokiAlreadyInitialized) {
+		C.p11_unload(s.handle)
+		return nil, err
+	}
+	slot, err := s.findSlot(label)
+	if err == nil {
+		err = rv(C.p11_open(s.funcs, slot, &s.session))
+	}
+	if err != nil {
+		C.p11_finalize(s.funcs)
+		C.p11_unload(s.handle)
+		return nil, err
+	}
+	cpin := []byte(pin)
+	if len(cpin) == 0 {
+		cpin = []byte{0} // Avoid passing a nil pointer for an empty PIN
+	}
+	if err := rv(C.p11_login(s.funcs, s.session, (*C.CK_BYTE)(&cpin[0]), C.CK_ULONG(len(pin)))); err != nil && err != pkcs11Error(ckrUserAlreadyLoggedIn) {
+		s.Close()
+		return nil, fmt.Errorf("PKCS#11 login failed: %v", err)
+	}
+	return s, nil
+}
+
+// findSlot returns the slot of the token with the given label, or of the first
+// token if no label is given.
+func (s *PKCS11Signer) findSlot(label string) (C.CK_ULONG, error) {
+	slots := make([]C.CK_ULONG, pkcs11MaxObjects)
+	count := C.CK_ULONG(len(slots))
+	if err := rv(C.p11_slots(s.funcs, &slots[0], &count)); err != nil {
+		return 0, err
+	}
+	for _, slot := range slots[:count] {
+		if label == "" {
+			return slot, nil
+		}
+		var have [32]byte
+		if err := rv(C.p11_token_label(s.funcs, slot, (*C.CK_BYTE)(&have[0]))); err != nil {
+			return 0, err
+		}
+		// Token labels are padded with blanks
+		if string(bytes.TrimRight(have[:], " \x00")) == label {
+			return slot, nil
+		}
+	}
+	if label == "" {
+		return 0, errors.New("no PKCS#11 token present")
+	}
+	return 0, fmt.Errorf("PKCS#11 token %q not found", label)
+}
+
+// URL implements Signer, returning the module and token label.
+func (s *PKCS11Signer) URL() accounts.URL {
+	return accounts.URL{Scheme: "pkcs11", Path: s.module + "#" + s.label}
+}
+
+// Keys implements Signer, listing the secp256k1 key pairs of the token.
+func (s *PKCS11Signer) Keys() ([]*Key, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	privs, err := s.find(ckoPrivateKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		keys    []*Key
+		handles = make(map[string]C.CK_ULONG)
+	)
+	for _, priv := range privs {
+		id, err := s.attribute(priv, ckaID)
+		if err != nil || len(id) == 0 {
+			continue // Can't find the public key without an id
+		}
+		pubs, err := s.find(ckoPublicKey, id)
+		if err != nil || len(pubs) == 0 {
+			continue
+		}
+		if params, err := s.attribute(pubs[0], ckaEcParams); err != nil || !bytes.Equal(params, ecParamsSecp256k1) {
+			continue
+		}
+		point, err := s.attribute(pubs[0], ckaEcPoint)
+		if err != nil {
+			continue
+		}
+		pub, err := ParsePublicKey(point)
+		if err != nil {
+			continue
+		}
+		key := &Key{ID: hex.EncodeToString(id), PublicKey: pub, Address: crypto.PubkeyToAddress(*pub)}
+		handles[key.ID] = priv
+		keys = append(keys, key)
+	}
+	s.keys = handles
+	return keys, nil
+}
+
+// find lists the EC key objects of the given class, optionally filtered by id.
+func (s *PKCS11Signer) find(class C.CK_ULONG, id []byte) ([]C.CK_ULONG, error) {
+	var (
+		objs  = make([]C.CK_ULONG, pkcs11MaxObjects)
+		count C.CK_ULONG
+		cid   *C.CK_BYTE
+	)
+	if len(id) > 0 {
+		cid = (*C.CK_BYTE)(&id[0])
+	}
+	if err := rv(C.p11_find(s.funcs, s.session, class, cid, C.CK_ULONG(len(id)), &objs[0], C.CK_ULONG(len(objs)), &count)); err != nil {
+		return nil, err
+	}
+	return objs[:count], nil
+}
+
+// attribute retrieves the value of an object attribute.
+func (s *PKCS11Signer) attribute(obj C.CK_ULONG, typ C.CK_ULONG) ([]byte, error) {
+	value := make([]byte, pkcs11MaxAttribute)
+	size := C.CK_ULONG(len(value))
+	if err := rv(C.p11_attribute(s.funcs, s.session, obj, typ, (*C.CK_BYTE)(&value[0]), &size)); err != nil {
+		return nil, err
+	}
+	if uint64(size) == ckUnavailableInformation {
+		return nil, errors.New("attribute unavailable")
+	}
+	return value[:size], nil
+}
+
+// Sign implements Signer, signing the digest with CKM_ECDSA on the token.
+func (s *PKCS11Signer) Sign(key *Key, digest []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	priv, ok := s.keys[key.ID]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	digest = append([]byte{}, digest...)
+	sig := make([]byte, 2*pkcs11MaxAttribute)
+	size := C.CK_ULONG(len(sig))
+	if err := rv(C.p11_sign(s.funcs, s.session, priv, (*C.CK_BYTE)(&digest[0]), C.CK_ULONG(len(digest)), (*C.CK_BYTE)(&sig[0]), &size)); err != nil {
+		return nil, err
+	}
+	return sig[:size], nil
+}
+
+// Close implements Signer, logging out of the token and unloading the module.
+func (s *PKCS11Signer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.funcs == nil {
+		return nil
+	}
+	C.p11_logout(s.funcs, s.session)
+	C.p11_close(s.funcs, s.session)
+	err := rv(C.p11_finalize(s.funcs))
+	C.p11_unload(s.handle)
+	s.funcs = nil
+	return err
+}
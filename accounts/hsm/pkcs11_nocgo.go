@@ -0,0 +1,41 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !cgo || windows
+// +build !cgo windows
+
+package hsm
+
+import (
+	"errors"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+)
+
+// PKCS11Signer is a Signer using the secp256k1 keys of a PKCS#11 token. It is
+// only available on cgo enabled, non-Windows builds.
+type PKCS11Signer struct{}
+
+// NewPKCS11Signer returns an error, as PKCS#11 modules cannot be loaded on this
+// platform.
+func NewPKCS11Signer(module string, label string, pin string) (*PKCS11Signer, error) {
+	return nil, errors.New("PKCS#11 is not supported on this platform")
+}
+
+func (s *PKCS11Signer) URL() accounts.URL                            { return accounts.URL{} }
+func (s *PKCS11Signer) Keys() ([]*Key, error)                        { return nil, nil }
+func (s *PKCS11Signer) Sign(key *Key, digest []byte) ([]byte, error) { return nil, ErrNotSupported }
+func (s *PKCS11Signer) Close() error                                 { return nil }
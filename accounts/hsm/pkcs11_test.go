@@ -0,0 +1,60 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hsm
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+)
+
+// TestPKCS11Signer signs a transaction with the first secp256k1 key of a PKCS#11
+// token. It needs a module to be configured, e.g. for SoftHSM:
+//
+//	softhsm2-util --init-token --free --label test --pin 1234 --so-pin 0000
+//	pkcs11-tool --module /usr/lib/softhsm/libsofthsm2.so --token-label test --pin 1234 \
+//	  --keypairgen --key-type EC:secp256k1 --id 01
+//	PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN=test PKCS11_PIN=1234 go test
+func TestPKCS11Signer(t *testing.T) {
+	module := os.Getenv("PKCS11_MODULE")
+	if module == "" {
+		t.Skip("PKCS11_MODULE not set")
+	}
+	signer, err := NewPKCS11Signer(module, os.Getenv("PKCS11_TOKEN"), os.Getenv("PKCS11_PIN"))
+	if err != nil {
+		t.Fatalf("failed to open token: %v", err)
+	}
+	backend := NewBackend(signer)
+	defer backend.Close()
+
+	accs := backend.Wallets()[0].Accounts()
+	if len(accs) == 0 {
+		t.Fatalf("no secp256k1 keys on token")
+	}
+	chainID := big.NewInt(1337)
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := backend.Wallets()[0].SignTx(accs[0], tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.NewEIP155Signer(chainID), signed); err != nil || sender != accs[0].Address {
+		t.Fatalf("sender mismatch: have %x, %v, want %x", sender, err, accs[0].Address)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hsm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// remoteTimeout is the time allowed for a remote signer to answer a request.
+const remoteTimeout = 30 * time.Second
+
+// RemoteSigner is a Signer speaking a simple HTTP protocol, which can be served
+// by a thin proxy in front of a cloud key management service:
+//
+//	GET  <endpoint>/keys
+//	     -> [{"id": "<key id>", "publicKey": "0x<public key>"}, ...]
+//	POST <endpoint>/sign {"id": "<key id>", "digest": "0x<32 byte hash>"}
+//	     -> {"signature": "0x<signature>"}
+//
+// Public keys may be in any encoding accepted by ParsePublicKey, and signatures
+// in any encoding accepted by NormalizeSignature. Errors are reported with a
+// non-200 status code, and the response body as the message. If a token is
+// configured, requests are authenticated with it as a bearer token.
+type RemoteSigner struct {
+	endpoint string
+	token    string
+	client   *http.Client
+
+	keys map[string]*Key // Keys listed last, to reuse parsed public keys
+	lock sync.Mutex
+}
+
+// remoteKey is a key as listed by a remote signer.
+type remoteKey struct {
+	ID        string        `json:"id"`
+	PublicKey hexutil.Bytes `json:"publicKey"`
+}
+
+// remoteSignRequest is the request for a signature from a remote signer.
+type remoteSignRequest struct {
+	ID     string        `json:"id"`
+	Digest hexutil.Bytes `json:"digest"`
+}
+
+// remoteSignResponse is the signature returned by a remote signer.
+type remoteSignResponse struct {
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// NewRemoteSigner creates a signer for the remote signing service at the given
+// endpoint, checking that its keys can be listed.
+func NewRemoteSigner(endpoint string, token string) (*RemoteSigner, error) {
+	signer := &RemoteSigner{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: remoteTimeout},
+	}
+	if _, err := signer.Keys(); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// URL implements Signer, returning the endpoint of the signing service.
+func (s *RemoteSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: "remotesigner", Path: s.endpoint}
+}
+
+// Keys implements Signer, listing the keys of the signing service.
+func (s *RemoteSigner) Keys() ([]*Key, error) {
+	var listed []remoteKey
+	if err := s.call("GET", "/keys", nil, &listed); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	keys := make(map[string]*Key, len(listed))
+	res := make([]*Key, 0, len(listed))
+	for _, k := range listed {
+		key := s.keys[k.ID]
+		if key == nil || !bytes.Equal(crypto.FromECDSAPub(key.PublicKey), k.PublicKey) {
+			pub, err := ParsePublicKey(k.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %v", k.ID, err)
+			}
+			key = &Key{ID: k.ID, PublicKey: pub, Address: crypto.PubkeyToAddress(*pub)}
+		}
+		keys[k.ID] = key
+		res = append(res, key)
+	}
+	s.keys = keys
+	return res, nil
+}
+
+// Sign implements Signer, requesting a signature from the signing service.
+func (s *RemoteSigner) Sign(key *Key, digest []byte) ([]byte, error) {
+	var res remoteSignResponse
+	if err := s.call("POST", "/sign", &remoteSignRequest{ID: key.ID, Digest: digest}, &res); err != nil {
+		return nil, err
+	}
+	return res.Signature, nil
+}
+
+// Close implements Signer. Remote signers hold no resources.
+func (s *RemoteSigner) Close() error {
+	return nil
+}
+
+// call sends a request to the signing service and decodes its response.
+func (s *RemoteSigner) call(method, path string, req interface{}, res interface{}) error {
+	var body io.Reader
+	if req != nil {
+		blob, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(blob)
+	}
+	httpReq, err := http.NewRequest(method, s.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	httpRes, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	if httpRes.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(httpRes.Body, 1024))
+		return fmt.Errorf("remote signer: %s: %s", httpRes.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(httpRes.Body).Decode(res)
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package hsm
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+var (
+	// ErrInvalidSignature is returned if a key store signature cannot be decoded,
+	// or does not belong to the signing key.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrInvalidPublicKey is returned if a public key is not a secp256k1 point in
+	// any of the supported encodings.
+	ErrInvalidPublicKey = errors.New("invalid secp256k1 public key")
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+)
+
+// NormalizeSignature converts a signature over the digest into the 65 byte
+// [R || S || V] format used by Ethereum, with V being 0 or 1. The signature may
+// be ASN.1 DER encoded or a raw [R || S] or [R || S || V] value. S is flipped into
+// the lower half of the curve order as required since Homestead, and V is found
+// by recovering the public key, which also verifies the signature.
+func NormalizeSignature(digest, sig []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var r, s *big.Int
+	switch {
+	case len(sig) == 64 || len(sig) == crypto.SignatureLength:
+		r, s = new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	default:
+		var der struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &der); err != nil || len(rest) > 0 {
+			return nil, ErrInvalidSignature
+		}
+		r, s = der.R, der.S
+	}
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(secp256k1N) >= 0 || s.Cmp(secp256k1N) >= 0 {
+		return nil, ErrInvalidSignature
+	}
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+	res := make([]byte, crypto.SignatureLength)
+	copy(res[32-len(r.Bytes()):32], r.Bytes())
+	copy(res[64-len(s.Bytes()):64], s.Bytes())
+
+	want := crypto.FromECDSAPub(pub)
+	for v := byte(0); v < 2; v++ {
+		res[crypto.RecoveryIDOffset] = v
+		if have, err := crypto.Ecrecover(digest, res); err == nil && bytes.Equal(have, want) {
+			return res, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+// oidSecp256k1 is the ASN.1 object identifier of the secp256k1 curve.
+var oidSecp256k1 = asn1.ObjectIdentifier{1, 3, 132, 0, 10}
+
+// ParsePublicKey decodes a secp256k1 public key, given either as a 65 byte
+// uncompressed or 33 byte compressed point, as a DER octet string wrapping the
+// point (the PKCS#11 CKA_EC_POINT encoding), or as a DER encoded X.509 subject
+// public key info (the encoding used by most cloud key management services).
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	switch {
+	case len(data) == 65 && data[0] == 4:
+		return crypto.UnmarshalPubkey(data)
+	case len(data) == 33 && (data[0] == 2 || data[0] == 3):
+		return crypto.DecompressPubkey(data)
+	}
+	var point []byte
+	if rest, err := asn1.Unmarshal(data, &point); err == nil && len(rest) == 0 {
+		return ParsePublicKey(point)
+	}
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if rest, err := asn1.Unmarshal(data, &spki); err == nil && len(rest) == 0 {
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(spki.Algorithm.Parameters.FullBytes, &curve); err != nil || !curve.Equal(oidSecp256k1) {
+			return nil, fmt.Errorf("%v: unsupported curve %v", ErrInvalidPublicKey, curve)
+		}
+		return ParsePublicKey(spki.PublicKey.Bytes)
+	}
+	return nil, ErrInvalidPublicKey
+}
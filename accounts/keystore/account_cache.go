@@ -62,6 +62,28 @@ func (err *AmbiguousAddrError) Error() string {
 	return fmt.Sprintf("multiple keys match address (%s)", files)
 }
 
+// accountIndex is an index of all accounts held in the storage of a keystore.
+type accountIndex interface {
+	// accounts returns all indexed accounts, ordered by their URLs.
+	accounts() []accounts.Account
+
+	// hasAddress reports whether a key with the given address is indexed.
+	hasAddress(addr common.Address) bool
+
+	// lookup returns the indexed account if there is a unique match. The exact
+	// matching rules are explained by the documentation of accounts.Account.
+	lookup(a accounts.Account) (accounts.Account, error)
+
+	// add inserts a newly stored account into the index.
+	add(a accounts.Account)
+
+	// delete removes an account from the index.
+	delete(a accounts.Account)
+
+	// close releases any resources held by the index.
+	close()
+}
+
 // accountCache is a live index of all accounts in the keystore.
 type accountCache struct {
 	keydir   string
@@ -191,6 +213,15 @@ func (ac *accountCache) find(a accounts.Account) (accounts.Account, error) {
 	}
 }
 
+// lookup implements accountIndex, reloading the cache if needed before finding
+// the account.
+func (ac *accountCache) lookup(a accounts.Account) (accounts.Account, error) {
+	ac.maybeReload()
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.find(a)
+}
+
 func (ac *accountCache) maybeReload() {
 	ac.mu.Lock()
 
@@ -0,0 +1,253 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/ethdb"
+	"github.com/Fantom-foundation/go-ethereum/log"
+)
+
+// keyPrefix + address -> encrypted JSON key
+var keyPrefix = []byte("k")
+
+// NewDatabaseKeyStore creates a keystore storing its keys in a key-value database
+// instead of a key directory. The keys are encrypted with scrypt in the same Web3
+// Secret Storage format as key files, and can be moved between the two keystore
+// types with ImportKeyFiles and ExportKeyFiles.
+//
+// The location is only used to construct the URLs of the accounts, and should be
+// the path of the database. The database is not closed by the keystore.
+func NewDatabaseKeyStore(db ethdb.KeyValueStore, location string, scryptN, scryptP int) (*KeyStore, error) {
+	location, _ = filepath.Abs(location)
+	storage := &keyStoreDatabase{db: db, location: location, scryptN: scryptN, scryptP: scryptP}
+
+	index, err := newDatabaseIndex(storage)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeyStore{storage: storage}
+	ks.init(index, nil) // All changes go through the keystore, no notifications needed
+	return ks, nil
+}
+
+// keyStoreDatabase is a key storage backed by a key-value database. Keys are
+// identified by their address, the trailing component of their path.
+type keyStoreDatabase struct {
+	db       ethdb.KeyValueStore
+	location string
+	scryptN  int
+	scryptP  int
+}
+
+// keyDatabaseKey returns the database key of the key stored at the given path.
+func keyDatabaseKey(path string) ([]byte, error) {
+	base := filepath.Base(path)
+	if len(base) < 2*common.AddressLength {
+		return nil, fmt.Errorf("invalid key path %s", path)
+	}
+	addr, err := hex.DecodeString(base[len(base)-2*common.AddressLength:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key path %s: %v", path, err)
+	}
+	return append(append([]byte{}, keyPrefix...), addr...), nil
+}
+
+func (ks *keyStoreDatabase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
+	keyjson, err := ks.GetKeyJSON(addr, filename)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecryptKey(keyjson, auth)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure we're really operating on the requested key (no swap attacks)
+	if key.Address != addr {
+		return nil, fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, addr)
+	}
+	return key, nil
+}
+
+func (ks *keyStoreDatabase) StoreKey(filename string, key *Key, auth string) error {
+	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	return ks.StoreKeyJSON(filename, key.Address, keyjson)
+}
+
+func (ks *keyStoreDatabase) GetKeyJSON(addr common.Address, filename string) ([]byte, error) {
+	dbkey, err := keyDatabaseKey(filename)
+	if err != nil {
+		return nil, err
+	}
+	keyjson, err := ks.db.Get(dbkey)
+	if err != nil {
+		return nil, ErrNoMatch
+	}
+	return keyjson, nil
+}
+
+func (ks *keyStoreDatabase) StoreKeyJSON(filename string, addr common.Address, keyjson []byte) error {
+	dbkey, err := keyDatabaseKey(filename)
+	if err != nil {
+		return err
+	}
+	if common.BytesToAddress(dbkey[len(keyPrefix):]) != addr {
+		return fmt.Errorf("key path mismatch: have %s, want account %x", filename, addr)
+	}
+	return ks.db.Put(dbkey, keyjson)
+}
+
+func (ks *keyStoreDatabase) DeleteKey(filename string) error {
+	dbkey, err := keyDatabaseKey(filename)
+	if err != nil {
+		return err
+	}
+	return ks.db.Delete(dbkey)
+}
+
+// JoinPath returns the path of a key within the database. As keys are identified
+// by their address alone, any other details of key file names are dropped.
+func (ks *keyStoreDatabase) JoinPath(filename string) string {
+	if idx := strings.LastIndex(filename, "--"); idx >= 0 {
+		filename = filename[idx+2:]
+	}
+	return filepath.Join(ks.location, filepath.Base(filename))
+}
+
+// databaseIndex is an in-memory index of all accounts in a database keystore.
+// As the keystore is the only writer of the database, the index is populated
+// once on startup and then kept up to date by the keystore itself.
+type databaseIndex struct {
+	mu     sync.Mutex
+	all    accountsByURL // Accounts ordered by URL, lazily sorted
+	sorted bool          // Whether the account list is currently ordered
+	byAddr map[common.Address]accounts.Account
+}
+
+// newDatabaseIndex creates an index of all the keys stored in the database.
+func newDatabaseIndex(storage *keyStoreDatabase) (*databaseIndex, error) {
+	idx := &databaseIndex{
+		byAddr: make(map[common.Address]accounts.Account),
+	}
+	it := storage.db.NewIteratorWithPrefix(keyPrefix)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(keyPrefix)+common.AddressLength {
+			log.Warn("Skipping invalid keystore database entry", "key", fmt.Sprintf("%x", key))
+			continue
+		}
+		addr := common.BytesToAddress(key[len(keyPrefix):])
+		idx.add(accounts.Account{
+			Address: addr,
+			URL:     accounts.URL{Scheme: KeyStoreScheme, Path: storage.JoinPath(hex.EncodeToString(addr[:]))},
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *databaseIndex) accounts() []accounts.Account {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.sorted {
+		sort.Sort(idx.all)
+		idx.sorted = true
+	}
+	cpy := make([]accounts.Account, len(idx.all))
+	copy(cpy, idx.all)
+	return cpy
+}
+
+func (idx *databaseIndex) hasAddress(addr common.Address) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	_, ok := idx.byAddr[addr]
+	return ok
+}
+
+func (idx *databaseIndex) lookup(a accounts.Account) (accounts.Account, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if (a.Address != common.Address{}) {
+		match, ok := idx.byAddr[a.Address]
+		if !ok || (a.URL.Path != "" && a.URL.Path != match.URL.Path && filepath.Base(a.URL.Path) != filepath.Base(match.URL.Path)) {
+			return accounts.Account{}, ErrNoMatch
+		}
+		return match, nil
+	}
+	// No address given, the key path must identify the account
+	if a.URL.Path != "" {
+		dbkey, err := keyDatabaseKey(a.URL.Path)
+		if err != nil {
+			return accounts.Account{}, ErrNoMatch
+		}
+		if match, ok := idx.byAddr[common.BytesToAddress(dbkey[len(keyPrefix):])]; ok {
+			return match, nil
+		}
+	}
+	return accounts.Account{}, ErrNoMatch
+}
+
+func (idx *databaseIndex) add(a accounts.Account) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, ok := idx.byAddr[a.Address]; ok {
+		return
+	}
+	idx.byAddr[a.Address] = a
+	idx.all = append(idx.all, a)
+	idx.sorted = false
+}
+
+func (idx *databaseIndex) delete(a accounts.Account) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, ok := idx.byAddr[a.Address]; !ok {
+		return
+	}
+	delete(idx.byAddr, a.Address)
+	for i := range idx.all {
+		if idx.all[i].Address == a.Address {
+			idx.all = append(idx.all[:i], idx.all[i+1:]...)
+			break
+		}
+	}
+}
+
+// close implements accountIndex, but is a noop as the database is owned by the
+// creator of the keystore.
+func (idx *databaseIndex) close() {}
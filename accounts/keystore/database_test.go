@@ -0,0 +1,170 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/ethdb"
+	"github.com/Fantom-foundation/go-ethereum/ethdb/memorydb"
+)
+
+func tmpDatabaseKeyStore(t *testing.T, db ethdb.KeyValueStore) *KeyStore {
+	ks, err := NewDatabaseKeyStore(db, "keystore.db", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to create database keystore: %v", err)
+	}
+	return ks
+}
+
+func TestDatabaseKeyStore(t *testing.T) {
+	db := memorydb.New()
+	ks := tmpDatabaseKeyStore(t, db)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ks.HasAddress(a.Address) {
+		t.Errorf("HasAccount(%x) should've returned true", a.Address)
+	}
+	// Accounts should be found both by address and by path
+	if found, err := ks.Find(accounts.Account{Address: a.Address}); err != nil || found != a {
+		t.Errorf("Find by address mismatch: have %v (%v), want %v", found, err, a)
+	}
+	if found, err := ks.Find(accounts.Account{URL: a.URL}); err != nil || found != a {
+		t.Errorf("Find by URL mismatch: have %v (%v), want %v", found, err, a)
+	}
+	if _, err := ks.Find(accounts.Account{Address: common.Address{1}}); err != ErrNoMatch {
+		t.Errorf("Find of missing account error mismatch: have %v, want %v", err, ErrNoMatch)
+	}
+	// Keys should be signing and persisted across restarts
+	if err := ks.Update(a, "foo", "bar"); err != nil {
+		t.Errorf("Update error: %v", err)
+	}
+	ks = tmpDatabaseKeyStore(t, db)
+	if wallets := ks.Wallets(); len(wallets) != 1 || wallets[0].Accounts()[0] != a {
+		t.Fatalf("reopened keystore wallets mismatch: have %v, want [%v]", wallets, a)
+	}
+	if err := ks.Unlock(a, "foo"); err != ErrDecrypt {
+		t.Errorf("Unlock with old passphrase error mismatch: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.Unlock(a, "bar"); err != nil {
+		t.Fatalf("Unlock error: %v", err)
+	}
+	if _, err := ks.SignHash(accounts.Account{Address: a.Address}, testSigData); err != nil {
+		t.Errorf("SignHash error: %v", err)
+	}
+	if err := ks.Delete(a, "bar"); err != nil {
+		t.Errorf("Delete error: %v", err)
+	}
+	if ks.HasAddress(a.Address) {
+		t.Errorf("HasAccount(%x) should've returned false after Delete", a.Address)
+	}
+	if ks = tmpDatabaseKeyStore(t, db); len(ks.Accounts()) != 0 {
+		t.Errorf("reopened keystore accounts mismatch: have %v, want none", ks.Accounts())
+	}
+}
+
+func TestDatabaseKeyStoreOrdering(t *testing.T) {
+	ks := tmpDatabaseKeyStore(t, memorydb.New())
+
+	for i := 0; i < 16; i++ {
+		key, _ := crypto.GenerateKey()
+		if _, err := ks.ImportECDSA(key, ""); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if _, err := ks.ImportECDSA(key, ""); err == nil {
+				t.Errorf("duplicate import succeeded")
+			}
+		}
+	}
+	accs := ks.Accounts()
+	if len(accs) != 16 {
+		t.Fatalf("account count mismatch: have %d, want 16", len(accs))
+	}
+	for i := 1; i < len(accs); i++ {
+		if accs[i-1].URL.Cmp(accs[i].URL) >= 0 {
+			t.Errorf("accounts %d and %d out of order: %v, %v", i-1, i, accs[i-1].URL, accs[i].URL)
+		}
+	}
+}
+
+func TestDatabaseKeyStoreKeyFiles(t *testing.T) {
+	dir, fileks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := fileks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := fileks.NewAccount("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Import the key files into the database keystore, skipping existing keys
+	ks := tmpDatabaseKeyStore(t, memorydb.New())
+	if _, err := ks.Import(mustReadFile(t, a1.URL.Path), "foo", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ks.ImportKeyFiles(dir)
+	if err != nil {
+		t.Fatalf("failed to import key files: %v", err)
+	}
+	if len(imported) != 1 || imported[0].Address != a2.Address {
+		t.Fatalf("imported accounts mismatch: have %v, want [%x]", imported, a2.Address)
+	}
+	if err := ks.Unlock(imported[0], "bar"); err != nil {
+		t.Errorf("failed to unlock imported key: %v", err)
+	}
+	// Export the keys into a new key directory and check they are usable
+	out, err := ioutil.TempDir("", "eth-keystore-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(out)
+
+	if err := ks.ExportKeyFiles(out); err != nil {
+		t.Fatalf("failed to export key files: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(out, "UTC--*"))
+	if len(files) != 2 {
+		t.Fatalf("exported key file count mismatch: have %d, want 2", len(files))
+	}
+	exported := NewKeyStore(out, veryLightScryptN, veryLightScryptP)
+	if err := exported.Unlock(accounts.Account{Address: a1.Address}, "foo"); err != nil {
+		t.Errorf("failed to unlock exported key: %v", err)
+	}
+	if err := exported.Unlock(accounts.Account{Address: a2.Address}, "bar"); err != nil {
+		t.Errorf("failed to unlock exported key: %v", err)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blob
+}
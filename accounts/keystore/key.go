@@ -55,6 +55,17 @@ type keyStore interface {
 	StoreKey(filename string, k *Key, auth string) error
 	// Joins filename with the key directory unless it is already absolute.
 	JoinPath(filename string) string
+	// Removes the key from disk.
+	DeleteKey(filename string) error
+}
+
+// keyStoreRaw is implemented by key stores holding keys in the Web3 Secret Storage
+// format, allowing them to be moved in bulk without decrypting them.
+type keyStoreRaw interface {
+	// Loads the encrypted JSON key from disk.
+	GetKeyJSON(addr common.Address, filename string) ([]byte, error)
+	// Writes the encrypted JSON key as is.
+	StoreKeyJSON(filename string, addr common.Address, keyjson []byte) error
 }
 
 type plainKeyJSON struct {
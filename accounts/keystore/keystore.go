@@ -18,15 +18,19 @@
 //
 // Keys are stored as encrypted JSON files according to the Web3 Secret Storage specification.
 // See https://github.com/ethereum/wiki/wiki/Web3-Secret-Storage-Definition for more information.
+//
+// For large numbers of keys, the same encrypted JSON keys can alternatively be stored in a
+// key-value database, see NewDatabaseKeyStore.
 package keystore
 
 import (
 	"crypto/ecdsa"
 	crand "crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/big"
-	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -44,6 +48,10 @@ var (
 	ErrLocked  = accounts.NewAuthNeededError("password or unlock")
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given password")
+
+	// ErrRawKeysUnsupported is returned by bulk key file operations if the
+	// keystore doesn't store keys in the Web3 Secret Storage format.
+	ErrRawKeysUnsupported = errors.New("keystore doesn't support raw key access")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -58,7 +66,7 @@ const walletRefreshCycle = 3 * time.Second
 // KeyStore manages a key storage directory on disk.
 type KeyStore struct {
 	storage  keyStore                     // Storage backend, might be cleartext or encrypted
-	cache    *accountCache                // In-memory account cache over the filesystem storage
+	cache    accountIndex                 // In-memory account index over the key storage
 	changes  chan struct{}                // Channel receiving change notifications from the cache
 	unlocked map[common.Address]*unlocked // Currently unlocked account (decrypted private keys)
 
@@ -79,7 +87,7 @@ type unlocked struct {
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
 	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, false}}
-	ks.init(keydir)
+	ks.init(newAccountCache(keydir))
 	return ks
 }
 
@@ -88,18 +96,18 @@ func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 func NewPlaintextKeyStore(keydir string) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
 	ks := &KeyStore{storage: &keyStorePlain{keydir}}
-	ks.init(keydir)
+	ks.init(newAccountCache(keydir))
 	return ks
 }
 
-func (ks *KeyStore) init(keydir string) {
+func (ks *KeyStore) init(cache accountIndex, changes chan struct{}) {
 	// Lock the mutex since the account cache might call back with events
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
 	// Initialize the set of unlocked keys and the account cache
 	ks.unlocked = make(map[common.Address]*unlocked)
-	ks.cache, ks.changes = newAccountCache(keydir)
+	ks.cache, ks.changes = cache, changes
 
 	// TODO: In order for this finalizer to work, there must be no references
 	// to ks. addressCache doesn't keep a reference but unlocked keys do,
@@ -246,7 +254,7 @@ func (ks *KeyStore) Delete(a accounts.Account, passphrase string) error {
 	// The order is crucial here. The key is dropped from the
 	// cache after the file is gone so that a reload happening in
 	// between won't insert it into the cache again.
-	err = os.Remove(a.URL.Path)
+	err = ks.storage.DeleteKey(a.URL.Path)
 	if err == nil {
 		ks.cache.delete(a)
 		ks.refreshWallets()
@@ -369,11 +377,7 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 
 // Find resolves the given account into a unique entry in the keystore.
 func (ks *KeyStore) Find(a accounts.Account) (accounts.Account, error) {
-	ks.cache.maybeReload()
-	ks.cache.mu.Lock()
-	a, err := ks.cache.find(a)
-	ks.cache.mu.Unlock()
-	return a, err
+	return ks.cache.lookup(a)
 }
 
 func (ks *KeyStore) getDecryptedKey(a accounts.Account, auth string) (accounts.Account, *Key, error) {
@@ -486,6 +490,75 @@ func (ks *KeyStore) ImportPreSaleKey(keyJSON []byte, passphrase string) (account
 	return a, nil
 }
 
+// ImportKeyFiles copies the encrypted keys from the key files in dir into the
+// keystore. The keys are not decrypted, so they retain their passphrases. Keys
+// of accounts already present in the keystore are skipped.
+func (ks *KeyStore) ImportKeyFiles(dir string) ([]accounts.Account, error) {
+	raw, ok := ks.storage.(keyStoreRaw)
+	if !ok {
+		return nil, ErrRawKeysUnsupported
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Refresh the wallets once all the keys have been imported, even on failure
+	var imported []accounts.Account
+	defer func() {
+		if len(imported) > 0 {
+			ks.refreshWallets()
+		}
+	}()
+	for _, fi := range files {
+		if nonKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		keyjson, err := ioutil.ReadFile(path)
+		if err != nil {
+			return imported, err
+		}
+		var key encryptedKeyJSONV3
+		if err := json.Unmarshal(keyjson, &key); err != nil {
+			return imported, fmt.Errorf("invalid key file %s: %v", path, err)
+		}
+		if key.Version != version || !common.IsHexAddress(key.Address) {
+			return imported, fmt.Errorf("invalid key file %s: not a version %d key", path, version)
+		}
+		addr := common.HexToAddress(key.Address)
+		if ks.cache.hasAddress(addr) {
+			continue
+		}
+		a := accounts.Account{Address: addr, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(addr))}}
+		if err := raw.StoreKeyJSON(a.URL.Path, addr, keyjson); err != nil {
+			return imported, err
+		}
+		ks.cache.add(a)
+		imported = append(imported, a)
+	}
+	return imported, nil
+}
+
+// ExportKeyFiles writes the encrypted keys of all accounts in the keystore into
+// dir as key files, which can be used by any wallet supporting the Web3 Secret
+// Storage format. The keys are not decrypted, so they retain their passphrases.
+func (ks *KeyStore) ExportKeyFiles(dir string) error {
+	raw, ok := ks.storage.(keyStoreRaw)
+	if !ok {
+		return ErrRawKeysUnsupported
+	}
+	for _, a := range ks.cache.accounts() {
+		keyjson, err := raw.GetKeyJSON(a.Address, a.URL.Path)
+		if err != nil {
+			return err
+		}
+		if err := writeKeyFile(filepath.Join(dir, keyFileName(a.Address)), keyjson); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // zeroKey zeroes a private key in memory.
 func zeroKey(k *ecdsa.PrivateKey) {
 	b := k.D.Bits()
@@ -129,6 +129,18 @@ func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) er
 	return os.Rename(tmpName, filename)
 }
 
+func (ks keyStorePassphrase) DeleteKey(filename string) error {
+	return os.Remove(filename)
+}
+
+func (ks keyStorePassphrase) GetKeyJSON(addr common.Address, filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+func (ks keyStorePassphrase) StoreKeyJSON(filename string, addr common.Address, keyjson []byte) error {
+	return writeKeyFile(filename, keyjson)
+}
+
 func (ks keyStorePassphrase) JoinPath(filename string) string {
 	if filepath.IsAbs(filename) {
 		return filename
@@ -53,6 +53,10 @@ func (ks keyStorePlain) StoreKey(filename string, key *Key, auth string) error {
 	return writeKeyFile(filename, content)
 }
 
+func (ks keyStorePlain) DeleteKey(filename string) error {
+	return os.Remove(filename)
+}
+
 func (ks keyStorePlain) JoinPath(filename string) string {
 	if filepath.IsAbs(filename) {
 		return filename
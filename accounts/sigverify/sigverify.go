@@ -0,0 +1,145 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package sigverify verifies signatures of both externally owned accounts and
+// smart contract wallets.
+//
+// Signatures of accounts without code are verified by recovering the signer's
+// public key. Accounts with code are asked whether the signature is valid via
+// the isValidSignature method specified by EIP-1271.
+package sigverify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi"
+	"github.com/Fantom-foundation/go-ethereum/accounts/typeddata"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// ERC1271ABI is the input ABI of the EIP-1271 signature validation method.
+const ERC1271ABI = `[{"constant":true,"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"payable":false,"stateMutability":"view","type":"function"}]`
+
+// MagicValue is returned by isValidSignature if the signature is valid, being
+// the selector of the method itself.
+var MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+var erc1271ABI abi.ABI
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(ERC1271ABI))
+	if err != nil {
+		panic(err)
+	}
+	erc1271ABI = parsed
+}
+
+// ContractCaller defines the methods needed to verify signatures of contract
+// accounts.
+//
+// CallContract must not return the output of reverted calls: a wallet reverting
+// with the magic value would otherwise validate any signature. Implementations
+// have to return an error or no output instead. Note that ethclient.Client and
+// the simulated backend return revert data like ordinary results, so they are
+// only suitable for wallets known never to revert with data.
+type ContractCaller interface {
+	// CodeAt returns the code of the given account.
+	CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error)
+	// ContractCall executes an Ethereum contract call with the specified data as the
+	// input.
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// VerifyHash reports whether sig is a valid signature of the hash by the signer
+// in the state of the given block, or the latest one if blockNumber is nil.
+//
+// If the signer has no code, the signature must be in the [R || S || V] format,
+// where V is either 0/1 or the legacy 27/28. Otherwise the signature is passed
+// as is to the isValidSignature method of the signer contract, and is deemed
+// valid if the call returns the magic value.
+//
+// An error is only returned if the verification couldn't be done, e.g. because
+// the backend failed.
+func VerifyHash(ctx context.Context, caller ContractCaller, blockNumber *big.Int, signer common.Address, hash common.Hash, sig []byte) (bool, error) {
+	code, err := caller.CodeAt(ctx, signer, blockNumber)
+	if err != nil {
+		return false, err
+	}
+	if len(code) == 0 {
+		recovered, err := recoverSigner(hash, sig)
+		return err == nil && recovered == signer, nil
+	}
+	// The signer is a contract, ask it to validate the signature
+	input, err := erc1271ABI.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return false, err
+	}
+	output, err := caller.CallContract(ctx, ethereum.CallMsg{To: &signer, Data: input}, blockNumber)
+	if err != nil {
+		return false, err
+	}
+	// Reverted calls and malformed results are deemed invalid signatures
+	if len(output) != 32 {
+		return false, nil
+	}
+	return bytes.Equal(output[:4], MagicValue[:]) && bytes.Count(output[4:], []byte{0}) == 28, nil
+}
+
+// VerifyText reports whether sig is a valid signature of the text by the signer,
+// as calculated by the eth_sign and personal_sign methods.
+func VerifyText(ctx context.Context, caller ContractCaller, blockNumber *big.Int, signer common.Address, text []byte, sig []byte) (bool, error) {
+	return VerifyHash(ctx, caller, blockNumber, signer, common.BytesToHash(accounts.TextHash(text)), sig)
+}
+
+// VerifyTypedData reports whether sig is a valid EIP-712 signature of the typed
+// data by the signer.
+func VerifyTypedData(ctx context.Context, caller ContractCaller, blockNumber *big.Int, signer common.Address, data *typeddata.TypedData, sig []byte) (bool, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return false, err
+	}
+	return VerifyHash(ctx, caller, blockNumber, signer, hash, sig)
+}
+
+// errInvalidSignature is returned when recovering the signer of a malformed
+// signature.
+var errInvalidSignature = errors.New("invalid signature")
+
+// recoverSigner returns the address of the account which signed the hash.
+func recoverSigner(hash common.Hash, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, errInvalidSignature
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, errInvalidSignature
+	}
+	pubkey, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package sigverify_test
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
+	"github.com/Fantom-foundation/go-ethereum/accounts/sigverify"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// walletCode is the runtime code of a minimal contract wallet which deems all
+// signatures of the hash stored in slot 0 valid:
+//
+//	if calldataload(4) != sload(0) { revert(0, 0) }
+//	mstore(0, shl(224, 0x1626ba7e))
+//	return(0, 32)
+var walletCode = common.Hex2Bytes("60043560005414600e57600080fd5b631626ba7e60e01b60005260206000f3")
+
+// revertingWalletCode is the runtime code of a malicious contract which reverts
+// every call with the magic value as revert data:
+//
+//	mstore(0, shl(224, 0x1626ba7e))
+//	revert(0, 32)
+var revertingWalletCode = common.Hex2Bytes("631626ba7e60e01b60005260206000fd")
+
+// strictCaller wraps the simulated backend, dropping the output of reverted calls
+// as required by sigverify.ContractCaller.
+type strictCaller struct {
+	*backends.SimulatedBackend
+}
+
+func (c strictCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if _, err := c.EstimateGas(ctx, call); err != nil {
+		return nil, nil // Always failing call
+	}
+	return c.SimulatedBackend.CallContract(ctx, call, blockNumber)
+}
+
+func TestVerifyHash(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		eoa    = crypto.PubkeyToAddress(key.PublicKey)
+		wallet = common.HexToAddress("0x1271")
+		hash   = crypto.Keccak256Hash([]byte("sign in"))
+	)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{
+		eoa: {Balance: big.NewInt(1)},
+		wallet: {
+			Balance: big.NewInt(0),
+			Code:    walletCode,
+			Storage: map[common.Hash]common.Hash{{}: hash},
+		},
+	}, 10000000)
+	defer sim.Close()
+
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	legacy := common.CopyBytes(sig)
+	legacy[64] += 27
+
+	tests := []struct {
+		signer common.Address
+		hash   common.Hash
+		sig    []byte
+		valid  bool
+	}{
+		{eoa, hash, sig, true},
+		{eoa, hash, legacy, true},
+		{eoa, common.Hash{1}, sig, false},
+		{eoa, hash, sig[:64], false},
+		{common.Address{}, hash, []byte{}, false},
+		{wallet, hash, sig, true},
+		{wallet, hash, nil, true},
+		{wallet, common.Hash{1}, sig, false},
+	}
+	for i, tt := range tests {
+		valid, err := sigverify.VerifyHash(context.Background(), sim, nil, tt.signer, tt.hash, tt.sig)
+		if err != nil {
+			t.Errorf("test %d: failed to verify signature: %v", i, err)
+			continue
+		}
+		if valid != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, valid, tt.valid)
+		}
+	}
+}
+
+func TestVerifyText(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{}, 10000000)
+	defer sim.Close()
+
+	text := []byte("sign in")
+	sig, err := crypto.Sign(accounts.TextHash(text), key)
+	if err != nil {
+		t.Fatalf("failed to sign text: %v", err)
+	}
+	sig[64] += 27 // As returned by eth_sign
+
+	if valid, err := sigverify.VerifyText(context.Background(), sim, nil, addr, text, sig); err != nil || !valid {
+		t.Errorf("text signature rejected: %v", err)
+	}
+	if valid, err := sigverify.VerifyText(context.Background(), sim, nil, addr, []byte("sign out"), sig); err != nil || valid {
+		t.Errorf("text signature accepted for different text: %v", err)
+	}
+}
+
+func TestVerifyHashRevertingWallet(t *testing.T) {
+	var (
+		wallet = common.HexToAddress("0x1271")
+		hash   = crypto.Keccak256Hash([]byte("sign in"))
+	)
+	sim := backends.NewSimulatedBackend(core.GenesisAlloc{
+		wallet: {Balance: big.NewInt(0), Code: revertingWalletCode},
+	}, 10000000)
+	defer sim.Close()
+
+	// Sanity check that the revert data would pass for a valid result
+	output, err := sim.CallContract(context.Background(), ethereum.CallMsg{To: &wallet}, nil)
+	if err != nil {
+		t.Fatalf("failed to call wallet: %v", err)
+	}
+	if len(output) != 32 || !bytes.Equal(output[:4], sigverify.MagicValue[:]) {
+		t.Fatalf("unexpected revert data: %x", output)
+	}
+	valid, err := sigverify.VerifyHash(context.Background(), strictCaller{sim}, nil, wallet, hash, nil)
+	if err != nil {
+		t.Fatalf("failed to verify signature: %v", err)
+	}
+	if valid {
+		t.Fatalf("signature accepted by reverting wallet")
+	}
+}
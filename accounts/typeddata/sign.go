@@ -0,0 +1,92 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// ErrInvalidSignature is returned if a signature doesn't have the 65 byte
+// [R || S || V] format, or its recovery id is out of range.
+var ErrInvalidSignature = errors.New("invalid typed data signature")
+
+// Signer is implemented by wallets which sign typed data natively, e.g. by
+// displaying its fields to the user, rather than as an opaque blob.
+type Signer interface {
+	// SignTypedData requests the wallet to sign the EIP-712 typed data. The
+	// signature is returned in the [R || S || V] format where V is 0 or 1.
+	SignTypedData(account accounts.Account, data TypedData) ([]byte, error)
+}
+
+// SignWithWallet signs the typed data with the given account of a wallet. If
+// the wallet implements Signer, signing is delegated to it, otherwise the
+// EIP-712 encoding is signed as accounts.MimetypeTypedData.
+func SignWithWallet(wallet accounts.Wallet, account accounts.Account, data *TypedData) ([]byte, error) {
+	if signer, ok := wallet.(Signer); ok {
+		return signer.SignTypedData(account, *data)
+	}
+	sighash, err := data.SigningData()
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignData(account, accounts.MimetypeTypedData, sighash)
+}
+
+// Sign signs the typed data with a private key. The signature is returned in
+// the [R || S || V] format where V is 0 or 1.
+func Sign(data *TypedData, key *ecdsa.PrivateKey) ([]byte, error) {
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(hash[:], key)
+}
+
+// Recover returns the address of the account that signed the typed data. Both
+// recovery ids 0/1 and the legacy 27/28 are accepted in the signature.
+func Recover(data *TypedData, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+	hash, err := data.Hash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, ErrInvalidSignature
+	}
+	pubkey, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// Verify reports whether the typed data was signed by the given address.
+func Verify(data *TypedData, sig []byte, address common.Address) bool {
+	signer, err := Recover(data, sig)
+	return err == nil && signer == address
+}
@@ -0,0 +1,350 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/common/math"
+)
+
+var (
+	addressT = reflect.TypeOf(common.Address{})
+	hashT    = reflect.TypeOf(common.Hash{})
+	bigT     = reflect.TypeOf(new(big.Int))
+)
+
+// FromStruct assembles the typed data of a message given as a Go struct. The
+// primary type is named after the struct type, and so are the types of nested
+// structs.
+//
+// Fields are mapped to members named after the field with the first letter
+// lowercased, and their types are derived from the Go types:
+//
+//	common.Address          address
+//	bool                    bool
+//	string                  string
+//	[]byte                  bytes
+//	[N]byte, common.Hash    bytesN
+//	intN, uintN             intN, uintN (int and uint map to int256 and uint256)
+//	*big.Int                uint256
+//	struct                  the type of the struct
+//	[]T, [N]T               T[]
+//
+// The member name and type can be overridden with an `eip712:"name,type"` field
+// tag, e.g. to encode a *big.Int as int128. Fields tagged `eip712:"-"` are
+// omitted.
+func FromStruct(domain TypedDataDomain, message interface{}) (*TypedData, error) {
+	value := reflect.ValueOf(message)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, errors.New("nil message")
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("message must be a struct, got %v", value.Type())
+	}
+	enc := &structEncoder{
+		types:   Types{"EIP712Domain": domain.types()},
+		sources: make(map[string]reflect.Type),
+	}
+	primaryType, data, err := enc.encodeStruct(value)
+	if err != nil {
+		return nil, err
+	}
+	typedData := &TypedData{
+		Types:       enc.types,
+		PrimaryType: primaryType,
+		Domain:      domain,
+		Message:     data,
+	}
+	if err := typedData.validate(); err != nil {
+		return nil, err
+	}
+	return typedData, nil
+}
+
+// types returns the members of the EIP712Domain type matching the fields set in
+// the domain.
+func (domain *TypedDataDomain) types() []Type {
+	var types []Type
+	if len(domain.Name) > 0 {
+		types = append(types, Type{Name: "name", Type: "string"})
+	}
+	if len(domain.Version) > 0 {
+		types = append(types, Type{Name: "version", Type: "string"})
+	}
+	if domain.ChainId != nil {
+		types = append(types, Type{Name: "chainId", Type: "uint256"})
+	}
+	if len(domain.VerifyingContract) > 0 {
+		types = append(types, Type{Name: "verifyingContract", Type: "address"})
+	}
+	if len(domain.Salt) > 0 {
+		types = append(types, Type{Name: "salt", Type: "bytes32"})
+	}
+	return types
+}
+
+// structEncoder collects the types and values of a message given as Go structs.
+type structEncoder struct {
+	types   Types
+	sources map[string]reflect.Type // Go types the collected struct types derive from
+}
+
+// encodeStruct collects the type of a struct, returning its name and the value
+// of the struct.
+func (enc *structEncoder) encodeStruct(value reflect.Value) (string, map[string]interface{}, error) {
+	typ := value.Type()
+
+	name := typ.Name()
+	if name == "" {
+		return "", nil, fmt.Errorf("anonymous struct %v can't be encoded", typ)
+	}
+	if source, ok := enc.sources[name]; ok && source != typ {
+		return "", nil, fmt.Errorf("conflicting struct types named %s: %v and %v", name, source, typ)
+	}
+	// Register the type up front, recursive types refer to it while collected
+	enc.sources[name] = typ
+
+	var (
+		members []Type
+		data    = make(map[string]interface{})
+	)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		member, ok := fieldMember(field)
+		if !ok {
+			continue
+		}
+		encType, encValue, err := enc.encode(value.Field(i))
+		if err != nil {
+			return "", nil, fmt.Errorf("field %s.%s: %v", name, field.Name, err)
+		}
+		if member.Type == "" {
+			member.Type = encType
+		}
+		members = append(members, member)
+		data[member.Name] = encValue
+	}
+	enc.types[name] = members
+	return name, data, nil
+}
+
+// encodeStructType collects the type of a struct without a value of it, e.g. for
+// the elements of empty lists, returning its name.
+func (enc *structEncoder) encodeStructType(typ reflect.Type) (string, error) {
+	name := typ.Name()
+	if name == "" {
+		return "", fmt.Errorf("anonymous struct %v can't be encoded", typ)
+	}
+	if source, ok := enc.sources[name]; ok {
+		if source != typ {
+			return "", fmt.Errorf("conflicting struct types named %s: %v and %v", name, source, typ)
+		}
+		return name, nil // Collected already, or being collected
+	}
+	enc.sources[name] = typ
+
+	var members []Type
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		member, ok := fieldMember(field)
+		if !ok {
+			continue
+		}
+		if member.Type == "" {
+			encType, err := enc.encodeType(field.Type)
+			if err != nil {
+				return "", fmt.Errorf("field %s.%s: %v", name, field.Name, err)
+			}
+			member.Type = encType
+		}
+		members = append(members, member)
+	}
+	enc.types[name] = members
+	return name, nil
+}
+
+// fieldMember returns the member a struct field maps to, with the type only set
+// if overridden by the field tag. False is returned for omitted fields.
+func fieldMember(field reflect.StructField) (Type, bool) {
+	if field.PkgPath != "" {
+		return Type{}, false // Unexported field
+	}
+	tag := field.Tag.Get("eip712")
+	if tag == "-" {
+		return Type{}, false
+	}
+	member := Type{Name: lowerFirst(field.Name)}
+	parts := strings.SplitN(tag, ",", 2)
+	if parts[0] != "" {
+		member.Name = parts[0]
+	}
+	if len(parts) == 2 {
+		member.Type = parts[1]
+	}
+	return member, true
+}
+
+// encode returns the type and value of a struct field.
+func (enc *structEncoder) encode(value reflect.Value) (string, interface{}, error) {
+	typ := value.Type()
+	switch typ {
+	case addressT:
+		return "address", value.Interface().(common.Address).Hex(), nil
+	case hashT:
+		hash := value.Interface().(common.Hash)
+		return "bytes32", hexutil.Bytes(hash[:]), nil
+	case bigT:
+		if value.IsNil() {
+			return "", nil, errors.New("nil integer")
+		}
+		return "uint256", (*math.HexOrDecimal256)(value.Interface().(*big.Int)), nil
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "bool", value.Bool(), nil
+
+	case reflect.String:
+		return "string", value.String(), nil
+
+	case reflect.Int:
+		return "int256", (*math.HexOrDecimal256)(big.NewInt(value.Int())), nil
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("int%d", typ.Bits()), (*math.HexOrDecimal256)(big.NewInt(value.Int())), nil
+
+	case reflect.Uint:
+		return "uint256", (*math.HexOrDecimal256)(new(big.Int).SetUint64(value.Uint())), nil
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("uint%d", typ.Bits()), (*math.HexOrDecimal256)(new(big.Int).SetUint64(value.Uint())), nil
+
+	case reflect.Struct:
+		return enc.encodeStruct(value)
+
+	case reflect.Ptr:
+		if value.IsNil() {
+			return "", nil, fmt.Errorf("nil %v", typ)
+		}
+		return enc.encode(value.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			blob := make([]byte, value.Len())
+			reflect.Copy(reflect.ValueOf(blob), value)
+			if typ.Kind() == reflect.Slice {
+				return "bytes", hexutil.Bytes(blob), nil
+			}
+			if len(blob) == 0 || len(blob) > 32 {
+				return "", nil, fmt.Errorf("unsupported byte array size %d", len(blob))
+			}
+			return fmt.Sprintf("bytes%d", len(blob)), hexutil.Bytes(blob), nil
+		}
+		var (
+			elemType string
+			elems    = make([]interface{}, value.Len())
+		)
+		for i := 0; i < value.Len(); i++ {
+			encType, encValue, err := enc.encode(value.Index(i))
+			if err != nil {
+				return "", nil, err
+			}
+			elemType, elems[i] = encType, encValue
+		}
+		if elemType == "" {
+			// Empty list, derive the element type from the Go type
+			encType, err := enc.encodeType(typ.Elem())
+			if err != nil {
+				return "", nil, err
+			}
+			elemType = encType
+		}
+		return elemType + "[]", elems, nil
+	}
+	return "", nil, fmt.Errorf("unsupported type %v", typ)
+}
+
+// encodeType returns the type a Go type maps to, without needing a value of it.
+func (enc *structEncoder) encodeType(typ reflect.Type) (string, error) {
+	switch typ {
+	case addressT:
+		return "address", nil
+	case hashT:
+		return "bytes32", nil
+	case bigT:
+		return "uint256", nil
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "bool", nil
+
+	case reflect.String:
+		return "string", nil
+
+	case reflect.Int:
+		return "int256", nil
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("int%d", typ.Bits()), nil
+
+	case reflect.Uint:
+		return "uint256", nil
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("uint%d", typ.Bits()), nil
+
+	case reflect.Struct:
+		return enc.encodeStructType(typ)
+
+	case reflect.Ptr:
+		return enc.encodeType(typ.Elem())
+
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			if typ.Kind() == reflect.Slice {
+				return "bytes", nil
+			}
+			if typ.Len() == 0 || typ.Len() > 32 {
+				return "", fmt.Errorf("unsupported byte array size %d", typ.Len())
+			}
+			return fmt.Sprintf("bytes%d", typ.Len()), nil
+		}
+		elemType, err := enc.encodeType(typ.Elem())
+		if err != nil {
+			return "", err
+		}
+		return elemType + "[]", nil
+	}
+	return "", fmt.Errorf("unsupported type %v", typ)
+}
+
+// lowerFirst lowercases the first letter of a field name.
+func lowerFirst(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,277 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts/keystore"
+	"github.com/Fantom-foundation/go-ethereum/accounts/typeddata"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/math"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+)
+
+// mailJSON is the example message from the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": "1",
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+// mailHash is the signing hash of the example message.
+var mailHash = common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2")
+
+type Person struct {
+	Name   string
+	Wallet common.Address
+}
+
+type Mail struct {
+	From     Person
+	To       Person
+	Contents string
+}
+
+var mailDomain = typeddata.TypedDataDomain{
+	Name:              "Ether Mail",
+	Version:           "1",
+	ChainId:           math.NewHexOrDecimal256(1),
+	VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+}
+
+var mail = Mail{
+	From:     Person{Name: "Cow", Wallet: common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826")},
+	To:       Person{Name: "Bob", Wallet: common.HexToAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")},
+	Contents: "Hello, Bob!",
+}
+
+func TestHashJSON(t *testing.T) {
+	var data typeddata.TypedData
+	if err := json.Unmarshal([]byte(mailJSON), &data); err != nil {
+		t.Fatalf("failed to unmarshal typed data: %v", err)
+	}
+	hash, err := data.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if hash != mailHash {
+		t.Errorf("hash mismatch: have %x, want %x", hash, mailHash)
+	}
+}
+
+func TestFromStruct(t *testing.T) {
+	data, err := typeddata.FromStruct(mailDomain, &mail)
+	if err != nil {
+		t.Fatalf("failed to assemble typed data: %v", err)
+	}
+	if data.PrimaryType != "Mail" {
+		t.Errorf("primary type mismatch: have %s, want Mail", data.PrimaryType)
+	}
+	if have, want := string(data.EncodeType("Mail")), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", have, want)
+	}
+	hash, err := data.Hash()
+	if err != nil {
+		t.Fatalf("failed to hash typed data: %v", err)
+	}
+	if hash != mailHash {
+		t.Errorf("hash mismatch: have %x, want %x", hash, mailHash)
+	}
+	// Ensure the assembled data survives a JSON round trip, as done when sending
+	// it to an external signer
+	blob, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to marshal typed data: %v", err)
+	}
+	var decoded typeddata.TypedData
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal typed data: %v", err)
+	}
+	if hash, err = decoded.Hash(); err != nil {
+		t.Fatalf("failed to hash decoded typed data: %v", err)
+	}
+	if hash != mailHash {
+		t.Errorf("decoded hash mismatch: have %x, want %x", hash, mailHash)
+	}
+}
+
+type Order struct {
+	Maker    common.Address
+	Amount   *big.Int `eip712:"amount,int128"`
+	Expiry   uint64
+	Salt     [8]byte
+	Payload  []byte
+	Tags     []string
+	Fills    []Fill
+	Internal string `eip712:"-"`
+	private  bool
+}
+
+type Fill struct {
+	Taker common.Address `eip712:"taker"`
+	Done  bool           `eip712:"done"`
+}
+
+func TestFromStructTypes(t *testing.T) {
+	order := Order{
+		Maker:   common.HexToAddress("0x01"),
+		Amount:  big.NewInt(-5),
+		Expiry:  1000,
+		Payload: []byte{0xde, 0xad},
+		Tags:    []string{"a", "b"},
+		Fills:   []Fill{{Taker: common.HexToAddress("0x02"), Done: true}},
+	}
+	data, err := typeddata.FromStruct(mailDomain, order)
+	if err != nil {
+		t.Fatalf("failed to assemble typed data: %v", err)
+	}
+	want := "Order(address maker,int128 amount,uint64 expiry,bytes8 salt,bytes payload,string[] tags,Fill[] fills)Fill(address taker,bool done)"
+	if have := string(data.EncodeType("Order")); have != want {
+		t.Errorf("type encoding mismatch: have %s, want %s", have, want)
+	}
+	if _, err := data.Hash(); err != nil {
+		t.Errorf("failed to hash typed data: %v", err)
+	}
+	// Invalid messages should be rejected
+	if _, err := typeddata.FromStruct(mailDomain, 1); err == nil {
+		t.Error("non-struct message accepted")
+	}
+	if _, err := typeddata.FromStruct(mailDomain, Order{}); err == nil {
+		t.Error("nil integer accepted")
+	}
+	if _, err := typeddata.FromStruct(mailDomain, struct{ A string }{}); err == nil {
+		t.Error("anonymous struct accepted")
+	}
+}
+
+type Thread struct {
+	From    string
+	Replies []Thread
+}
+
+// Tests that self-referential types are collected without recursing forever on
+// empty lists.
+func TestFromStructRecursive(t *testing.T) {
+	threads := []Thread{
+		{From: "a"},
+		{From: "a", Replies: []Thread{{From: "b"}, {From: "c", Replies: []Thread{}}}},
+	}
+	for i, thread := range threads {
+		data, err := typeddata.FromStruct(mailDomain, thread)
+		if err != nil {
+			t.Fatalf("test %d: failed to assemble typed data: %v", i, err)
+		}
+		want := "Thread(string from,Thread[] replies)"
+		if have := string(data.EncodeType("Thread")); have != want {
+			t.Errorf("test %d: type encoding mismatch: have %s, want %s", i, have, want)
+		}
+	}
+}
+
+func TestSignRecover(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	data, err := typeddata.FromStruct(mailDomain, mail)
+	if err != nil {
+		t.Fatalf("failed to assemble typed data: %v", err)
+	}
+	sig, err := typeddata.Sign(data, key)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	if signer, err := typeddata.Recover(data, sig); err != nil || signer != addr {
+		t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, addr)
+	}
+	// Legacy recovery ids should be accepted too
+	sig[64] += 27
+	if !typeddata.Verify(data, sig, addr) {
+		t.Errorf("legacy signature rejected")
+	}
+	sig[64] += 2
+	if _, err := typeddata.Recover(data, sig); err != typeddata.ErrInvalidSignature {
+		t.Errorf("invalid recovery id error mismatch: have %v, want %v", err, typeddata.ErrInvalidSignature)
+	}
+	// Signatures over a different message should not verify
+	other := mail
+	other.Contents = "Hello, Alice!"
+	if data, err = typeddata.FromStruct(mailDomain, other); err != nil {
+		t.Fatalf("failed to assemble typed data: %v", err)
+	}
+	if sig[64] -= 2; typeddata.Verify(data, sig, addr) {
+		t.Errorf("signature verified for a different message")
+	}
+}
+
+func TestSignWithKeystore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "typeddata-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	if err := ks.Unlock(account, ""); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	data, err := typeddata.FromStruct(mailDomain, mail)
+	if err != nil {
+		t.Fatalf("failed to assemble typed data: %v", err)
+	}
+	sig, err := typeddata.SignWithWallet(ks.Wallets()[0], account, data)
+	if err != nil {
+		t.Fatalf("failed to sign typed data: %v", err)
+	}
+	if !typeddata.Verify(data, sig, account.Address) {
+		t.Errorf("keystore signature rejected")
+	}
+}
@@ -52,6 +52,7 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/signer/core"
 	"github.com/Fantom-foundation/go-ethereum/signer/fourbyte"
 	"github.com/Fantom-foundation/go-ethereum/signer/rules"
+	"github.com/Fantom-foundation/go-ethereum/signer/simulation"
 	"github.com/Fantom-foundation/go-ethereum/signer/storage"
 	colorable "github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
@@ -120,6 +121,33 @@ var (
 		Name:  "rules",
 		Usage: "Path to the rule file to auto-authorize requests with",
 	}
+	policyFlag = cli.StringFlag{
+		Name:  "policy",
+		Usage: "Path to the declarative policy file to auto-authorize requests with (instead of --rules)",
+	}
+	simulateFlag = cli.StringFlag{
+		Name:  "simulate",
+		Usage: "RPC endpoint of a node to simulate transactions on before approval (requires eth_getProof)",
+	}
+	quorumApproversFlag = cli.StringFlag{
+		Name:  "quorum.approvers",
+		Usage: "Comma separated addresses of the approvers required to grant sign requests (enables quorum approval)",
+	}
+	quorumThresholdFlag = cli.IntFlag{
+		Name:  "quorum.threshold",
+		Usage: "Number of approvers needed to grant a sign request",
+		Value: 2,
+	}
+	quorumExpiryFlag = cli.DurationFlag{
+		Name:  "quorum.expiry",
+		Usage: "Time after which a sign request not granted by the approvers is rejected",
+		Value: time.Hour,
+	}
+	quorumPortFlag = cli.IntFlag{
+		Name:  "quorum.port",
+		Usage: "HTTP-RPC server listening port of the approval API",
+		Value: node.DefaultHTTPPort + 6,
+	}
 	stdiouiFlag = cli.BoolFlag{
 		Name: "stdio-ui",
 		Usage: "Use STDIN/STDOUT as a channel for an external UI. " +
@@ -187,6 +215,23 @@ The setpw command stores a password for a given address (keyfile).
 		Description: `
 The delpw command removes a password for a given address (keyfile).
 `}
+	dryrunCommand = cli.Command{
+		Action:    utils.MigrateFlags(dryrunPolicy),
+		Name:      "dryrun",
+		Usage:     "Evaluate transaction requests against a policy file",
+		ArgsUsage: "<policy file> <transactions file>",
+		Flags: []cli.Flag{
+			logLevelFlag,
+			customDBFlag,
+		},
+		Description: `
+The dryrun command evaluates a list of transaction requests against a declarative policy file,
+printing whether each would be approved, rejected or passed on for manual approval.
+
+The transactions file contains a JSON list of transactions in the same format as the
+account_signTransaction API. They are evaluated in order, with approved transactions counting
+towards the rolling limits of the subsequent ones.`,
+	}
 	gendocCommand = cli.Command{
 		Action: GenDoc,
 		Name:   "gendoc",
@@ -207,6 +252,11 @@ func init() {
 		utils.LightKDFFlag,
 		utils.NoUSBFlag,
 		utils.SmartCardDaemonPathFlag,
+		utils.PKCS11ModuleFlag,
+		utils.PKCS11TokenFlag,
+		utils.PKCS11PINFileFlag,
+		utils.RemoteSignerFlag,
+		utils.RemoteSignerTokenFileFlag,
 		utils.RPCListenAddrFlag,
 		utils.RPCVirtualHostsFlag,
 		utils.IPCDisabledFlag,
@@ -217,12 +267,18 @@ func init() {
 		customDBFlag,
 		auditLogFlag,
 		ruleFlag,
+		policyFlag,
+		simulateFlag,
+		quorumApproversFlag,
+		quorumThresholdFlag,
+		quorumExpiryFlag,
+		quorumPortFlag,
 		stdiouiFlag,
 		testFlag,
 		advancedMode,
 	}
 	app.Action = signer
-	app.Commands = []cli.Command{initCommand, attestCommand, setCredentialCommand, delCredentialCommand, gendocCommand}
+	app.Commands = []cli.Command{initCommand, attestCommand, setCredentialCommand, delCredentialCommand, dryrunCommand, gendocCommand}
 }
 
 func main() {
@@ -323,6 +379,52 @@ func attestFile(ctx *cli.Context) error {
 	return nil
 }
 
+func dryrunPolicy(ctx *cli.Context) error {
+	if len(ctx.Args()) < 2 {
+		utils.Fatalf("This command requires a policy and a transactions file.")
+	}
+	if err := initialize(ctx); err != nil {
+		return err
+	}
+	policyJSON, err := ioutil.ReadFile(ctx.Args()[0])
+	if err != nil {
+		utils.Fatalf("Failed to read policy: %v", err)
+	}
+	policy, err := rules.ParsePolicy(policyJSON)
+	if err != nil {
+		utils.Fatalf("Invalid policy: %v", err)
+	}
+	txsJSON, err := ioutil.ReadFile(ctx.Args()[1])
+	if err != nil {
+		utils.Fatalf("Failed to read transactions: %v", err)
+	}
+	var txs []core.SendTxArgs
+	if err := json.Unmarshal(txsJSON, &txs); err != nil {
+		utils.Fatalf("Invalid transactions: %v", err)
+	}
+	db, err := fourbyte.NewWithFile(ctx.GlobalString(customDBFlag.Name))
+	if err != nil {
+		utils.Fatalf(err.Error())
+	}
+	requests := make([]*core.SignTxRequest, len(txs))
+	for i := range txs {
+		requests[i] = &core.SignTxRequest{Transaction: txs[i]}
+	}
+	for i, decision := range rules.DryRun(policy, db, requests) {
+		fmt.Printf("Transaction #%d: %s\n", i, decision.Verdict)
+		if decision.Rule != "" {
+			fmt.Printf("  rule:   %s\n", decision.Rule)
+		}
+		if decision.Method != "" {
+			fmt.Printf("  method: %s\n", decision.Method)
+		}
+		for _, reason := range decision.Reasons {
+			fmt.Printf("  reason: %s\n", reason)
+		}
+	}
+	return nil
+}
+
 func setCredential(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an address to be passed as an argument")
@@ -425,6 +527,22 @@ func ipcEndpoint(ipcPath, datadir string) string {
 	return ipcPath
 }
 
+// checkApprovalFlags ensures that at most one mechanism to automatically approve
+// or grant requests is configured. Rules and policies wrap the quorum, so their
+// approvals would otherwise sign without the approvers' signatures.
+func checkApprovalFlags(c *cli.Context) error {
+	var set []string
+	for _, flag := range []cli.StringFlag{ruleFlag, policyFlag, quorumApproversFlag} {
+		if c.GlobalString(flag.Name) != "" {
+			set = append(set, "--"+flag.Name)
+		}
+	}
+	if len(set) > 1 {
+		return fmt.Errorf("%s can't be combined", strings.Join(set, " and "))
+	}
+	return nil
+}
+
 func signer(c *cli.Context) error {
 	// If we have some unrecognized command, bail out
 	if args := c.Args(); len(args) > 0 {
@@ -433,6 +551,9 @@ func signer(c *cli.Context) error {
 	if err := initialize(c); err != nil {
 		return err
 	}
+	if err := checkApprovalFlags(c); err != nil {
+		utils.Fatalf("%v", err)
+	}
 	var (
 		ui core.UIClientAPI
 	)
@@ -443,6 +564,26 @@ func signer(c *cli.Context) error {
 		log.Info("Using CLI as UI-channel")
 		ui = core.NewCommandlineUI()
 	}
+	// Require approver signatures instead of the user's confirmation if a quorum is configured
+	var quorum *core.QuorumUI
+	if approvers := c.GlobalString(quorumApproversFlag.Name); approvers != "" {
+		config := core.QuorumConfig{
+			Threshold: c.GlobalInt(quorumThresholdFlag.Name),
+			Expiry:    c.GlobalDuration(quorumExpiryFlag.Name),
+		}
+		for _, approver := range splitAndTrim(approvers) {
+			if !common.IsHexAddress(approver) {
+				utils.Fatalf("Invalid approver address %q", approver)
+			}
+			config.Approvers = append(config.Approvers, common.HexToAddress(approver))
+		}
+		var err error
+		if quorum, err = core.NewQuorumUI(ui, config); err != nil {
+			utils.Fatalf("Invalid quorum: %v", err)
+		}
+		ui = quorum
+		log.Info("Quorum approval configured", "threshold", config.Threshold, "approvers", len(config.Approvers), "expiry", config.Expiry)
+	}
 	// 4bytedb data
 	fourByteLocal := c.GlobalString(customDBFlag.Name)
 	db, err := fourbyte.NewWithFile(fourByteLocal)
@@ -496,6 +637,27 @@ func signer(c *cli.Context) error {
 				}
 			}
 		}
+		// Do we have a policy file?
+		if policyFile := c.GlobalString(policyFlag.Name); policyFile != "" {
+			policyJSON, err := ioutil.ReadFile(policyFile)
+			if err != nil {
+				log.Warn("Could not load policy, disabling", "file", policyFile, "err", err)
+			} else {
+				shasum := sha256.Sum256(policyJSON)
+				foundShaSum := hex.EncodeToString(shasum[:])
+				storedShasum, _ := configStorage.Get("ruleset_sha256")
+				if storedShasum != foundShaSum {
+					log.Warn("Policy hash not attested, disabling", "hash", foundShaSum, "attested", storedShasum)
+				} else {
+					policy, err := rules.ParsePolicy(policyJSON)
+					if err != nil {
+						utils.Fatalf("Invalid policy %s: %v", policyFile, err)
+					}
+					ui = rules.NewPolicyEvaluator(ui, policy, db, jsStorage)
+					log.Info("Policy engine configured", "file", policyFile)
+				}
+			}
+		}
 	}
 	var (
 		chainId  = c.GlobalInt64(chainIdFlag.Name)
@@ -507,19 +669,48 @@ func signer(c *cli.Context) error {
 	)
 	log.Info("Starting signer", "chainid", chainId, "keystore", ksLoc,
 		"light-kdf", lightKdf, "advanced", advanced)
-	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath)
-	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, advanced, pwStorage)
+	// Sign with keys in a hardware security module or remote signing service if requested
+	var remoteKeys []accounts.Backend
+	keyCfg := new(node.Config)
+	utils.SetRemoteKeys(c, keyCfg)
+	if hsmhub, err := keyCfg.RemoteKeyBackend(); err != nil {
+		utils.Fatalf("Failed to open remote keys: %v", err)
+	} else if hsmhub != nil {
+		remoteKeys = append(remoteKeys, hsmhub)
+		defer hsmhub.Close()
+	}
+	am := core.StartClefAccountManager(ksLoc, nousb, lightKdf, scpath, remoteKeys...)
+	// Simulate transactions on a node if requested, so the user sees their effects
+	var simulator core.Simulator
+	if endpoint := c.GlobalString(simulateFlag.Name); endpoint != "" {
+		client, err := rpc.Dial(endpoint)
+		if err != nil {
+			utils.Fatalf("Could not connect to simulation node: %v", err)
+		}
+		defer client.Close()
+		simulator = simulation.New(client)
+		log.Info("Transaction simulation enabled", "node", endpoint)
+	}
+	apiImpl := core.NewSignerAPI(am, chainId, nousb, ui, db, simulator, advanced, pwStorage)
 
 	// Establish the bidirectional communication, by creating a new UI backend and registering
 	// it with the UI.
 	ui.RegisterUIServer(core.NewUIServerAPI(apiImpl))
 	api = apiImpl
 	// Audit logging
+	var approvalAPI core.ApprovalAPI
+	if quorum != nil {
+		approvalAPI = quorum.API()
+	}
 	if logfile := c.GlobalString(auditLogFlag.Name); logfile != "" {
-		api, err = core.NewAuditLogger(logfile, api)
+		auditLogger, err := core.NewAuditLogger(logfile, api)
 		if err != nil {
 			utils.Fatalf(err.Error())
 		}
+		api = auditLogger
+		if quorum != nil {
+			approvalAPI = core.NewApprovalAuditLogger(auditLogger, quorum)
+		}
 		log.Info("Audit logs configured", "file", logfile)
 	}
 	// register signer API with server
@@ -538,9 +729,13 @@ func signer(c *cli.Context) error {
 		vhosts := splitAndTrim(c.GlobalString(utils.RPCVirtualHostsFlag.Name))
 		cors := splitAndTrim(c.GlobalString(utils.RPCCORSDomainFlag.Name))
 
-		// start http server
+		// start http server, allowing requests to wait for the approvers if needed
+		timeouts := rpc.DefaultHTTPTimeouts
+		if quorum != nil {
+			timeouts.WriteTimeout += c.GlobalDuration(quorumExpiryFlag.Name)
+		}
 		httpEndpoint := fmt.Sprintf("%s:%d", c.GlobalString(utils.RPCListenAddrFlag.Name), c.Int(rpcPortFlag.Name))
-		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, []string{"account"}, cors, vhosts, rpc.DefaultHTTPTimeouts)
+		listener, _, err := rpc.StartHTTPEndpoint(httpEndpoint, rpcAPI, []string{"account"}, cors, vhosts, timeouts, nil, nil)
 		if err != nil {
 			utils.Fatalf("Could not start RPC api: %v", err)
 		}
@@ -552,6 +747,28 @@ func signer(c *cli.Context) error {
 			log.Info("HTTP endpoint closed", "url", httpEndpoint)
 		}()
 	}
+	if approvalAPI != nil {
+		// The approval API is served separately from the external API, as it is
+		// meant for the approvers and not the applications requesting signatures.
+		approvalEndpoint := fmt.Sprintf("%s:%d", c.GlobalString(utils.RPCListenAddrFlag.Name), c.GlobalInt(quorumPortFlag.Name))
+		vhosts := splitAndTrim(c.GlobalString(utils.RPCVirtualHostsFlag.Name))
+		listener, _, err := rpc.StartHTTPEndpoint(approvalEndpoint, []rpc.API{
+			{
+				Namespace: "approval",
+				Public:    true,
+				Service:   approvalAPI,
+				Version:   "1.0"},
+		}, []string{"approval"}, nil, vhosts, rpc.DefaultHTTPTimeouts, nil, nil)
+		if err != nil {
+			utils.Fatalf("Could not start approval api: %v", err)
+		}
+		log.Info("Approval endpoint opened", "url", fmt.Sprintf("http://%s", approvalEndpoint))
+
+		defer func() {
+			listener.Close()
+			log.Info("Approval endpoint closed", "url", approvalEndpoint)
+		}()
+	}
 	if !c.GlobalBool(utils.IPCDisabledFlag.Name) {
 		givenPath := c.GlobalString(utils.IPCPathFlag.Name)
 		ipcapiURL = ipcEndpoint(filepath.Join(givenPath, "clef.ipc"), configDir)
@@ -938,7 +1155,7 @@ func GenDoc(ctx *cli.Context) {
 			"of the work in canonicalizing and making sense of the data, and it's up to the UI to present" +
 			"the user with the contents of the `message`"
 		sighash, msg := accounts.TextAndHash([]byte("hello world"))
-		messages := []*core.NameValueType{{"message", msg, accounts.MimetypeTextPlain}}
+		messages := []*core.NameValueType{{Name: "message", Value: msg, Typ: accounts.MimetypeTextPlain}}
 
 		add("SignDataRequest", desc, &core.SignDataRequest{
 			Address:     common.NewMixedcaseAddress(a),
@@ -0,0 +1,54 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Tests that the mechanisms approving requests without the user are exclusive,
+// so that rules or policies can't sign without the quorum of approvers.
+func TestCheckApprovalFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		fail bool
+	}{
+		{args: nil},
+		{args: []string{"--rules", "rules.js"}},
+		{args: []string{"--policy", "policy.json"}},
+		{args: []string{"--quorum.approvers", "0x01,0x02"}},
+		{args: []string{"--rules", "rules.js", "--policy", "policy.json"}, fail: true},
+		{args: []string{"--quorum.approvers", "0x01,0x02", "--rules", "rules.js"}, fail: true},
+		{args: []string{"--quorum.approvers", "0x01,0x02", "--policy", "policy.json"}, fail: true},
+	}
+	for i, tt := range tests {
+		set := flag.NewFlagSet("clef", flag.ContinueOnError)
+		for _, f := range []cli.StringFlag{ruleFlag, policyFlag, quorumApproversFlag} {
+			f.Apply(set)
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("test %d: failed to parse flags: %v", i, err)
+		}
+		err := checkApprovalFlags(cli.NewContext(cli.NewApp(), set, nil))
+		if fail := err != nil; fail != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.fail)
+		}
+	}
+}
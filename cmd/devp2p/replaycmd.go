@@ -0,0 +1,239 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/p2p/msgrec"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	replayCommand = cli.Command{
+		Name:  "replay",
+		Usage: "Message capture tools",
+		Subcommands: []cli.Command{
+			replayDumpCommand,
+			replaySendCommand,
+		},
+	}
+	replayDumpCommand = cli.Command{
+		Name:      "dump",
+		Usage:     "Prints the messages of a capture file",
+		Action:    replayDump,
+		ArgsUsage: "<capture>",
+		Flags:     []cli.Flag{replayPeerFlag, replayProtocolFlag},
+	}
+	replaySendCommand = cli.Command{
+		Name:      "send",
+		Usage:     "Connects to a node and sends it the inbound messages of a capture",
+		Action:    replaySend,
+		ArgsUsage: "<capture> <node>",
+		Flags:     []cli.Flag{replayPeerFlag, replayProtocolFlag, replayTimeoutFlag},
+	}
+)
+
+var (
+	replayPeerFlag = cli.StringFlag{
+		Name:  "peer",
+		Usage: "Only use messages of the peer with this node ID",
+	}
+	replayProtocolFlag = cli.StringFlag{
+		Name:  "protocol",
+		Usage: "Only use messages of this protocol (name or name/version)",
+	}
+	replayTimeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "Time limit for connecting and sending",
+		Value: 30 * time.Second,
+	}
+)
+
+func replayDump(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need capture file as argument")
+	}
+	filter := getReplayFilter(ctx)
+	r, closer, err := msgrec.Open(ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	var start time.Time
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !filter.Match(rec) {
+			continue
+		}
+		if start.IsZero() {
+			start = rec.Timestamp()
+		}
+		dir := "<-"
+		if !rec.Inbound {
+			dir = "->"
+		}
+		fmt.Printf("%-12v %x %s %-21s %s/%d code=%d size=%d\n", rec.Timestamp().Sub(start), rec.Peer[:8], dir, rec.Remote, rec.Protocol, rec.Version, rec.Code, rec.Size)
+	}
+}
+
+func replaySend(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("need capture file and node as arguments")
+	}
+	file := ctx.Args().Get(0)
+	node, err := parseNode(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	filter := getReplayFilter(ctx)
+	if filter.Protocol == "" || filter.Version == 0 {
+		return fmt.Errorf("-%s name/version is required", replayProtocolFlag.Name)
+	}
+	length, err := captureProtocolLength(file, filter)
+	if err != nil {
+		return err
+	}
+
+	// Run a server with a single protocol, which feeds the capture into
+	// the remote node.
+	type result struct {
+		count int
+		err   error
+	}
+	done := make(chan result, 1)
+	report := func(res result) {
+		select {
+		case done <- res:
+		default:
+		}
+	}
+	proto := p2p.Protocol{
+		Name:    filter.Protocol,
+		Version: filter.Version,
+		Length:  length,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			go func() {
+				for {
+					msg, err := rw.ReadMsg()
+					if err != nil {
+						return
+					}
+					msg.Discard()
+				}
+			}()
+			r, closer, err := msgrec.Open(file)
+			if err != nil {
+				report(result{err: err})
+				return err
+			}
+			defer closer.Close()
+			count, err := msgrec.Feed(r, rw, filter)
+			report(result{count, err})
+			return err
+		},
+	}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	srv := &p2p.Server{
+		Config: p2p.Config{
+			PrivateKey:  key,
+			MaxPeers:    1,
+			NoDiscovery: true,
+			Protocols:   []p2p.Protocol{proto},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		return err
+	}
+	defer srv.Stop()
+	srv.AddPeer(node)
+
+	select {
+	case res := <-done:
+		fmt.Printf("sent %d messages\n", res.count)
+		return res.err
+	case <-time.After(ctx.Duration(replayTimeoutFlag.Name)):
+		return fmt.Errorf("timed out, node did not accept %s/%d", filter.Protocol, filter.Version)
+	}
+}
+
+// getReplayFilter creates a capture filter from command-line flags.
+func getReplayFilter(ctx *cli.Context) msgrec.Filter {
+	var f msgrec.Filter
+	if id := ctx.String(replayPeerFlag.Name); id != "" {
+		b, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+		if err != nil || len(b) != len(f.Peer) {
+			exit(fmt.Errorf("-%s: invalid node ID %q", replayPeerFlag.Name, id))
+		}
+		copy(f.Peer[:], b)
+	}
+	if proto := ctx.String(replayProtocolFlag.Name); proto != "" {
+		parts := strings.SplitN(proto, "/", 2)
+		f.Protocol = parts[0]
+		if len(parts) == 2 {
+			v, err := strconv.ParseUint(parts[1], 10, 32)
+			if err != nil {
+				exit(fmt.Errorf("-%s: invalid version %q", replayProtocolFlag.Name, parts[1]))
+			}
+			f.Version = uint(v)
+		}
+	}
+	return f
+}
+
+// captureProtocolLength returns the number of message codes needed to send
+// the messages of the capture selected by f.
+func captureProtocolLength(file string, f msgrec.Filter) (uint64, error) {
+	r, closer, err := msgrec.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer closer.Close()
+
+	var length uint64
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		if rec.Inbound && f.Match(rec) && rec.Code >= length {
+			length = rec.Code + 1
+		}
+	}
+	if length == 0 {
+		return 0, fmt.Errorf("no matching messages in %s", file)
+	}
+	return length, nil
+}
@@ -158,6 +158,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
 			utils.ExternalSignerFlag,
+			utils.PKCS11ModuleFlag,
+			utils.PKCS11TokenFlag,
+			utils.PKCS11PINFileFlag,
+			utils.RemoteSignerFlag,
+			utils.RemoteSignerTokenFileFlag,
 			utils.InsecureUnlockAllowedFlag,
 		},
 	},
@@ -173,11 +178,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCGlobalGasCap,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCSlowCallFlag,
+			utils.RPCSlowCallParamsFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSJWTSecretFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLListenAddrFlag,
 			utils.GraphQLPortFlag,
@@ -200,7 +209,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
+			utils.DiscoveryV51Flag,
+			utils.TLSTransportFlag,
 			utils.NetrestrictFlag,
+			utils.MsgRecordFlag,
+			utils.MsgRecordPayloadsFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
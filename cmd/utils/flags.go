@@ -58,6 +58,7 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/p2p"
 	"github.com/Fantom-foundation/go-ethereum/p2p/discv5"
 	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/msgrec"
 	"github.com/Fantom-foundation/go-ethereum/p2p/nat"
 	"github.com/Fantom-foundation/go-ethereum/p2p/netutil"
 	"github.com/Fantom-foundation/go-ethereum/params"
@@ -150,6 +151,26 @@ var (
 		Usage: "Path to the smartcard daemon (pcscd) socket file",
 		Value: pcsclite.PCSCDSockName,
 	}
+	PKCS11ModuleFlag = cli.StringFlag{
+		Name:  "pkcs11.module",
+		Usage: "Path to a PKCS#11 module to sign with the secp256k1 keys of its token",
+	}
+	PKCS11TokenFlag = cli.StringFlag{
+		Name:  "pkcs11.token",
+		Usage: "Label of the PKCS#11 token to use (default = first token)",
+	}
+	PKCS11PINFileFlag = cli.StringFlag{
+		Name:  "pkcs11.pinfile",
+		Usage: "File containing the user PIN of the PKCS#11 token",
+	}
+	RemoteSignerFlag = cli.StringFlag{
+		Name:  "remotesigner",
+		Usage: "URL of an HTTP signing service to sign with the keys of",
+	}
+	RemoteSignerTokenFileFlag = cli.StringFlag{
+		Name:  "remotesigner.tokenfile",
+		Usage: "File containing the bearer token to authenticate to the remote signer",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -551,6 +572,19 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpcjwtsecret",
+		Usage: "Path to a hex encoded JWT secret to authenticate HTTP-RPC requests with (generated if missing)",
+		Value: "",
+	}
+	RPCSlowCallFlag = cli.DurationFlag{
+		Name:  "rpcslowcall",
+		Usage: "Serving time above which RPC calls are logged (0 = disabled)",
+	}
+	RPCSlowCallParamsFlag = cli.BoolFlag{
+		Name:  "rpcslowcall.params",
+		Usage: "Logs the parameters of slow RPC calls at debug level (never for personal, account and clef methods)",
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -575,6 +609,11 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSJWTSecretFlag = cli.StringFlag{
+		Name:  "wsjwtsecret",
+		Usage: "Path to a hex encoded JWT secret to authenticate WS-RPC connections with (generated if missing)",
+		Value: "",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable the GraphQL server",
@@ -660,10 +699,26 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	DiscoveryV51Flag = cli.BoolFlag{
+		Name:  "v51disc",
+		Usage: "Enables the ENR-based discovery v5.1 protocol alongside v4 discovery",
+	}
+	TLSTransportFlag = cli.BoolFlag{
+		Name:  "tlstransport",
+		Usage: "Carries peer connections in TLS over TCP (only reaches TLS peers found by --v51disc or given as enr: URLs)",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	MsgRecordFlag = cli.StringFlag{
+		Name:  "msgrecord",
+		Usage: "Records all peer protocol messages to the given capture file",
+	}
+	MsgRecordPayloadsFlag = cli.BoolFlag{
+		Name:  "msgrecord.payloads",
+		Usage: "Includes message payloads in the capture file",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -926,6 +981,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.HTTPJWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -964,6 +1022,9 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSJWTSecretFlag.Name) {
+		cfg.WSJWTSecret = ctx.GlobalString(WSJWTSecretFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1152,6 +1213,26 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	} else if forceV5Discovery {
 		cfg.DiscoveryV5 = true
 	}
+	if ctx.GlobalIsSet(DiscoveryV51Flag.Name) {
+		cfg.DiscoveryV51 = ctx.GlobalBool(DiscoveryV51Flag.Name)
+	}
+	if cfg.DiscoveryV5 && cfg.DiscoveryV51 {
+		Fatalf("Option %q can't be combined with topic discovery (--%s, implied by light client/server mode)", DiscoveryV51Flag.Name, DiscoveryV5Flag.Name)
+	}
+	if cfg.DiscoveryV51 && cfg.BootstrapNodesV51 == nil {
+		cfg.BootstrapNodesV51 = cfg.BootstrapNodes
+	}
+
+	if ctx.GlobalBool(TLSTransportFlag.Name) {
+		tr, err := p2p.NewTLSTransport()
+		if err != nil {
+			Fatalf("Option %q: %v", TLSTransportFlag.Name, err)
+		}
+		cfg.Transport = tr
+		if !cfg.DiscoveryV51 {
+			log.Warn("TLS transport only reaches peers given as enr: URLs without discovery v5.1", "flag", DiscoveryV51Flag.Name)
+		}
+	}
 
 	if netrestrict := ctx.GlobalString(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
@@ -1161,12 +1242,21 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NetRestrict = list
 	}
 
+	if file := ctx.GlobalString(MsgRecordFlag.Name); file != "" {
+		rec, err := msgrec.Create(file, ctx.GlobalBool(MsgRecordPayloadsFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", MsgRecordFlag.Name, err)
+		}
+		cfg.Recorder = rec
+	}
+
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
 		cfg.MaxPeers = 0
 		cfg.ListenAddr = ":0"
 		cfg.NoDiscovery = true
 		cfg.DiscoveryV5 = false
+		cfg.DiscoveryV51 = false
 	}
 }
 
@@ -1180,6 +1270,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
+	SetRemoteKeys(ctx, cfg)
 
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
@@ -1197,6 +1288,12 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(InsecureUnlockAllowedFlag.Name) {
 		cfg.InsecureUnlockAllowed = ctx.GlobalBool(InsecureUnlockAllowedFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCSlowCallFlag.Name) {
+		cfg.RPCSlowCallThreshold = ctx.GlobalDuration(RPCSlowCallFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCSlowCallParamsFlag.Name) {
+		cfg.RPCSlowCallParams = ctx.GlobalBool(RPCSlowCallParamsFlag.Name)
+	}
 }
 
 func setSmartCard(ctx *cli.Context, cfg *node.Config) {
@@ -1219,6 +1316,35 @@ func setSmartCard(ctx *cli.Context, cfg *node.Config) {
 	cfg.SmartCardDaemonPath = path
 }
 
+// SetRemoteKeys configures the PKCS#11 token and remote signer to sign with,
+// reading their credentials from the given files.
+func SetRemoteKeys(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(PKCS11ModuleFlag.Name) {
+		cfg.PKCS11Module = ctx.GlobalString(PKCS11ModuleFlag.Name)
+	}
+	if ctx.GlobalIsSet(PKCS11TokenFlag.Name) {
+		cfg.PKCS11Token = ctx.GlobalString(PKCS11TokenFlag.Name)
+	}
+	if path := ctx.GlobalString(PKCS11PINFileFlag.Name); path != "" {
+		cfg.PKCS11PIN = readSecretFile(path)
+	}
+	if ctx.GlobalIsSet(RemoteSignerFlag.Name) {
+		cfg.RemoteSigner = ctx.GlobalString(RemoteSignerFlag.Name)
+	}
+	if path := ctx.GlobalString(RemoteSignerTokenFileFlag.Name); path != "" {
+		cfg.RemoteSignerToken = readSecretFile(path)
+	}
+}
+
+// readSecretFile reads a secret from the first line of a file.
+func readSecretFile(path string) string {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read secret file: %v", err)
+	}
+	return strings.TrimRight(strings.SplitN(string(text), "\n", 2)[0], "\r")
+}
+
 func setDataDir(ctx *cli.Context, cfg *node.Config) {
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
@@ -16,6 +16,8 @@
 
 package vm
 
+import "github.com/Fantom-foundation/go-ethereum/params"
+
 // bitvec is a bit vector which maps bytes in a program.
 // An unset bit means the byte is an opcode, a set bit means
 // it's data (i.e. argument of PUSHxx).
@@ -60,3 +62,85 @@ func codeBitmap(code []byte) bitvec {
 	}
 	return bits
 }
+
+// basicBlock is a straight-line run of instructions that can only be entered
+// at its first instruction and is only left after its last one. The static
+// requirements of all contained instructions are aggregated, so that the
+// interpreter can validate them once upon entering the block.
+type basicBlock struct {
+	constantGas uint64 // Sum of the constant gas costs of all instructions
+	minStack    int    // Minimum stack height required on entry
+	maxStack    int    // Maximum stack height allowed on entry
+	end         uint64 // Program counter right after the last instruction
+}
+
+// blockMap maps the program counters of block entry points to the aggregated
+// static requirements of the blocks starting there.
+type blockMap map[uint64]basicBlock
+
+// readsGas checks whether the operation observes the remaining gas of the
+// contract, in which case the constant gas of subsequent instructions must
+// not be charged before the operation has been executed.
+func readsGas(op OpCode) bool {
+	switch op {
+	case GAS, SSTORE, CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2:
+		return true
+	}
+	return false
+}
+
+// codeBlocks splits code into basic blocks according to the given instruction
+// set. A block starts at the beginning of the code, at every JUMPDEST and after
+// every instruction which terminates a block: jumps, halts, invalid opcodes
+// and instructions that read the remaining gas.
+func codeBlocks(code []byte, jt *JumpTable) blockMap {
+	var (
+		blocks = make(blockMap)
+		block  basicBlock
+		start  uint64
+		height int  // stack height relative to block entry
+		open   bool // whether a block is currently being assembled
+	)
+	for pc := uint64(0); ; {
+		// Instructions outside of the code are implicit STOPs
+		op := STOP
+		if pc < uint64(len(code)) {
+			op = OpCode(code[pc])
+		}
+		// Jump destinations always start a new block
+		if op == JUMPDEST && open {
+			block.end = pc
+			blocks[start] = block
+			open = false
+		}
+		if !open {
+			start, block, height, open = pc, basicBlock{maxStack: int(params.StackLimit)}, 0, true
+		}
+		operation := &jt[op]
+		if operation.valid {
+			if need := operation.minStack - height; need > block.minStack {
+				block.minStack = need
+			}
+			if limit := operation.maxStack - height; limit < block.maxStack {
+				block.maxStack = limit
+			}
+			block.constantGas += operation.constantGas
+			height += int(params.StackLimit) - operation.maxStack
+		}
+		next := pc + 1
+		if op >= PUSH1 && op <= PUSH32 {
+			next += uint64(op - PUSH1 + 1)
+		}
+		if !operation.valid || operation.halts || operation.jumps || operation.reverts || readsGas(op) {
+			block.end = next
+			blocks[start] = block
+			open = false
+
+			if pc >= uint64(len(code)) {
+				break
+			}
+		}
+		pc = next
+	}
+	return blocks
+}
@@ -73,3 +73,29 @@ func BenchmarkJumpdestHashing_1200k(bench *testing.B) {
 	}
 	bench.StopTimer()
 }
+
+func TestCodeBlocks(t *testing.T) {
+	code := []byte{
+		byte(PUSH1), 0x01, byte(PUSH1), 0x02, byte(ADD), // block 0: 3+3+3 gas, stack -> 1
+		byte(JUMPDEST), byte(POP), byte(GAS), // block 5: 1+2+2 gas, ends on GAS
+		byte(SWAP2), byte(PUSH1), 0x00, byte(JUMP), // block 8: 3+3+8 gas
+		byte(PUSH2), byte(JUMPDEST), // block 12: JUMPDEST within push data, implicit STOP
+	}
+	jt := newIstanbulInstructionSet()
+	blocks := codeBlocks(code, &jt)
+
+	want := blockMap{
+		0:  {constantGas: 9, minStack: 0, maxStack: 1022, end: 5},
+		5:  {constantGas: 5, minStack: 1, maxStack: 1024, end: 8},
+		8:  {constantGas: 14, minStack: 3, maxStack: 1023, end: 12},
+		12: {constantGas: 3, minStack: 0, maxStack: 1023, end: 16},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("block count mismatch: have %d, want %d", len(blocks), len(want))
+	}
+	for pc, block := range want {
+		if have := blocks[pc]; have != block {
+			t.Errorf("block %d mismatch: have %+v, want %+v", pc, have, block)
+		}
+	}
+}
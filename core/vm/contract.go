@@ -52,6 +52,9 @@ type Contract struct {
 	jumpdests map[common.Hash]bitvec // Aggregated result of JUMPDEST analysis.
 	analysis  bitvec                 // Locally cached result of JUMPDEST analysis
 
+	blockdests map[common.Hash]blockMap // Aggregated result of basic block analysis
+	blocks     blockMap                 // Locally cached result of basic block analysis
+
 	Code     []byte
 	CodeHash common.Hash
 	CodeAddr *common.Address
@@ -66,10 +69,12 @@ func NewContract(caller ContractRef, object ContractRef, value *big.Int, gas uin
 	c := &Contract{CallerAddress: caller.Address(), caller: caller, self: object}
 
 	if parent, ok := caller.(*Contract); ok {
-		// Reuse JUMPDEST analysis from parent context if available.
+		// Reuse JUMPDEST and block analysis from parent context if available.
 		c.jumpdests = parent.jumpdests
+		c.blockdests = parent.blockdests
 	} else {
 		c.jumpdests = make(map[common.Hash]bitvec)
+		c.blockdests = make(map[common.Hash]blockMap)
 	}
 
 	// Gas should be a pointer so it can safely be reduced through the run
@@ -114,6 +119,24 @@ func (c *Contract) validJumpdest(dest *big.Int) bool {
 	return c.analysis.codeSegment(udest)
 }
 
+// basicBlocks returns the basic block analysis of the contract code for the
+// given instruction set, caching it in the parent context if the code hash
+// is known, or locally otherwise.
+func (c *Contract) basicBlocks(jt *JumpTable) blockMap {
+	if c.CodeHash != (common.Hash{}) {
+		blocks, exist := c.blockdests[c.CodeHash]
+		if !exist {
+			blocks = codeBlocks(c.Code, jt)
+			c.blockdests[c.CodeHash] = blocks
+		}
+		return blocks
+	}
+	if c.blocks == nil {
+		c.blocks = codeBlocks(c.Code, jt)
+	}
+	return c.blocks
+}
+
 // AsDelegate sets the contract to be a delegate call and returns the current
 // contract (for chaining calls)
 func (c *Contract) AsDelegate() *Contract {
@@ -173,9 +173,18 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
 		res     []byte // result of the opcode execution function
+
+		// Static checks are aggregated per basic block unless tracing, in
+		// which case every instruction is checked on its own.
+		blocks   blockMap
+		blockEnd = uint64(0) // end of the current block, 0 if a new one is due
 	)
 	contract.Input = input
 
+	if !in.cfg.Debug {
+		blocks = contract.basicBlocks((*JumpTable)(&in.cfg.JumpTable))
+	}
+
 	// Reclaim the stack as an int pool when the execution stops
 	defer func() { in.intPool.put(stack.data...) }()
 
@@ -200,6 +209,20 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			logged, pcCopy, gasCopy = false, pc, contract.Gas
 		}
 
+		// When entering a new basic block, validate the stack bounds and charge the
+		// static gas of all its instructions at once.
+		if blocks != nil && pc >= blockEnd {
+			block := blocks[pc]
+			if sLen := stack.len(); sLen < block.minStack {
+				return nil, fmt.Errorf("stack underflow (%d <=> %d)", sLen, block.minStack)
+			} else if sLen > block.maxStack {
+				return nil, fmt.Errorf("stack limit reached %d (%d)", sLen, block.maxStack)
+			}
+			if !contract.UseGas(block.constantGas) {
+				return nil, ErrOutOfGas
+			}
+			blockEnd = block.end
+		}
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
@@ -208,10 +231,12 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			return nil, fmt.Errorf("invalid opcode 0x%x", int(op))
 		}
 		// Validate stack
-		if sLen := stack.len(); sLen < operation.minStack {
-			return nil, fmt.Errorf("stack underflow (%d <=> %d)", sLen, operation.minStack)
-		} else if sLen > operation.maxStack {
-			return nil, fmt.Errorf("stack limit reached %d (%d)", sLen, operation.maxStack)
+		if blocks == nil {
+			if sLen := stack.len(); sLen < operation.minStack {
+				return nil, fmt.Errorf("stack underflow (%d <=> %d)", sLen, operation.minStack)
+			} else if sLen > operation.maxStack {
+				return nil, fmt.Errorf("stack limit reached %d (%d)", sLen, operation.maxStack)
+			}
 		}
 		// If the operation is valid, enforce and write restrictions
 		if in.readOnly && in.evm.chainRules.IsByzantium {
@@ -226,7 +251,7 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		}
 		// Static portion of gas
 		cost = operation.constantGas // For tracing
-		if !contract.UseGas(operation.constantGas) {
+		if blocks == nil && !contract.UseGas(operation.constantGas) {
 			return nil, ErrOutOfGas
 		}
 
@@ -288,6 +313,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 			return res, nil
 		case !operation.jumps:
 			pc++
+		default:
+			// Jumps always end a block, the destination starts a new one
+			blockEnd = 0
 		}
 	}
 	return nil, nil
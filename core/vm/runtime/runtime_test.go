@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"bytes"
 	"math/big"
 	"strings"
 	"testing"
@@ -203,3 +204,75 @@ func BenchmarkEVM_CREATE2_1200(bench *testing.B) {
 	// initcode size 1200K, repeatedly calls CREATE2 and then modifies the mem contents
 	benchmarkEVM_Create(bench, "5b5862124f80600080f5600152600056")
 }
+
+// blockTestLoop counts down from 0x100, storing the remaining gas in every
+// iteration, and returns a zero word when done.
+var blockTestLoop = []byte{
+	byte(vm.PUSH2), 0x01, 0x00,
+	byte(vm.JUMPDEST),
+	byte(vm.PUSH1), 0x01,
+	byte(vm.SWAP1),
+	byte(vm.SUB),
+	byte(vm.GAS),
+	byte(vm.PUSH1), 0x00,
+	byte(vm.SSTORE),
+	byte(vm.DUP1),
+	byte(vm.PUSH1), 0x03,
+	byte(vm.JUMPI),
+	byte(vm.PUSH1), 0x20,
+	byte(vm.PUSH1), 0x00,
+	byte(vm.RETURN),
+}
+
+// Tests that executing code with the per basic block static checks yields the
+// same results as the instruction by instruction checks used when tracing.
+func TestBlockAnalysisMatchesTracing(t *testing.T) {
+	tests := []struct {
+		code []byte
+		gas  uint64
+	}{
+		{blockTestLoop, 10000000},
+		{blockTestLoop, 50000},                               // out of gas within the loop
+		{[]byte{byte(vm.PUSH1), 0x01, byte(vm.ADD)}, 100000}, // stack underflow mid-block
+		{[]byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x02, byte(vm.PUSH32), 0x01}, 100000},      // truncated push
+		{[]byte{byte(vm.PUSH1), 0x05, byte(vm.JUMP), byte(vm.PUSH1), byte(vm.JUMPDEST)}, 100000}, // jump into push data
+	}
+	for i, tt := range tests {
+		run := func(debug bool) ([]byte, uint64, error) {
+			statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+			address := common.BytesToAddress([]byte("contract"))
+			statedb.SetCode(address, tt.code)
+
+			cfg := &Config{State: statedb, GasLimit: tt.gas}
+			if debug {
+				cfg.EVMConfig = vm.Config{Debug: true, Tracer: vm.NewStructLogger(nil)}
+			}
+			return Call(address, nil, cfg)
+		}
+		wantRet, wantGas, wantErr := run(true)
+		haveRet, haveGas, haveErr := run(false)
+
+		if !bytes.Equal(haveRet, wantRet) {
+			t.Errorf("test %d: return data mismatch: have %x, want %x", i, haveRet, wantRet)
+		}
+		if haveGas != wantGas {
+			t.Errorf("test %d: leftover gas mismatch: have %d, want %d", i, haveGas, wantGas)
+		}
+		if (haveErr == nil) != (wantErr == nil) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, haveErr, wantErr)
+		}
+	}
+}
+
+func BenchmarkSimpleLoop(b *testing.B) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	address := common.BytesToAddress([]byte("contract"))
+	statedb.SetCode(address, blockTestLoop)
+
+	cfg := &Config{State: statedb, GasLimit: 10000000}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Call(address, nil, cfg)
+	}
+}
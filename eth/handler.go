@@ -152,7 +152,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 	if atomic.LoadUint32(&manager.fastSync) == 1 {
 		stateBloom = trie.NewSyncBloom(uint64(cacheLimit), chaindb)
 	}
-	manager.downloader = downloader.New(manager.checkpointNumber, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(manager.checkpointNumber, chaindb, stateBloom, manager.eventMux, blockchain, nil, manager.penalisePeer(p2p.ScoreDropped))
 
 	// Construct the fetcher (short sync)
 	validator := func(header *types.Header) error {
@@ -187,7 +187,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		}
 		return n, err
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.penalisePeer(p2p.ScoreInvalidBlock))
 
 	return manager, nil
 }
@@ -244,6 +244,31 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// penalisePeer returns a peer drop callback for the synchronisers that also
+// reports the misbehaviour to the peer scoring of the p2p layer.
+func (pm *ProtocolManager) penalisePeer(event p2p.ScoreEvent) func(id string) {
+	return func(id string) {
+		if peer := pm.peers.Peer(id); peer != nil {
+			peer.ReportScore(event)
+		}
+		pm.removePeer(id)
+	}
+}
+
+// reportResponse rates the reply of a peer to one of our data requests: replies
+// delivering data are useful, empty, undeliverable or unrequested ones useless.
+func (pm *ProtocolManager) reportResponse(p *peer, request uint64, items int, err error) {
+	latency, ok := p.requestLatency(request)
+	if ok {
+		p.ReportLatency(latency)
+	}
+	if !ok || items == 0 || err != nil {
+		p.ReportScore(p2p.ScoreUselessResponse)
+	} else {
+		p.ReportScore(p2p.ScoreUsefulResponse)
+	}
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -491,7 +516,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 		}
 		// Filter out any explicitly requested headers, deliver the rest to the downloader
-		filter := len(headers) == 1
+		var (
+			filter = len(headers) == 1
+			count  = len(headers)
+			err    error
+		)
 		if filter {
 			// If it's a potential sync progress check, validate the content and advertised chain weight
 			if p.syncDrop != nil && headers[0].Number.Uint64() == pm.checkpointNumber {
@@ -517,11 +546,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			headers = pm.fetcher.FilterHeaders(p.id, headers, time.Now())
 		}
 		if len(headers) > 0 || !filter {
-			err := pm.downloader.DeliverHeaders(p.id, headers)
+			err = pm.downloader.DeliverHeaders(p.id, headers)
 			if err != nil {
 				log.Debug("Failed to deliver headers", "err", err)
 			}
 		}
+		pm.reportResponse(p, GetBlockHeadersMsg, count, err)
 
 	case msg.Code == GetBlockBodiesMsg:
 		// Decode the retrieval message
@@ -565,16 +595,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			uncles[i] = body.Uncles
 		}
 		// Filter out any explicitly requested bodies, deliver the rest to the downloader
-		filter := len(transactions) > 0 || len(uncles) > 0
+		var (
+			filter = len(transactions) > 0 || len(uncles) > 0
+			err    error
+		)
 		if filter {
 			transactions, uncles = pm.fetcher.FilterBodies(p.id, transactions, uncles, time.Now())
 		}
 		if len(transactions) > 0 || len(uncles) > 0 || !filter {
-			err := pm.downloader.DeliverBodies(p.id, transactions, uncles)
+			err = pm.downloader.DeliverBodies(p.id, transactions, uncles)
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
 			}
 		}
+		pm.reportResponse(p, GetBlockBodiesMsg, len(request), err)
 
 	case p.version >= eth63 && msg.Code == GetNodeDataMsg:
 		// Decode the retrieval message
@@ -610,9 +644,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
-		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
+		err := pm.downloader.DeliverNodeData(p.id, data)
+		if err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
+		pm.reportResponse(p, GetNodeDataMsg, len(data), err)
 
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
@@ -657,9 +693,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
-		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
+		err := pm.downloader.DeliverReceipts(p.id, receipts)
+		if err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
+		pm.reportResponse(p, GetReceiptsMsg, len(receipts), err)
 
 	case msg.Code == NewBlockHashesMsg:
 		var announces newBlockHashesData
@@ -35,6 +35,7 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/eth/downloader"
 	"github.com/Fantom-foundation/go-ethereum/event"
 	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
 	"github.com/Fantom-foundation/go-ethereum/params"
 )
 
@@ -634,3 +635,31 @@ outer:
 		t.Errorf("block broadcast to %d peers, expected %d", receivedCount, broadcastExpected)
 	}
 }
+
+// Tests that responses are matched to the outstanding requests in order, and
+// that unrequested responses aren't credited to the peer.
+func TestReportResponse(t *testing.T) {
+	pm := new(ProtocolManager)
+	p := newPeer(63, p2p.NewPeer(enode.ID{1}, "peer", nil), nil)
+
+	// Concurrent requests are tracked separately
+	p.markRequest(GetBlockHeadersMsg)
+	p.markRequest(GetBlockHeadersMsg)
+	pm.reportResponse(p, GetBlockHeadersMsg, 1, nil)
+	pm.reportResponse(p, GetBlockHeadersMsg, 1, nil)
+	if info := p.Peer.Info().Score; info.Useful != 2 || info.Useless != 0 {
+		t.Fatalf("requested responses misreported: %+v", info)
+	}
+	// Pushed responses are useless
+	pm.reportResponse(p, GetBlockHeadersMsg, 1, nil)
+	if info := p.Peer.Info().Score; info.Useful != 2 || info.Useless != 1 {
+		t.Fatalf("unrequested response misreported: %+v", info)
+	}
+	// Only a limited number of requests are tracked
+	for i := 0; i < 2*maxTrackedRequests; i++ {
+		p.markRequest(GetBlockBodiesMsg)
+	}
+	if have := len(p.requests[GetBlockBodiesMsg]); have != maxTrackedRequests {
+		t.Fatalf("tracked request count mismatch: have %d, want %d", have, maxTrackedRequests)
+	}
+}
@@ -56,6 +56,10 @@ const (
 	// above some healthy uncle limit, so use that.
 	maxQueuedAnns = 4
 
+	// maxTrackedRequests is the maximum number of outstanding data requests of a
+	// kind to track the send times of. Older ones are forgotten.
+	maxTrackedRequests = 16
+
 	handshakeTimeout = 5 * time.Second
 )
 
@@ -82,9 +86,10 @@ type peer struct {
 	version  int         // Protocol version negotiated
 	syncDrop *time.Timer // Timed connection dropper if sync progress isn't validated in time
 
-	head common.Hash
-	td   *big.Int
-	lock sync.RWMutex
+	head     common.Hash
+	td       *big.Int
+	requests map[uint64][]time.Time // Send times of outstanding data requests, keyed by message code
+	lock     sync.RWMutex
 
 	knownTxs    mapset.Set                // Set of transaction hashes known to be known by this peer
 	knownBlocks mapset.Set                // Set of block hashes known to be known by this peer
@@ -100,6 +105,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		rw:          rw,
 		version:     version,
 		id:          fmt.Sprintf("%x", p.ID().Bytes()[:8]),
+		requests:    make(map[uint64][]time.Time),
 		knownTxs:    mapset.NewSet(),
 		knownBlocks: mapset.NewSet(),
 		queuedTxs:   make(chan []*types.Transaction, maxQueuedTxs),
@@ -311,10 +317,47 @@ func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
 	return p2p.Send(p.rw, ReceiptsMsg, receipts)
 }
 
+// markRequest records the send time of a data request, to measure the latency of
+// the response.
+//
+// The protocol has no request ids, so responses are matched to the oldest
+// outstanding request of the same kind. Since peers answer in order this is
+// exact, unless requests go unanswered, which skews the latency until they
+// are forgotten.
+func (p *peer) markRequest(code uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sent := append(p.requests[code], time.Now())
+	if len(sent) > maxTrackedRequests {
+		sent = sent[len(sent)-maxTrackedRequests:]
+	}
+	p.requests[code] = sent
+}
+
+// requestLatency returns the time elapsed since the oldest outstanding data
+// request with the given code was sent, if there is one.
+func (p *peer) requestLatency(code uint64) (time.Duration, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sent := p.requests[code]
+	if len(sent) == 0 {
+		return 0, false
+	}
+	if len(sent) == 1 {
+		delete(p.requests, code)
+	} else {
+		p.requests[code] = sent[1:]
+	}
+	return time.Since(sent[0]), true
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *peer) RequestOneHeader(hash common.Hash) error {
 	p.Log().Debug("Fetching single header", "hash", hash)
+	p.markRequest(GetBlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: hash}, Amount: uint64(1), Skip: uint64(0), Reverse: false})
 }
 
@@ -322,6 +365,7 @@ func (p *peer) RequestOneHeader(hash common.Hash) error {
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool) error {
 	p.Log().Debug("Fetching batch of headers", "count", amount, "fromhash", origin, "skip", skip, "reverse", reverse)
+	p.markRequest(GetBlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse})
 }
 
@@ -329,6 +373,7 @@ func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, re
 // specified header query, based on the number of an origin block.
 func (p *peer) RequestHeadersByNumber(origin uint64, amount int, skip int, reverse bool) error {
 	p.Log().Debug("Fetching batch of headers", "count", amount, "fromnum", origin, "skip", skip, "reverse", reverse)
+	p.markRequest(GetBlockHeadersMsg)
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Number: origin}, Amount: uint64(amount), Skip: uint64(skip), Reverse: reverse})
 }
 
@@ -336,6 +381,7 @@ func (p *peer) RequestHeadersByNumber(origin uint64, amount int, skip int, rever
 // specified.
 func (p *peer) RequestBodies(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of block bodies", "count", len(hashes))
+	p.markRequest(GetBlockBodiesMsg)
 	return p2p.Send(p.rw, GetBlockBodiesMsg, hashes)
 }
 
@@ -343,12 +389,14 @@ func (p *peer) RequestBodies(hashes []common.Hash) error {
 // data, corresponding to the specified hashes.
 func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of state data", "count", len(hashes))
+	p.markRequest(GetNodeDataMsg)
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
+	p.markRequest(GetReceiptsMsg)
 	return p2p.Send(p.rw, GetReceiptsMsg, hashes)
 }
 
@@ -0,0 +1,327 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package follower implements a reorg-safe chain follower on top of a remote node.
+//
+// A Follower walks the canonical chain block by block, starting from a checkpoint,
+// and reports every block it adds together with the matching logs. If the chain
+// reorganises, the blocks which are no longer canonical are reported as removed,
+// newest first, before the blocks of the new chain are added. The position of the
+// follower is persisted after each event, so a restarted follower resumes where
+// it stopped and reconciles any reorg which happened in the meantime.
+package follower
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/log"
+)
+
+const (
+	defaultHistory      = 128              // Default number of processed blocks retained for reorg detection
+	defaultPollInterval = 15 * time.Second // Default interval of chain polls without head notifications
+)
+
+// ErrReorgTooDeep is returned if the chain reorganised beyond all blocks retained
+// by the follower, so the common ancestor with the new chain cannot be determined.
+var ErrReorgTooDeep = errors.New("reorg deeper than retained history")
+
+// Backend is the chain access needed by a Follower. It is implemented by both
+// ethclient.Client and the simulated backend.
+type Backend interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// EventType distinguishes blocks joining the canonical chain from those leaving it.
+type EventType int
+
+const (
+	BlockAdded   EventType = iota // Block became part of the followed chain
+	BlockRemoved                  // Block was reorged out of the followed chain
+)
+
+// String implements fmt.Stringer.
+func (t EventType) String() string {
+	switch t {
+	case BlockAdded:
+		return "added"
+	case BlockRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of the followed chain.
+type Event struct {
+	Type   EventType
+	Header *types.Header
+	Logs   []types.Log // Logs of the block matching the filter, flagged as removed for BlockRemoved
+}
+
+// Config contains the settings of a Follower.
+type Config struct {
+	// Start is the number of the first block to report if no cursor is stored.
+	// If nil, following starts at the current head.
+	Start *big.Int
+
+	// Confirmations is the number of blocks which must be built on top of a block
+	// before it is reported.
+	Confirmations uint64
+
+	// Filter selects the logs reported with each block. Its block range fields
+	// are ignored. If nil, no logs are retrieved.
+	Filter *ethereum.FilterQuery
+
+	// History is the number of processed blocks retained for reorg detection. It
+	// bounds the depth of reorgs the follower can recover from.
+	History int
+
+	// PollInterval is the interval in which the chain is polled for new blocks
+	// if no head notifications arrive, e.g. because the connection was lost.
+	PollInterval time.Duration
+
+	// Store persists the position of the follower. If nil, it is kept in memory.
+	Store Store
+}
+
+// Follower follows the canonical chain of a backend.
+type Follower struct {
+	backend Backend
+	config  Config
+	blocks  []*Block // Processed blocks, oldest first
+}
+
+// New creates a follower for the chain of the given backend, loading its position
+// from the configured store.
+func New(backend Backend, config Config) (*Follower, error) {
+	if config.History <= 0 {
+		config.History = defaultHistory
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Store == nil {
+		config.Store = new(MemoryStore)
+	}
+	blocks, err := config.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Follower{backend: backend, config: config, blocks: blocks}, nil
+}
+
+// Cursor returns the header of the last reported block, or nil if no block has
+// been reported yet.
+func (f *Follower) Cursor() *types.Header {
+	if len(f.blocks) == 0 {
+		return nil
+	}
+	return f.blocks[len(f.blocks)-1].Header
+}
+
+// Run follows the chain until the context is canceled or the handler fails,
+// invoking the handler for every event. The position is persisted after each
+// handled event, so events are delivered at least once across restarts.
+//
+// Errors of the backend are logged and retried, which makes the follower catch up
+// with all missed blocks once the connection recovers.
+func (f *Follower) Run(ctx context.Context, handler func(Event) error) error {
+	var (
+		heads = make(chan *types.Header, 1)
+		sub   ethereum.Subscription
+		subch <-chan error
+	)
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	poll := time.NewTicker(f.config.PollInterval)
+	defer poll.Stop()
+
+	for {
+		// (Re)subscribe to head notifications, falling back to polling on failure.
+		if sub == nil {
+			var err error
+			if sub, err = f.backend.SubscribeNewHead(ctx, heads); err != nil {
+				log.Debug("Chain head subscription failed", "err", err)
+				sub = nil
+			} else {
+				subch = sub.Err()
+			}
+		}
+		err := f.sync(ctx, handler)
+		if herr, ok := err.(*handlerError); ok {
+			return herr.err
+		}
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err == ErrReorgTooDeep:
+			return err
+		case err != nil:
+			log.Warn("Chain follower sync failed", "err", err)
+		}
+		select {
+		case <-heads:
+		case <-poll.C:
+		case err := <-subch:
+			log.Debug("Chain head subscription dropped", "err", err)
+			sub.Unsubscribe()
+			sub, subch = nil, nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handlerError wraps errors of the event handler to tell them apart from errors
+// of the backend.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+// Sync brings the follower up to date with the current chain once, reporting all
+// events to the handler. Errors of the handler abort the sync and are returned.
+func (f *Follower) Sync(ctx context.Context, handler func(Event) error) error {
+	err := f.sync(ctx, handler)
+	if herr, ok := err.(*handlerError); ok {
+		return herr.err
+	}
+	return err
+}
+
+// sync implements Sync, wrapping errors of the handler into handlerError.
+func (f *Follower) sync(ctx context.Context, handler func(Event) error) error {
+	head, err := f.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if head.Number.Uint64() < f.config.Confirmations {
+		return nil
+	}
+	target := head.Number.Uint64() - f.config.Confirmations
+
+	for {
+		// Unwind all processed blocks which are no longer canonical.
+		if err := f.unwind(ctx, handler); err != nil {
+			return err
+		}
+		// Extend the chain up to the target, restarting if a reorg happens midway.
+		reorged, err := f.extend(ctx, target, handler)
+		if err != nil || !reorged {
+			return err
+		}
+	}
+}
+
+// next returns the number of the next block to process.
+func (f *Follower) next(ctx context.Context) (uint64, error) {
+	if cursor := f.Cursor(); cursor != nil {
+		return cursor.Number.Uint64() + 1, nil
+	}
+	if f.config.Start != nil {
+		return f.config.Start.Uint64(), nil
+	}
+	head, err := f.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if head.Number.Uint64() < f.config.Confirmations {
+		return 0, nil
+	}
+	return head.Number.Uint64() - f.config.Confirmations, nil
+}
+
+// unwind reports the processed blocks which are no longer part of the canonical
+// chain as removed, newest first.
+func (f *Follower) unwind(ctx context.Context, handler func(Event) error) error {
+	for len(f.blocks) > 0 {
+		tip := f.blocks[len(f.blocks)-1]
+		canonical, err := f.backend.HeaderByNumber(ctx, tip.Header.Number)
+		switch {
+		case err == nil && canonical.Hash() == tip.Header.Hash():
+			return nil
+		case err != nil && err != ethereum.NotFound:
+			return err
+		case len(f.blocks) == 1:
+			// The reorg goes beyond all retained blocks, the common ancestor is unknown.
+			return ErrReorgTooDeep
+		}
+		logs := make([]types.Log, len(tip.Logs))
+		for i, l := range tip.Logs {
+			l.Removed = true
+			logs[i] = l
+		}
+		if err := handler(Event{Type: BlockRemoved, Header: tip.Header, Logs: logs}); err != nil {
+			return &handlerError{err}
+		}
+		f.blocks = f.blocks[:len(f.blocks)-1]
+		if err := f.config.Store.Save(f.blocks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extend reports canonical blocks up to the target as added. It returns true if
+// a block did not build on the last processed one, i.e. the chain reorganised.
+func (f *Follower) extend(ctx context.Context, target uint64, handler func(Event) error) (bool, error) {
+	number, err := f.next(ctx)
+	if err != nil {
+		return false, err
+	}
+	for ; number <= target; number++ {
+		header, err := f.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return false, err
+		}
+		if cursor := f.Cursor(); cursor != nil && header.ParentHash != cursor.Hash() {
+			return true, nil
+		}
+		var logs []types.Log
+		if f.config.Filter != nil {
+			hash := header.Hash()
+			query := ethereum.FilterQuery{BlockHash: &hash, Addresses: f.config.Filter.Addresses, Topics: f.config.Filter.Topics}
+			if logs, err = f.backend.FilterLogs(ctx, query); err != nil {
+				return false, err
+			}
+		}
+		if err := handler(Event{Type: BlockAdded, Header: header, Logs: logs}); err != nil {
+			return false, &handlerError{err}
+		}
+		f.blocks = append(f.blocks, &Block{Header: header, Logs: logs})
+		if len(f.blocks) > f.config.History {
+			f.blocks = f.blocks[len(f.blocks)-f.config.History:]
+		}
+		if err := f.config.Store.Save(f.blocks); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package follower
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/consensus/ethash"
+	"github.com/Fantom-foundation/go-ethereum/core"
+	"github.com/Fantom-foundation/go-ethereum/core/rawdb"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/ethclient"
+	"github.com/Fantom-foundation/go-ethereum/ethdb"
+	"github.com/Fantom-foundation/go-ethereum/params"
+)
+
+// Verify that both remote and simulated chains can be followed.
+var (
+	_ Backend = (*ethclient.Client)(nil)
+	_ Backend = (*backends.SimulatedBackend)(nil)
+)
+
+// testChain is a simulated chain which can be reorganised.
+type testChain struct {
+	*backends.SimulatedBackend
+	db ethdb.Database
+}
+
+func newTestChain(blocks int) *testChain {
+	db := rawdb.NewMemoryDatabase()
+	chain := &testChain{backends.NewSimulatedBackendWithDatabase(db, core.GenesisAlloc{}, 10000000), db}
+	for i := 0; i < blocks; i++ {
+		chain.Commit()
+	}
+	return chain
+}
+
+// fork replaces the chain above the given block with n new blocks.
+func (c *testChain) fork(t *testing.T, number uint64, n int) {
+	parent := c.Blockchain().GetBlockByNumber(number)
+	blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, parent, ethash.NewFaker(), c.db, n, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := c.Blockchain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+}
+
+// eventString describes an event by type and block for comparisons.
+func eventString(ev Event) string {
+	return fmt.Sprintf("%v %d %x", ev.Type, ev.Header.Number, ev.Header.Hash().Bytes()[:4])
+}
+
+// collect syncs the follower, returning the descriptions of all events.
+func collect(t *testing.T, f *Follower) []string {
+	var events []string
+	if err := f.Sync(context.Background(), func(ev Event) error {
+		events = append(events, eventString(ev))
+		return nil
+	}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	return events
+}
+
+// expected returns the event descriptions of the given canonical blocks.
+func expected(c *testChain, typ EventType, headers ...*types.Header) []string {
+	var events []string
+	for _, h := range headers {
+		events = append(events, eventString(Event{Type: typ, Header: h}))
+	}
+	return events
+}
+
+func (c *testChain) headers(from, to uint64) []*types.Header {
+	var headers []*types.Header
+	for n := from; n <= to; n++ {
+		headers = append(headers, c.Blockchain().GetHeaderByNumber(n))
+	}
+	return headers
+}
+
+func TestFollowReorg(t *testing.T) {
+	chain := newTestChain(5)
+	defer chain.Close()
+
+	f, err := New(chain, Config{Start: big.NewInt(1), Filter: new(ethereum.FilterQuery)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collect(t, f), expected(chain, BlockAdded, chain.headers(1, 5)...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong initial events:\ngot  %v\nwant %v", got, want)
+	}
+	old := chain.headers(4, 5)
+
+	// Replace blocks 4 and 5 by a longer fork, which must unwind them newest first.
+	chain.fork(t, 3, 4)
+	want := append(expected(chain, BlockRemoved, old[1], old[0]), expected(chain, BlockAdded, chain.headers(4, 7)...)...)
+	if got := collect(t, f); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong reorg events:\ngot  %v\nwant %v", got, want)
+	}
+	if got := collect(t, f); len(got) != 0 {
+		t.Fatalf("unexpected events without chain changes: %v", got)
+	}
+}
+
+func TestFollowConfirmations(t *testing.T) {
+	chain := newTestChain(6)
+	defer chain.Close()
+
+	f, err := New(chain, Config{Start: big.NewInt(1), Confirmations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := collect(t, f), expected(chain, BlockAdded, chain.headers(1, 4)...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong events:\ngot  %v\nwant %v", got, want)
+	}
+	// Reorgs shallower than the confirmation depth are invisible.
+	chain.fork(t, 5, 2)
+	if got, want := collect(t, f), expected(chain, BlockAdded, chain.headers(5, 5)...); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong events after fork:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestFollowReorgTooDeep(t *testing.T) {
+	chain := newTestChain(6)
+	defer chain.Close()
+
+	f, err := New(chain, Config{Start: big.NewInt(1), History: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect(t, f)
+	chain.fork(t, 2, 6)
+	err = f.Sync(context.Background(), func(Event) error { return nil })
+	if err != ErrReorgTooDeep {
+		t.Fatalf("wrong error: got %v, want %v", err, ErrReorgTooDeep)
+	}
+}
+
+func TestFollowResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follower-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := newTestChain(3)
+	defer chain.Close()
+
+	store := NewFileStore(filepath.Join(dir, "cursor.json"))
+	f, err := New(chain, Config{Start: big.NewInt(1), Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collect(t, f)
+
+	// Reorg while the follower is down, then resume from the stored position.
+	old := chain.headers(3, 3)
+	chain.fork(t, 2, 2)
+
+	f, err = New(chain, Config{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Cursor() == nil || f.Cursor().Hash() != old[0].Hash() {
+		t.Fatalf("cursor not restored: %v", f.Cursor())
+	}
+	want := append(expected(chain, BlockRemoved, old...), expected(chain, BlockAdded, chain.headers(3, 4)...)...)
+	if got := collect(t, f); !reflect.DeepEqual(got, want) {
+		t.Fatalf("wrong events after resume:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestFollowRun(t *testing.T) {
+	chain := newTestChain(1)
+	defer chain.Close()
+
+	f, err := New(chain, Config{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- f.Run(ctx, func(ev Event) error {
+			events <- ev
+			return nil
+		})
+	}()
+	// The follower starts at the current head, further blocks arrive through the
+	// head subscription.
+	for number := uint64(1); number <= 3; number++ {
+		select {
+		case ev := <-events:
+			if ev.Type != BlockAdded || ev.Header.Number.Uint64() != number {
+				t.Fatalf("wrong event: %s", eventString(ev))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for block %d", number)
+		}
+		chain.Commit()
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("wrong error after cancel: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package follower
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+)
+
+// Block is a processed block retained for reorg detection.
+type Block struct {
+	Header *types.Header `json:"header"`
+	Logs   []types.Log   `json:"logs"`
+}
+
+// Store persists the processed blocks retained by a follower, which make up its
+// position in the chain.
+type Store interface {
+	// Load returns the stored blocks, oldest first, or nil if none are stored.
+	Load() ([]*Block, error)
+
+	// Save replaces the stored blocks.
+	Save(blocks []*Block) error
+}
+
+// MemoryStore is a Store keeping the blocks in memory.
+type MemoryStore struct {
+	blocks []*Block
+}
+
+// Load implements Store, returning the last saved blocks.
+func (s *MemoryStore) Load() ([]*Block, error) {
+	return s.blocks, nil
+}
+
+// Save implements Store, retaining a copy of the block list.
+func (s *MemoryStore) Save(blocks []*Block) error {
+	s.blocks = append([]*Block(nil), blocks...)
+	return nil
+}
+
+// FileStore is a Store keeping the blocks in a JSON file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a store persisting blocks into the file at the given path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load implements Store, reading the blocks from the file. A missing file is
+// treated as an empty store.
+func (s *FileStore) Load() ([]*Block, error) {
+	blob, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var blocks []*Block
+	if err := json.Unmarshal(blob, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// Save implements Store, atomically replacing the file contents.
+func (s *FileStore) Save(blocks []*Block) error {
+	blob, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
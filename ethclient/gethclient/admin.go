@@ -0,0 +1,84 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+
+	"github.com/Fantom-foundation/go-ethereum"
+	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+// AdminClient defines typed wrappers for the admin RPC API.
+type AdminClient struct {
+	c *rpc.Client
+}
+
+// NewAdminClient creates an admin API client that uses the given RPC client.
+func NewAdminClient(c *rpc.Client) *AdminClient {
+	return &AdminClient{c}
+}
+
+// NodeInfo returns information about the node itself.
+func (ac *AdminClient) NodeInfo(ctx context.Context) (*p2p.NodeInfo, error) {
+	var result *p2p.NodeInfo
+	err := ac.c.CallContext(ctx, &result, "admin_nodeInfo")
+	return result, err
+}
+
+// Peers returns information about the connected remote nodes.
+func (ac *AdminClient) Peers(ctx context.Context) ([]*p2p.PeerInfo, error) {
+	var result []*p2p.PeerInfo
+	err := ac.c.CallContext(ctx, &result, "admin_peers")
+	return result, err
+}
+
+// Datadir returns the data directory of the node.
+func (ac *AdminClient) Datadir(ctx context.Context) (string, error) {
+	var result string
+	err := ac.c.CallContext(ctx, &result, "admin_datadir")
+	return result, err
+}
+
+// AddPeer requests the node to connect to a remote node, maintaining the
+// connection at all times.
+func (ac *AdminClient) AddPeer(ctx context.Context, url string) error {
+	return ac.c.CallContext(ctx, nil, "admin_addPeer", url)
+}
+
+// RemovePeer requests the node to disconnect from a remote node.
+func (ac *AdminClient) RemovePeer(ctx context.Context, url string) error {
+	return ac.c.CallContext(ctx, nil, "admin_removePeer", url)
+}
+
+// AddTrustedPeer allows a remote node to always connect, even if the peer slots
+// of the node are full.
+func (ac *AdminClient) AddTrustedPeer(ctx context.Context, url string) error {
+	return ac.c.CallContext(ctx, nil, "admin_addTrustedPeer", url)
+}
+
+// RemoveTrustedPeer removes a remote node from the trusted peer set.
+func (ac *AdminClient) RemoveTrustedPeer(ctx context.Context, url string) error {
+	return ac.c.CallContext(ctx, nil, "admin_removeTrustedPeer", url)
+}
+
+// SubscribePeerEvents subscribes to the connection and message events of the
+// peers of the node.
+func (ac *AdminClient) SubscribePeerEvents(ctx context.Context, ch chan<- *p2p.PeerEvent) (ethereum.Subscription, error) {
+	return ac.c.Subscribe(ctx, "admin", ch, "peerEvents")
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/consensus/clique"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+// CliqueClient defines typed wrappers for the clique RPC API.
+type CliqueClient struct {
+	c *rpc.Client
+}
+
+// NewCliqueClient creates a clique API client that uses the given RPC client.
+func NewCliqueClient(c *rpc.Client) *CliqueClient {
+	return &CliqueClient{c}
+}
+
+// GetSnapshot returns the voting snapshot at a canonical block. If number is
+// nil, the snapshot at the latest block is returned.
+func (cc *CliqueClient) GetSnapshot(ctx context.Context, number *big.Int) (*clique.Snapshot, error) {
+	var result *clique.Snapshot
+	err := cc.c.CallContext(ctx, &result, "clique_getSnapshot", toBlockNumArg(number))
+	return result, err
+}
+
+// GetSnapshotAtHash returns the voting snapshot at a block.
+func (cc *CliqueClient) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*clique.Snapshot, error) {
+	var result *clique.Snapshot
+	err := cc.c.CallContext(ctx, &result, "clique_getSnapshotAtHash", hash)
+	return result, err
+}
+
+// GetSigners returns the authorized signers at a canonical block. If number is
+// nil, the signers at the latest block are returned.
+func (cc *CliqueClient) GetSigners(ctx context.Context, number *big.Int) ([]common.Address, error) {
+	var result []common.Address
+	err := cc.c.CallContext(ctx, &result, "clique_getSigners", toBlockNumArg(number))
+	return result, err
+}
+
+// GetSignersAtHash returns the authorized signers at a block.
+func (cc *CliqueClient) GetSignersAtHash(ctx context.Context, hash common.Hash) ([]common.Address, error) {
+	var result []common.Address
+	err := cc.c.CallContext(ctx, &result, "clique_getSignersAtHash", hash)
+	return result, err
+}
+
+// Proposals returns the proposals the node is voting on, mapping the proposed
+// signers to whether they are to be authorized or dropped.
+func (cc *CliqueClient) Proposals(ctx context.Context) (map[common.Address]bool, error) {
+	var result map[common.Address]bool
+	err := cc.c.CallContext(ctx, &result, "clique_proposals")
+	return result, err
+}
+
+// Propose makes the node vote on authorizing or dropping a signer when sealing
+// blocks.
+func (cc *CliqueClient) Propose(ctx context.Context, signer common.Address, auth bool) error {
+	return cc.c.CallContext(ctx, nil, "clique_propose", signer, auth)
+}
+
+// Discard drops a proposal of the node.
+func (cc *CliqueClient) Discard(ctx context.Context, signer common.Address) error {
+	return cc.c.CallContext(ctx, nil, "clique_discard", signer)
+}
@@ -0,0 +1,167 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/core/state"
+	"github.com/Fantom-foundation/go-ethereum/core/vm"
+	"github.com/Fantom-foundation/go-ethereum/eth"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+// DebugClient defines typed wrappers for the debug RPC API.
+type DebugClient struct {
+	c *rpc.Client
+}
+
+// NewDebugClient creates a debug API client that uses the given RPC client.
+func NewDebugClient(c *rpc.Client) *DebugClient {
+	return &DebugClient{c}
+}
+
+// ExecutionResult is the outcome of a transaction traced by the default struct
+// logger. It mirrors ethapi.ExecutionResult, whose log errors can't be decoded.
+type ExecutionResult struct {
+	Gas         uint64      `json:"gas"`
+	Failed      bool        `json:"failed"`
+	ReturnValue string      `json:"returnValue"`
+	StructLogs  []StructLog `json:"structLogs"`
+}
+
+// StructLog is a single step of the EVM recorded by the struct logger.
+type StructLog struct {
+	Pc      uint64             `json:"pc"`
+	Op      string             `json:"op"`
+	Gas     uint64             `json:"gas"`
+	GasCost uint64             `json:"gasCost"`
+	Depth   int                `json:"depth"`
+	Stack   *[]string          `json:"stack,omitempty"`
+	Memory  *[]string          `json:"memory,omitempty"`
+	Storage *map[string]string `json:"storage,omitempty"`
+}
+
+// TxTraceResult is the trace of a single transaction of a traced block.
+type TxTraceResult struct {
+	Result json.RawMessage `json:"result,omitempty"` // Trace produced by the tracer
+	Error  string          `json:"error,omitempty"`  // Failure of the tracer
+}
+
+// TraceTransaction replays a transaction with the struct logger, returning the
+// executed steps. The config may be nil to record everything.
+func (dc *DebugClient) TraceTransaction(ctx context.Context, txHash common.Hash, config *vm.LogConfig) (*ExecutionResult, error) {
+	var result *ExecutionResult
+	err := dc.c.CallContext(ctx, &result, "debug_traceTransaction", txHash, &eth.TraceConfig{LogConfig: config})
+	return result, err
+}
+
+// TraceTransactionWithTracer replays a transaction with the tracer set in the
+// config, decoding the output of the tracer into result.
+func (dc *DebugClient) TraceTransactionWithTracer(ctx context.Context, txHash common.Hash, config *eth.TraceConfig, result interface{}) error {
+	return dc.c.CallContext(ctx, result, "debug_traceTransaction", txHash, config)
+}
+
+// TraceBlockByNumber replays all transactions of a canonical block. If number is
+// nil, the latest block is traced.
+func (dc *DebugClient) TraceBlockByNumber(ctx context.Context, number *big.Int, config *eth.TraceConfig) ([]*TxTraceResult, error) {
+	var results []*TxTraceResult
+	err := dc.c.CallContext(ctx, &results, "debug_traceBlockByNumber", toBlockNumArg(number), config)
+	return results, err
+}
+
+// TraceBlockByHash replays all transactions of a block.
+func (dc *DebugClient) TraceBlockByHash(ctx context.Context, hash common.Hash, config *eth.TraceConfig) ([]*TxTraceResult, error) {
+	var results []*TxTraceResult
+	err := dc.c.CallContext(ctx, &results, "debug_traceBlockByHash", hash, config)
+	return results, err
+}
+
+// StorageRangeAt returns a range of the storage of a contract, as of after the
+// transaction at txIndex in the given block was executed.
+func (dc *DebugClient) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contract common.Address, keyStart []byte, maxResult int) (*eth.StorageRangeResult, error) {
+	var result *eth.StorageRangeResult
+	err := dc.c.CallContext(ctx, &result, "debug_storageRangeAt", blockHash, txIndex, contract, hexutil.Bytes(keyStart), maxResult)
+	return result, err
+}
+
+// AccountRange returns a range of the accounts in the latest state, starting at
+// the given account hash.
+func (dc *DebugClient) AccountRange(ctx context.Context, start common.Hash, maxResults int) (*eth.AccountRangeResult, error) {
+	var result *eth.AccountRangeResult
+	err := dc.c.CallContext(ctx, &result, "debug_accountRange", start, maxResults)
+	return result, err
+}
+
+// DumpBlock returns the full state at a canonical block. If number is nil, the
+// latest state is dumped.
+func (dc *DebugClient) DumpBlock(ctx context.Context, number *big.Int) (*state.Dump, error) {
+	var result *state.Dump
+	err := dc.c.CallContext(ctx, &result, "debug_dumpBlock", toBlockNumArg(number))
+	return result, err
+}
+
+// GetModifiedAccountsByNumber returns the accounts modified between two blocks,
+// or by the start block alone if end is nil.
+func (dc *DebugClient) GetModifiedAccountsByNumber(ctx context.Context, start uint64, end *uint64) ([]common.Address, error) {
+	var result []common.Address
+	err := dc.c.CallContext(ctx, &result, "debug_getModifiedAccountsByNumber", start, end)
+	return result, err
+}
+
+// GetModifiedAccountsByHash returns the accounts modified between two blocks, or
+// by the start block alone if end is nil.
+func (dc *DebugClient) GetModifiedAccountsByHash(ctx context.Context, start common.Hash, end *common.Hash) ([]common.Address, error) {
+	var result []common.Address
+	err := dc.c.CallContext(ctx, &result, "debug_getModifiedAccountsByHash", start, end)
+	return result, err
+}
+
+// Preimage returns the preimage of a hash stored by the node.
+func (dc *DebugClient) Preimage(ctx context.Context, hash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := dc.c.CallContext(ctx, &result, "debug_preimage", hash)
+	return result, err
+}
+
+// GetBadBlocks returns the last blocks the node rejected.
+func (dc *DebugClient) GetBadBlocks(ctx context.Context) ([]*eth.BadBlockArgs, error) {
+	var result []*eth.BadBlockArgs
+	err := dc.c.CallContext(ctx, &result, "debug_getBadBlocks")
+	return result, err
+}
+
+// GetBlockRlp returns the RLP encoding of a canonical block.
+func (dc *DebugClient) GetBlockRlp(ctx context.Context, number uint64) ([]byte, error) {
+	var result string
+	if err := dc.c.CallContext(ctx, &result, "debug_getBlockRlp", number); err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(result)
+}
+
+// ChaindbProperty returns a property of the node's chain database.
+func (dc *DebugClient) ChaindbProperty(ctx context.Context, property string) (string, error) {
+	var result string
+	err := dc.c.CallContext(ctx, &result, "debug_chaindbProperty", property)
+	return result, err
+}
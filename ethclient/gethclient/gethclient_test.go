@@ -0,0 +1,256 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/consensus/ethash"
+	"github.com/Fantom-foundation/go-ethereum/core"
+	"github.com/Fantom-foundation/go-ethereum/core/rawdb"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/eth"
+	"github.com/Fantom-foundation/go-ethereum/ethclient"
+	"github.com/Fantom-foundation/go-ethereum/node"
+	"github.com/Fantom-foundation/go-ethereum/params"
+	"github.com/Fantom-foundation/go-ethereum/rlp"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBalance = big.NewInt(params.Ether)
+
+	// testCode is the deployment code of a contract storing 0x42 in its first slot.
+	testCode = common.FromHex("604260005500")
+)
+
+func newTestBackend(t *testing.T, genesis *core.Genesis, blocks []*types.Block) *node.Node {
+	var ethservice *eth.Ethereum
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("can't create test node: %v", err)
+	}
+	n.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		config := &eth.Config{Genesis: genesis}
+		config.Ethash.PowMode = ethash.ModeFake
+		ethservice, err = eth.New(ctx, config)
+		return ethservice, err
+	})
+	if err := n.Start(); err != nil {
+		t.Fatalf("can't start test node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("can't import test blocks: %v", err)
+	}
+	return n
+}
+
+// generateTestChain creates a chain whose first block deploys the test contract.
+func generateTestChain() (*core.Genesis, []*types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	generate := func(i int, g *core.BlockGen) {
+		if i == 0 {
+			tx, _ := types.SignTx(types.NewContractCreation(0, new(big.Int), 100000, big.NewInt(1), testCode), types.HomesteadSigner{}, testKey)
+			g.AddTx(tx)
+		}
+	}
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.ToBlock(db), ethash.NewFaker(), db, 2, generate)
+	return genesis, blocks
+}
+
+func TestDebugClient(t *testing.T) {
+	genesis, blocks := generateTestChain()
+	backend := newTestBackend(t, genesis, blocks)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	var (
+		ctx    = context.Background()
+		debug  = NewDebugClient(client)
+		tx     = blocks[0].Transactions()[0]
+		number = big.NewInt(1)
+	)
+	result, err := debug.TraceTransaction(ctx, tx.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if result.Failed || len(result.StructLogs) != 4 || result.StructLogs[2].Op != "SSTORE" {
+		t.Fatalf("transaction trace mismatch: %+v", result)
+	}
+	traces, err := debug.TraceBlockByNumber(ctx, number, nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(traces) != 1 || traces[0].Error != "" || len(traces[0].Result) == 0 {
+		t.Fatalf("block trace mismatch: %+v", traces)
+	}
+	blob, err := debug.GetBlockRlp(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to retrieve block RLP: %v", err)
+	}
+	if want, _ := rlp.EncodeToBytes(blocks[0]); !bytes.Equal(blob, want) {
+		t.Fatalf("block RLP mismatch: have %x, want %x", blob, want)
+	}
+	dump, err := debug.DumpBlock(ctx, number)
+	if err != nil {
+		t.Fatalf("failed to dump block: %v", err)
+	}
+	contract := crypto.CreateAddress(testAddr, 0)
+	if account, ok := dump.Accounts[contract]; !ok || len(account.Storage) != 1 {
+		t.Fatalf("dumped contract mismatch: %+v", dump.Accounts[contract])
+	}
+	modified, err := debug.GetModifiedAccountsByNumber(ctx, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve modified accounts: %v", err)
+	}
+	found := false
+	for _, addr := range modified {
+		found = found || addr == contract
+	}
+	if !found {
+		t.Fatalf("deployed contract missing from modified accounts: %x", modified)
+	}
+}
+
+func TestTxPoolClient(t *testing.T) {
+	genesis, blocks := generateTestChain()
+	backend := newTestBackend(t, genesis, blocks)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	var (
+		ctx    = context.Background()
+		txpool = NewTxPoolClient(client)
+		to     = common.HexToAddress("0x01")
+	)
+	pending, _ := types.SignTx(types.NewTransaction(1, to, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	queued, _ := types.SignTx(types.NewTransaction(3, to, big.NewInt(1), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+	for _, tx := range []*types.Transaction{pending, queued} {
+		if err := ethclient.NewClient(client).SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send transaction: %v", err)
+		}
+	}
+	if p, q, err := txpool.Status(ctx); err != nil || p != 1 || q != 1 {
+		t.Fatalf("status mismatch: have %d/%d (%v), want 1/1", p, q, err)
+	}
+	content, err := txpool.Content(ctx)
+	if err != nil {
+		t.Fatalf("failed to retrieve content: %v", err)
+	}
+	if tx := content.Pending[testAddr][1]; tx == nil || tx.Hash != pending.Hash() {
+		t.Fatalf("pending transaction mismatch: %+v", tx)
+	}
+	if tx := content.Queued[testAddr][3]; tx == nil || tx.Hash != queued.Hash() {
+		t.Fatalf("queued transaction mismatch: %+v", tx)
+	}
+	inspection, err := txpool.Inspect(ctx)
+	if err != nil {
+		t.Fatalf("failed to inspect: %v", err)
+	}
+	if inspection.Pending[testAddr][1] == "" || inspection.Queued[testAddr][3] == "" {
+		t.Fatalf("inspection mismatch: %+v", inspection)
+	}
+}
+
+func TestAdminClient(t *testing.T) {
+	genesis, blocks := generateTestChain()
+	backend := newTestBackend(t, genesis, blocks)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	ctx := context.Background()
+	admin := NewAdminClient(client)
+
+	info, err := admin.NodeInfo(ctx)
+	if err != nil {
+		t.Fatalf("failed to retrieve node info: %v", err)
+	}
+	if info.Enode != backend.Server().NodeInfo().Enode {
+		t.Fatalf("node info mismatch: have %s, want %s", info.Enode, backend.Server().NodeInfo().Enode)
+	}
+	if _, ok := info.Protocols["eth"]; !ok {
+		t.Fatalf("eth protocol missing from node info: %v", info.Protocols)
+	}
+	peers, err := admin.Peers(ctx)
+	if err != nil || len(peers) != 0 {
+		t.Fatalf("peers mismatch: %v, %v", peers, err)
+	}
+	if err := admin.AddPeer(ctx, "invalid"); err == nil {
+		t.Fatalf("invalid peer added")
+	}
+}
+
+func TestCliqueClient(t *testing.T) {
+	var (
+		signer   = testAddr
+		proposed = common.HexToAddress("0x01")
+		config   = *params.AllCliqueProtocolChanges
+	)
+	config.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000}
+	genesis := &core.Genesis{
+		Config:    &config,
+		ExtraData: append(append(make([]byte, 32), signer[:]...), make([]byte, 65)...),
+		Alloc:     core.GenesisAlloc{testAddr: {Balance: testBalance}},
+	}
+	backend := newTestBackend(t, genesis, nil)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+
+	ctx := context.Background()
+	clique := NewCliqueClient(client)
+
+	signers, err := clique.GetSigners(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve signers: %v", err)
+	}
+	if len(signers) != 1 || signers[0] != signer {
+		t.Fatalf("signers mismatch: have %v, want [%x]", signers, signer)
+	}
+	snapshot, err := clique.GetSnapshot(ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	if _, ok := snapshot.Signers[signer]; !ok || snapshot.Number != 0 {
+		t.Fatalf("snapshot mismatch: %+v", snapshot)
+	}
+	if err := clique.Propose(ctx, proposed, true); err != nil {
+		t.Fatalf("failed to propose: %v", err)
+	}
+	if proposals, _ := clique.Proposals(ctx); len(proposals) != 1 || !proposals[proposed] {
+		t.Fatalf("proposals mismatch: %v", proposals)
+	}
+	if err := clique.Discard(ctx, proposed); err != nil {
+		t.Fatalf("failed to discard: %v", err)
+	}
+	if proposals, _ := clique.Proposals(ctx); len(proposals) != 0 {
+		t.Fatalf("proposal not discarded: %v", proposals)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/internal/ethapi"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+)
+
+// TxPoolClient defines typed wrappers for the txpool RPC API.
+type TxPoolClient struct {
+	c *rpc.Client
+}
+
+// NewTxPoolClient creates a txpool API client that uses the given RPC client.
+func NewTxPoolClient(c *rpc.Client) *TxPoolClient {
+	return &TxPoolClient{c}
+}
+
+// TxPoolContent is the content of the transaction pool, grouped by sender and
+// nonce.
+type TxPoolContent struct {
+	Pending map[common.Address]map[uint64]*ethapi.RPCTransaction `json:"pending"`
+	Queued  map[common.Address]map[uint64]*ethapi.RPCTransaction `json:"queued"`
+}
+
+// TxPoolInspection is a textual summary of the transaction pool content, grouped
+// by sender and nonce.
+type TxPoolInspection struct {
+	Pending map[common.Address]map[uint64]string `json:"pending"`
+	Queued  map[common.Address]map[uint64]string `json:"queued"`
+}
+
+// Content returns the transactions contained within the transaction pool.
+func (tc *TxPoolClient) Content(ctx context.Context) (*TxPoolContent, error) {
+	var result *TxPoolContent
+	err := tc.c.CallContext(ctx, &result, "txpool_content")
+	return result, err
+}
+
+// Inspect returns a summary of the transactions contained within the
+// transaction pool.
+func (tc *TxPoolClient) Inspect(ctx context.Context) (*TxPoolInspection, error) {
+	var result *TxPoolInspection
+	err := tc.c.CallContext(ctx, &result, "txpool_inspect")
+	return result, err
+}
+
+// Status returns the number of pending and queued transactions in the pool.
+func (tc *TxPoolClient) Status(ctx context.Context) (pending, queued uint, err error) {
+	var result map[string]hexutil.Uint
+	if err := tc.c.CallContext(ctx, &result, "txpool_status"); err != nil {
+		return 0, 0, err
+	}
+	return uint(result["pending"]), uint(result["queued"]), nil
+}
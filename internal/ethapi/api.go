@@ -26,9 +26,12 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/Fantom-foundation/go-ethereum"
 	"github.com/Fantom-foundation/go-ethereum/accounts"
 	"github.com/Fantom-foundation/go-ethereum/accounts/keystore"
 	"github.com/Fantom-foundation/go-ethereum/accounts/scwallet"
+	"github.com/Fantom-foundation/go-ethereum/accounts/sigverify"
+	"github.com/Fantom-foundation/go-ethereum/accounts/typeddata"
 	"github.com/Fantom-foundation/go-ethereum/common"
 	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
 	"github.com/Fantom-foundation/go-ethereum/common/math"
@@ -883,6 +886,58 @@ func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOr
 	return (hexutil.Bytes)(result), err
 }
 
+// VerifySignature reports whether sig is a valid signature of the hash by the
+// given address. Signatures of accounts with code are checked by calling their
+// EIP-1271 isValidSignature method, others by recovering the signer.
+func (s *PublicBlockChainAPI) VerifySignature(ctx context.Context, address common.Address, hash common.Hash, sig hexutil.Bytes, blockNrOrHash rpc.BlockNumberOrHash) (bool, error) {
+	caller := &stateCaller{b: s.b, blockNrOrHash: blockNrOrHash}
+	return sigverify.VerifyHash(ctx, caller, nil, address, hash, sig)
+}
+
+// stateCaller implements sigverify.ContractCaller on top of the state of a given
+// block, ignoring the block numbers requested by the callers.
+type stateCaller struct {
+	b             Backend
+	blockNrOrHash rpc.BlockNumberOrHash
+}
+
+// CodeAt implements sigverify.ContractCaller, returning the code of the account in
+// the state the caller was created for.
+func (c *stateCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	state, _, err := c.b.StateAndHeaderByNumberOrHash(ctx, c.blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	code := state.GetCode(contract)
+	return code, state.Error()
+}
+
+// CallContract implements sigverify.ContractCaller, executing the call on top of the
+// state the caller was created for.
+func (c *stateCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	args := CallArgs{
+		From:  &call.From,
+		To:    call.To,
+		Data:  (*hexutil.Bytes)(&call.Data),
+		Value: (*hexutil.Big)(call.Value),
+	}
+	if call.Gas != 0 {
+		args.Gas = (*hexutil.Uint64)(&call.Gas)
+	}
+	if call.GasPrice != nil {
+		args.GasPrice = (*hexutil.Big)(call.GasPrice)
+	}
+	result, _, failed, err := DoCall(ctx, c.b, args, c.blockNrOrHash, nil, vm.Config{}, 5*time.Second, c.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	// Reverted calls yield no output, so revert data cannot pass for a valid result
+	if failed {
+		return nil, nil
+	}
+	return result, nil
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap *big.Int) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1532,6 +1587,29 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an EIP-712 signature for the given typed data:
+// keccak256("\x19\x01" + hashStruct(domain) + hashStruct(message)).
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data typeddata.TypedData) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	// Sign the typed data with the wallet
+	signature, err := typeddata.SignWithWallet(wallet, account, &data)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -186,7 +186,11 @@ func (api *PrivateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 		}
 	}
 
-	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts); err != nil {
+	secret, err := api.node.config.JWTSecret(api.node.config.HTTPJWTSecret)
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startHTTP(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, allowedOrigins, allowedVHosts, api.node.config.HTTPTimeouts, secret, &api.node.config.HTTPPolicy); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -240,7 +244,11 @@ func (api *PrivateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		}
 	}
 
-	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll); err != nil {
+	secret, err := api.node.config.JWTSecret(api.node.config.WSJWTSecret)
+	if err != nil {
+		return false, err
+	}
+	if err := api.node.startWS(fmt.Sprintf("%s:%d", *host, *port), api.node.rpcAPIs, modules, origins, api.node.config.WSExposeAll, secret, &api.node.config.WSPolicy); err != nil {
 		return false, err
 	}
 	return true, nil
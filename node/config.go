@@ -18,6 +18,8 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -25,9 +27,11 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Fantom-foundation/go-ethereum/accounts"
 	"github.com/Fantom-foundation/go-ethereum/accounts/external"
+	"github.com/Fantom-foundation/go-ethereum/accounts/hsm"
 	"github.com/Fantom-foundation/go-ethereum/accounts/keystore"
 	"github.com/Fantom-foundation/go-ethereum/accounts/scwallet"
 	"github.com/Fantom-foundation/go-ethereum/accounts/usbwallet"
@@ -47,6 +51,10 @@ const (
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
 )
 
+// jwtSecretLength is the length of generated JWT secrets, the minimum
+// recommended key size for HS256.
+const jwtSecretLength = 32
+
 // Config represents a small collection of configuration values to fine tune the
 // P2P network layer of a protocol stack. These values can be further extended by
 // all registered services.
@@ -98,6 +106,23 @@ type Config struct {
 	// SmartCardDaemonPath is the path to the smartcard daemon's socket
 	SmartCardDaemonPath string `toml:",omitempty"`
 
+	// PKCS11Module is the path to a PKCS#11 module, whose token's secp256k1 keys
+	// are made available as accounts.
+	PKCS11Module string `toml:",omitempty"`
+
+	// PKCS11Token is the label of the PKCS#11 token to use (the first if empty).
+	PKCS11Token string `toml:",omitempty"`
+
+	// PKCS11PIN is the user PIN to log into the PKCS#11 token with.
+	PKCS11PIN string `toml:"-"`
+
+	// RemoteSigner is the URL of an HTTP signing service, whose keys are made
+	// available as accounts.
+	RemoteSigner string `toml:",omitempty"`
+
+	// RemoteSignerToken is the bearer token to authenticate to the remote signer.
+	RemoteSignerToken string `toml:"-"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -136,6 +161,16 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPJWTSecret is the path to a file containing the hex encoded shared secret
+	// used to authenticate HTTP RPC requests with HS256 JWT tokens. Relative paths
+	// are resolved within the instance directory. If the file does not exist, a new
+	// random secret is generated and stored. An empty path disables authentication.
+	HTTPJWTSecret string `toml:",omitempty"`
+
+	// HTTPPolicy restricts the methods, request rates and resources available to
+	// clients of the HTTP RPC interface.
+	HTTPPolicy rpc.Policy
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -162,6 +197,24 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSJWTSecret is the path to a file containing the hex encoded shared secret
+	// used to authenticate websocket RPC connections with HS256 JWT tokens. It is
+	// handled the same way as HTTPJWTSecret.
+	WSJWTSecret string `toml:",omitempty"`
+
+	// WSPolicy restricts the methods, request rates and resources available to
+	// clients of the websocket RPC interface.
+	WSPolicy rpc.Policy
+
+	// RPCSlowCallThreshold is the serving time above which RPC calls are logged as
+	// slow. Zero disables slow call logging.
+	RPCSlowCallThreshold time.Duration `toml:",omitempty"`
+
+	// RPCSlowCallParams enables logging the parameters of slow calls at debug
+	// level. Parameters of the personal, account and clef namespaces are never
+	// logged.
+	RPCSlowCallParams bool `toml:",omitempty"`
+
 	// GraphQLHost is the host interface on which to start the GraphQL server. If this
 	// field is empty, no GraphQL API endpoint will be started.
 	GraphQLHost string `toml:",omitempty"`
@@ -391,6 +444,43 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecret loads the shared secret used for RPC authentication from the given
+// file, generating and storing a new random one if the file does not exist yet.
+// A nil secret is returned if no path is set, disabling authentication.
+func (c *Config) JWTSecret(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if resolved := c.ResolvePath(path); resolved != "" {
+		path = resolved
+	}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+		}
+		if len(secret) < jwtSecretLength {
+			return nil, fmt.Errorf("JWT secret in %s too short: have %d bytes, want at least %d", path, len(secret), jwtSecretLength)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one
+	secret := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
@@ -522,11 +612,49 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 				backends = append(backends, schub)
 			}
 		}
+		// Keys in a hardware security module or remote signing service were
+		// explicitly requested, so fail if they are unavailable
+		hsmhub, err := conf.RemoteKeyBackend()
+		if err != nil {
+			return nil, "", err
+		}
+		if hsmhub != nil {
+			backends = append(backends, hsmhub)
+		}
 	}
 
 	return accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: conf.InsecureUnlockAllowed}, backends...), ephemeral, nil
 }
 
+// RemoteKeyBackend creates an accounts backend for the configured PKCS#11 token
+// and remote signer, or returns nil if neither is configured.
+func (c *Config) RemoteKeyBackend() (*hsm.Backend, error) {
+	var signers []hsm.Signer
+	if c.PKCS11Module != "" {
+		signer, err := hsm.NewPKCS11Signer(c.PKCS11Module, c.PKCS11Token, c.PKCS11PIN)
+		if err != nil {
+			return nil, fmt.Errorf("error opening PKCS#11 token: %v", err)
+		}
+		log.Info("Using PKCS#11 token", "module", c.PKCS11Module, "token", c.PKCS11Token)
+		signers = append(signers, signer)
+	}
+	if c.RemoteSigner != "" {
+		signer, err := hsm.NewRemoteSigner(c.RemoteSigner, c.RemoteSignerToken)
+		if err != nil {
+			for _, s := range signers {
+				s.Close()
+			}
+			return nil, fmt.Errorf("error connecting to remote signer: %v", err)
+		}
+		log.Info("Using remote signer", "url", c.RemoteSigner)
+		signers = append(signers, signer)
+	}
+	if len(signers) == 0 {
+		return nil, nil
+	}
+	return hsm.NewBackend(signers...), nil
+}
+
 var warnLock sync.Mutex
 
 func (c *Config) warnOnce(w *bool, format string, args ...interface{}) {
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"time"
 
 	"github.com/Fantom-foundation/go-ethereum/log"
@@ -79,6 +80,8 @@ type dialstate struct {
 	lookupBuf     []*enode.Node // current discovery lookup results
 	static        map[enode.ID]*dialTask
 	hist          expHeap
+
+	score func(enode.ID) float64 // reputation of nodes, used to rank dial candidates
 }
 
 type task interface {
@@ -119,7 +122,11 @@ func (s *dialstate) removeStatic(n *enode.Node) {
 func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Time) []task {
 	var newtasks []task
 	addDial := func(flag connFlag, n *enode.Node) bool {
-		if err := s.checkDial(n, peers); err != nil {
+		err := s.checkDial(n, peers)
+		if err == nil && flag&dynDialedConn != 0 && s.score != nil && s.score(n.ID()) < minDialScore {
+			err = errLowScore
+		}
+		if err != nil {
 			s.log.Trace("Skipping dial candidate", "id", n.ID(), "addr", &net.TCPAddr{IP: n.IP(), Port: n.TCP()}, "err", err)
 			return false
 		}
@@ -203,6 +210,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errLowScore         = errors.New("low reputation")
 )
 
 func (s *dialstate) checkDial(n *enode.Node, peers map[enode.ID]*Peer) error {
@@ -230,7 +238,23 @@ func (s *dialstate) taskDone(t task, now time.Time) {
 	case *discoverTask:
 		s.lookupRunning = false
 		s.lookupBuf = append(s.lookupBuf, t.results...)
+		s.rankLookupBuf()
+	}
+}
+
+// rankLookupBuf orders the discovery results by reputation, so that nodes known
+// to be good peers are dialed first.
+func (s *dialstate) rankLookupBuf() {
+	if s.score == nil {
+		return
+	}
+	scores := make(map[enode.ID]float64, len(s.lookupBuf))
+	for _, n := range s.lookupBuf {
+		scores[n.ID()] = s.score(n.ID())
 	}
+	sort.SliceStable(s.lookupBuf, func(i, j int) bool {
+		return scores[s.lookupBuf[i].ID()] > scores[s.lookupBuf[j].ID()]
+	})
 }
 
 // A dialTask is generated for each node that is dialed. Its
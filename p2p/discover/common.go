@@ -20,8 +20,10 @@ import (
 	"crypto/ecdsa"
 	"net"
 
+	"github.com/Fantom-foundation/go-ethereum/common/mclock"
 	"github.com/Fantom-foundation/go-ethereum/log"
 	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
 	"github.com/Fantom-foundation/go-ethereum/p2p/netutil"
 )
 
@@ -43,6 +45,23 @@ type Config struct {
 	Bootnodes   []*enode.Node     // list of bootstrap nodes
 	Unhandled   chan<- ReadPacket // unhandled packets are sent on this channel
 	Log         log.Logger        // if set, log messages go here
+
+	// These settings are only used by the v5 protocol:
+	ValidSchemes enr.IdentityScheme // allowed identity schemes in received records
+	Clock        mclock.Clock       // time source for request timeouts
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Log == nil {
+		cfg.Log = log.Root()
+	}
+	if cfg.ValidSchemes == nil {
+		cfg.ValidSchemes = enode.ValidSchemes
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = mclock.System{}
+	}
+	return cfg
 }
 
 // ListenUDP starts listening for discovery packets on the given UDP socket.
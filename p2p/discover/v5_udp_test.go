@@ -0,0 +1,316 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/log"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
+)
+
+// startLocalhostV5 creates a UDPv5 listener on a random localhost port.
+func startLocalhostV5(t *testing.T, cfg Config) *UDPv5 {
+	cfg.PrivateKey = newkey()
+	db, _ := enode.OpenDB("")
+	ln := enode.NewLocalNode(db, cfg.PrivateKey)
+
+	// Prefix logs with node ID.
+	lprefix := ln.ID().TerminalString()
+	lfmt := log.TerminalFormat(false)
+	cfg.Log = log.New()
+	cfg.Log.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		t.Logf("%s %s", lprefix, lfmt.Format(r))
+		return nil
+	}))
+
+	// Listen.
+	socket, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	realaddr := socket.LocalAddr().(*net.UDPAddr)
+	ln.SetStaticIP(realaddr.IP)
+	ln.SetFallbackUDP(realaddr.Port)
+	udp, err := ListenV5(socket, ln, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return udp
+}
+
+// recordAtDistance creates a record for which enode.LogDist(base, n.ID()) == ld. The
+// record uses the null identity scheme and is only accepted with ValidSchemesForTesting.
+func recordAtDistance(base enode.ID, ld int, port int) *enode.Node {
+	var r enr.Record
+	r.Set(enr.IP(net.IP{127, 0, 0, 1}))
+	r.Set(enr.UDP(port))
+	return enode.SignNull(&r, idAtDistance(base, ld))
+}
+
+// This test checks that two nodes can complete a handshake and exchange PING/PONG.
+func TestUDPv5_pingHandshake(t *testing.T) {
+	t.Parallel()
+	node1 := startLocalhostV5(t, Config{})
+	node2 := startLocalhostV5(t, Config{})
+	defer node1.Close()
+	defer node2.Close()
+
+	// The first ping performs the handshake, the second one reuses the session.
+	for i := 0; i < 2; i++ {
+		if err := node1.Ping(node2.Self()); err != nil {
+			t.Fatalf("ping %d failed: %v", i, err)
+		}
+	}
+	// The recipient of the handshake should have added the initiator to its table.
+	if n := node2.tab.getNode(node1.Self().ID()); n == nil {
+		t.Fatal("node1 not in node2's table after handshake")
+	}
+	// Pinging in the other direction uses the established session.
+	if err := node2.Ping(node1.Self()); err != nil {
+		t.Fatal("reverse ping failed:", err)
+	}
+}
+
+// This test checks that RequestENR returns the current record of the remote node.
+func TestUDPv5_requestENR(t *testing.T) {
+	t.Parallel()
+	node1 := startLocalhostV5(t, Config{})
+	node2 := startLocalhostV5(t, Config{})
+	defer node1.Close()
+	defer node2.Close()
+
+	old := node2.Self()
+	node2.localNode.Set(enr.WithEntry("foo", "bar"))
+	n, err := node1.RequestENR(old)
+	if err != nil {
+		t.Fatal("RequestENR failed:", err)
+	}
+	if n.Seq() != node2.Self().Seq() {
+		t.Fatalf("wrong seq %d in response, want %d", n.Seq(), node2.Self().Seq())
+	}
+	if resolved := node1.Resolve(old); resolved.Seq() != node2.Self().Seq() {
+		t.Fatalf("Resolve returned seq %d, want %d", resolved.Seq(), node2.Self().Seq())
+	}
+}
+
+// This test checks that FINDNODE returns table entries at the requested distances and
+// that responses are split across multiple NODES packets.
+func TestUDPv5_findnode(t *testing.T) {
+	t.Parallel()
+	node1 := startLocalhostV5(t, Config{ValidSchemes: enode.ValidSchemesForTesting})
+	node2 := startLocalhostV5(t, Config{})
+	defer node1.Close()
+	defer node2.Close()
+
+	// Fill node2's table with entries at distance 255 and 256.
+	var want255 []*enode.Node
+	for i := 0; i < 10; i++ {
+		n := recordAtDistance(node2.Self().ID(), 255, 30000+i)
+		want255 = append(want255, n)
+		fillTable(node2.tab, []*node{wrapNode(n)})
+	}
+	for i := 0; i < 4; i++ {
+		fillTable(node2.tab, []*node{wrapNode(recordAtDistance(node2.Self().ID(), 256, 31000+i))})
+	}
+
+	// The handshake adds node1 to node2's table, exclude it from the results.
+	findnode := func(distances []uint) []*enode.Node {
+		nodes, err := node1.FindNode(node2.Self(), distances)
+		if err != nil {
+			t.Fatal("findnode failed:", err)
+		}
+		var result []*enode.Node
+		for _, n := range nodes {
+			if n.ID() != node1.Self().ID() {
+				result = append(result, n)
+			}
+		}
+		return result
+	}
+
+	nodes := findnode([]uint{255})
+	sortByID(nodes)
+	sortByID(want255)
+	if err := checkNodesEqual(nodes, want255); err != nil {
+		t.Fatal(err)
+	}
+
+	// Asking for both distances returns all entries.
+	if nodes := findnode([]uint{255, 256}); len(nodes) != 14 {
+		t.Fatalf("wrong number of results: got %d, want %d", len(nodes), 14)
+	}
+
+	// Distance zero returns the node itself.
+	nodes = findnode([]uint{0})
+	if len(nodes) != 1 || nodes[0].ID() != node2.Self().ID() {
+		t.Fatalf("wrong result for distance zero: %v", nodes)
+	}
+}
+
+// This test checks that TALKREQ messages are delivered to the registered handler.
+func TestUDPv5_talkRequest(t *testing.T) {
+	t.Parallel()
+	node1 := startLocalhostV5(t, Config{})
+	node2 := startLocalhostV5(t, Config{})
+	defer node1.Close()
+	defer node2.Close()
+
+	var gotFrom enode.ID
+	node2.RegisterTalkHandler("test", func(id enode.ID, addr *net.UDPAddr, msg []byte) []byte {
+		gotFrom = id
+		return append([]byte("echo "), msg...)
+	})
+
+	resp, err := node1.TalkRequest(node2.Self(), "test", []byte("hello"))
+	if err != nil {
+		t.Fatal("talk request failed:", err)
+	}
+	if !bytes.Equal(resp, []byte("echo hello")) {
+		t.Fatalf("wrong response %q", resp)
+	}
+	if gotFrom != node1.Self().ID() {
+		t.Fatalf("handler called with wrong ID %v", gotFrom)
+	}
+
+	// Requests for unknown protocols get an empty response.
+	resp, err = node1.TalkRequest(node2.Self(), "unknown", []byte("hello"))
+	if err != nil {
+		t.Fatal("talk request failed:", err)
+	}
+	if len(resp) != 0 {
+		t.Fatalf("non-empty response %q for unknown protocol", resp)
+	}
+}
+
+// This test checks that calls to unresponsive nodes time out.
+func TestUDPv5_callTimeout(t *testing.T) {
+	t.Parallel()
+	node1 := startLocalhostV5(t, Config{})
+	defer node1.Close()
+
+	// Create a node which doesn't listen.
+	socket, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := socket.LocalAddr().(*net.UDPAddr)
+	socket.Close()
+	var r enr.Record
+	r.Set(enr.IP(addr.IP))
+	r.Set(enr.UDP(addr.Port))
+	enode.SignV4(&r, newkey())
+	dead, _ := enode.New(enode.ValidSchemes, &r)
+
+	start := time.Now()
+	if err := node1.Ping(dead); err != errTimeout {
+		t.Fatalf("wrong error %v, want %v", err, errTimeout)
+	}
+	if d := time.Since(start); d < respTimeoutV5 {
+		t.Fatalf("call returned after %v, before response timeout", d)
+	}
+}
+
+// This test checks that lookups and the random node iterator find nodes across
+// multiple hops.
+func TestUDPv5_lookup(t *testing.T) {
+	t.Parallel()
+	bootnode := startLocalhostV5(t, Config{})
+	defer bootnode.Close()
+
+	// Start some nodes which announce themselves to the bootnode.
+	var nodes []*UDPv5
+	for i := 0; i < 5; i++ {
+		n := startLocalhostV5(t, Config{})
+		defer n.Close()
+		if err := n.Ping(bootnode.Self()); err != nil {
+			t.Fatal("ping to bootnode failed:", err)
+		}
+		nodes = append(nodes, n)
+	}
+
+	// Start a node which only knows the bootnode and look up the others.
+	test := startLocalhostV5(t, Config{Bootnodes: []*enode.Node{bootnode.Self()}})
+	defer test.Close()
+	for _, n := range nodes {
+		results := test.Lookup(n.Self().ID())
+		if len(results) == 0 || results[0].ID() != n.Self().ID() {
+			t.Fatalf("lookup for %v didn't find the target, results: %v", n.Self().ID(), results)
+		}
+	}
+
+	// The random nodes iterator should yield all of them.
+	want := make(map[enode.ID]bool)
+	for _, n := range nodes {
+		want[n.Self().ID()] = true
+	}
+	want[bootnode.Self().ID()] = true
+	it := test.RandomNodes()
+	defer it.Close()
+	found := make(map[enode.ID]bool)
+	for len(found) < len(want) && it.Next() {
+		if want[it.Node().ID()] {
+			found[it.Node().ID()] = true
+		}
+	}
+	if !reflect.DeepEqual(found, want) {
+		t.Fatalf("iterator found %d of %d nodes", len(found), len(want))
+	}
+}
+
+func TestLookupDistances(t *testing.T) {
+	var (
+		base   = enode.ID{}
+		tests  = []int{256, 255, 200, 1}
+		expect = [][]uint{{256, 255, 254}, {255, 256, 254}, {200, 201, 199}, {1, 2, 3}}
+	)
+	for i, ld := range tests {
+		target := idAtDistance(base, ld)
+		if dists := lookupDistances(target, base); !reflect.DeepEqual(dists, expect[i]) {
+			t.Errorf("wrong distances for logdist %d: got %v, want %v", ld, dists, expect[i])
+		}
+	}
+}
+
+func TestPackNodes(t *testing.T) {
+	var nodes []*enode.Node
+	for i := 0; i < 7; i++ {
+		nodes = append(nodes, recordAtDistance(enode.ID{}, 256, 30000+i))
+	}
+	packets := packNodes([]byte{1}, nodes)
+	if len(packets) != 3 {
+		t.Fatalf("wrong number of packets: got %d, want 3", len(packets))
+	}
+	count := 0
+	for _, p := range packets {
+		if p.Total != 3 {
+			t.Errorf("wrong total %d in packet", p.Total)
+		}
+		count += len(p.Nodes)
+	}
+	if count != len(nodes) {
+		t.Fatalf("wrong number of records: got %d, want %d", count, len(nodes))
+	}
+	if empty := packNodes([]byte{1}, nil); len(empty) != 1 || empty[0].Total != 1 {
+		t.Fatal("empty node list should produce a single empty response")
+	}
+}
@@ -0,0 +1,461 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package v5wire
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/common/mclock"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
+)
+
+// Keys of the test vectors in the discv5.1 wire specification, see
+// https://github.com/ethereum/devp2p/blob/master/discv5/discv5-wire-test-vectors.md
+var (
+	testKeyA, _   = crypto.HexToECDSA("eef77acb6c6a6eebc5b363a475ac583ec7eccdb42b6481424c60f59aa326547f")
+	testKeyB, _   = crypto.HexToECDSA("66fb62bfbd66b9177a138c1e5cddbe4f7c30c343e94e68df8769459cb1cde628")
+	testEphKey, _ = crypto.HexToECDSA("0288ef00023598499cb6c940146d050d2b1fb914198c327f76aad590bead68b6")
+)
+
+// This test checks the HKDF implementation against test case 1 of RFC 5869.
+func TestHKDF(t *testing.T) {
+	var (
+		ikm  = bytes.Repeat([]byte{0x0b}, 22)
+		salt = hexBytes("000102030405060708090a0b0c")
+		info = hexBytes("f0f1f2f3f4f5f6f7f8f9")
+		want = hexBytes("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+	)
+	if okm := hkdf(sha256.New, ikm, salt, info, len(want)); !bytes.Equal(okm, want) {
+		t.Fatalf("wrong output\ngot:  %x\nwant: %x", okm, want)
+	}
+}
+
+func TestVectorECDH(t *testing.T) {
+	var (
+		key    = hexPrivkey("0xfb757dc581730490a1d7a00deea65e9b1936924caaea8f44d476014856b68736")
+		pub    = hexPubkey("0x039961e4c2356d61bedb83052c115d311acb3a96f5777296dcf297351130266231")
+		result = ecdh(key, pub)
+		want   = hexutil.MustDecode("0x033b11a2a1f214567e1537ce5e509ffd9b21373247f2a3ff6841f4976f53165e7e")
+	)
+	checkVector(t, "shared-secret", result, want)
+}
+
+func TestVectorKDF(t *testing.T) {
+	var (
+		ephKey = hexPrivkey("0xfb757dc581730490a1d7a00deea65e9b1936924caaea8f44d476014856b68736")
+		cdata  = hexutil.MustDecode("0x000000000000000000000000000000006469736376350001010102030405060708090a0b0c00180102030405060708090a0b0c0d0e0f100000000000000000")
+		net    = newHandshakeTest()
+	)
+	defer net.close()
+
+	destKey := &testKeyB.PublicKey
+	s := deriveKeys(sha256.New, ephKey, destKey, net.nodeA.id(), net.nodeB.id(), cdata)
+	checkVector(t, "initiator-key", s.writeKey, hexutil.MustDecode("0xdccc82d81bd610f4f76d3ebe97a40571"))
+	checkVector(t, "recipient-key", s.readKey, hexutil.MustDecode("0xac74bb8773749920b0d3a8881c173ec5"))
+}
+
+func TestVectorIDSignature(t *testing.T) {
+	var (
+		key    = hexPrivkey("0xfb757dc581730490a1d7a00deea65e9b1936924caaea8f44d476014856b68736")
+		destID = enode.HexID("0xbbbb9d047f0488c0b5a93c1c3f2d8bafc7c8ff337024a55434a0d0555de64db9")
+		ephkey = hexutil.MustDecode("0x039961e4c2356d61bedb83052c115d311acb3a96f5777296dcf297351130266231")
+		cdata  = hexutil.MustDecode("0x000000000000000000000000000000006469736376350001010102030405060708090a0b0c00180102030405060708090a0b0c0d0e0f100000000000000000")
+	)
+	sig, err := makeIDSignature(sha256.New(), key, cdata, ephkey, destID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkVector(t, "id-signature", sig, hexutil.MustDecode("0x94852a1e2318c4e5e9d422c98eaf19d1d90d876b29cd06ca7cb7546d0fff7b484fe86c09a064fe72bdbef73ba8e9c34df0cd2b53e9d65528c2c7f336d5dfc6e6"))
+}
+
+// This test checks that the packets of the specification's test vectors in
+// testdata are encoded identically, and that they decode.
+func TestVectorPackets(t *testing.T) {
+	var (
+		addr    = "127.0.0.1"
+		session = &session{
+			writeKey: hexutil.MustDecode("0x00000000000000000000000000000000"),
+			readKey:  hexutil.MustDecode("0x01010101010101010101010101010101"),
+		}
+		challenge0 = &Whoareyou{
+			ChallengeData: hexutil.MustDecode("0x000000000000000000000000000000006469736376350001010102030405060708090a0b0c00180102030405060708090a0b0c0d0e0f100000000000000000"),
+			Nonce:         Nonce{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			IDNonce:       testIDnonce,
+			RecordSeq:     0,
+		}
+		challenge1 = &Whoareyou{
+			ChallengeData: hexutil.MustDecode("0x000000000000000000000000000000006469736376350001010102030405060708090a0b0c00180102030405060708090a0b0c0d0e0f100000000000000001"),
+			Nonce:         Nonce{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			IDNonce:       testIDnonce,
+			RecordSeq:     1,
+		}
+	)
+	tests := []struct {
+		name      string     // Name of the test vector file
+		packet    Packet     // Packet sent by node A
+		challenge *Whoareyou // Challenge previously sent by node B
+		session   bool       // Whether the nodes share a session
+	}{
+		{name: "v5.1-whoareyou", packet: challenge0},
+		{name: "v5.1-ping-message", packet: &Ping{ReqID: []byte{0, 0, 0, 1}, ENRSeq: 2}, session: true},
+		{name: "v5.1-ping-handshake", packet: &Ping{ReqID: []byte{0, 0, 0, 1}, ENRSeq: 1}, challenge: challenge1},
+		{name: "v5.1-ping-handshake-enr", packet: &Ping{ReqID: []byte{0, 0, 0, 1}, ENRSeq: 1}, challenge: challenge0},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			net := newHandshakeTest()
+			defer net.close()
+
+			// Override all random inputs of node A
+			net.nodeA.c.sc.nonceGen = func(counter uint32) (Nonce, error) {
+				return Nonce{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, nil
+			}
+			net.nodeA.c.sc.maskingIVGen = func(buf []byte) error {
+				return nil // all zero
+			}
+			net.nodeA.c.sc.ephemeralKeyGen = func() (*ecdsa.PrivateKey, error) {
+				return testEphKey, nil
+			}
+			// Prime node B with the state the packet relies on
+			if test.session {
+				net.nodeA.c.sc.storeNewSession(net.nodeB.id(), addr, session)
+				net.nodeB.c.sc.storeNewSession(net.nodeA.id(), addr, session.keysFlipped())
+			}
+			if test.challenge != nil {
+				sent := *test.challenge
+				sent.Node = net.nodeA.n()
+				net.nodeB.c.sc.storeSentHandshake(net.nodeA.id(), addr, &sent)
+			}
+			want := hexFile(filepath.Join("testdata", test.name+".txt"))
+
+			enc, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, test.challenge, test.packet)
+			if !bytes.Equal(enc, want) {
+				t.Errorf("encoding mismatch\ngot:  %x\nwant: %x", enc, want)
+			}
+			dec := net.nodeB.expectDecode(t, test.packet.Kind(), want)
+			if challenge, ok := test.packet.(*Whoareyou); ok {
+				got := dec.(*Whoareyou)
+				if !bytes.Equal(got.ChallengeData, challenge.ChallengeData) || got.Nonce != challenge.Nonce || got.IDNonce != challenge.IDNonce || got.RecordSeq != challenge.RecordSeq {
+					t.Errorf("decoded challenge mismatch: %+v", got)
+				}
+			} else if !reflect.DeepEqual(dec, test.packet) {
+				t.Errorf("decoded packet mismatch: %+v", dec)
+			}
+		})
+	}
+}
+
+func TestIDSignature(t *testing.T) {
+	var (
+		key       = newKey()
+		ephkey    = EncodePubkey(&newKey().PublicKey)
+		challenge = []byte("challenge data")
+		destID    = enode.ID{1}
+	)
+	sig, err := makeIDSignature(sha256.New(), key, challenge, ephkey, destID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r enr.Record
+	enode.SignV4(&r, key)
+	n, _ := enode.New(enode.ValidSchemes, &r)
+	if err := verifyIDSignature(sha256.New(), sig, n, challenge, ephkey, destID); err != nil {
+		t.Fatal("signature does not verify:", err)
+	}
+	if err := verifyIDSignature(sha256.New(), sig, n, challenge, ephkey, enode.ID{2}); err != errInvalidNonceSig {
+		t.Fatal("signature verified for wrong destination, err:", err)
+	}
+}
+
+func TestDeriveKeys(t *testing.T) {
+	var (
+		a, b      = newKey(), newKey()
+		idA, idB  = enode.ID{1}, enode.ID{2}
+		challenge = []byte("challenge data")
+	)
+	s1 := deriveKeys(sha256.New, a, &b.PublicKey, idA, idB, challenge)
+	s2 := deriveKeys(sha256.New, b, &a.PublicKey, idA, idB, challenge).keysFlipped()
+	if !bytes.Equal(s1.writeKey, s2.readKey) || !bytes.Equal(s1.readKey, s2.writeKey) {
+		t.Fatal("derived keys don't match")
+	}
+	if bytes.Equal(s1.writeKey, s1.readKey) {
+		t.Fatal("read and write keys are equal")
+	}
+}
+
+// This test runs a complete handshake and checks that messages can be exchanged
+// afterwards in both directions.
+func TestHandshake(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	// A -> B   RANDOM PACKET
+	packet, _ := net.nodeA.encode(t, net.nodeB, &Findnode{})
+	resp := net.nodeB.expectDecode(t, UnknownPacket, packet)
+
+	// A <- B   WHOAREYOU
+	challenge := &Whoareyou{
+		Nonce:     resp.(*Unknown).Nonce,
+		IDNonce:   testIDnonce,
+		RecordSeq: 0,
+	}
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, challenge)
+	net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+
+	// A -> B   FINDNODE (handshake packet)
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, challenge, &Findnode{})
+	net.nodeB.expectDecode(t, FindnodeMsg, findnode)
+	if len(net.nodeB.c.sc.handshakes) > 0 {
+		t.Fatalf("node B didn't remove handshake from challenge map")
+	}
+
+	// A <- B   NODES
+	nodes, _ := net.nodeB.encode(t, net.nodeA, &Nodes{Total: 1})
+	net.nodeA.expectDecode(t, NodesMsg, nodes)
+
+	// A -> B   TALKREQ
+	talk, _ := net.nodeA.encode(t, net.nodeB, &TalkRequest{Protocol: "test", Message: []byte("hi")})
+	msg := net.nodeB.expectDecode(t, TalkRequestMsg, talk).(*TalkRequest)
+	if msg.Protocol != "test" || string(msg.Message) != "hi" {
+		t.Fatalf("wrong TALKREQ contents: %+v", msg)
+	}
+}
+
+// This test checks that handshake attempts are removed within the timeout.
+func TestHandshake_timeout(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	// A -> B   RANDOM PACKET
+	packet, _ := net.nodeA.encode(t, net.nodeB, &Findnode{})
+	resp := net.nodeB.expectDecode(t, UnknownPacket, packet)
+
+	// A <- B   WHOAREYOU
+	challenge := &Whoareyou{
+		Nonce:     resp.(*Unknown).Nonce,
+		IDNonce:   testIDnonce,
+		RecordSeq: 0,
+	}
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, challenge)
+	net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+
+	// A -> B   FINDNODE after timeout
+	net.clock.Run(handshakeTimeout + 1)
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, challenge, &Findnode{})
+	net.nodeB.expectDecodeErr(t, errUnexpectedHandshake, findnode)
+}
+
+// This test checks that a handshake with a forged ID signature is rejected.
+func TestHandshake_BadSignature(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	// A -> B   RANDOM PACKET
+	packet, _ := net.nodeA.encode(t, net.nodeB, &Findnode{})
+	resp := net.nodeB.expectDecode(t, UnknownPacket, packet)
+
+	// A <- B   WHOAREYOU
+	challenge := &Whoareyou{
+		Nonce:     resp.(*Unknown).Nonce,
+		IDNonce:   testIDnonce,
+		RecordSeq: 0,
+	}
+	whoareyou, _ := net.nodeB.encode(t, net.nodeA, challenge)
+	net.nodeA.expectDecode(t, WhoareyouPacket, whoareyou)
+
+	// A -> B   FINDNODE signed over a different challenge
+	forged := *challenge
+	forged.ChallengeData = append([]byte{}, challenge.ChallengeData...)
+	forged.ChallengeData[len(forged.ChallengeData)-1]++
+	findnode, _ := net.nodeA.encodeWithChallenge(t, net.nodeB, &forged, &Findnode{})
+	net.nodeB.expectDecodeErr(t, errInvalidNonceSig, findnode)
+}
+
+// This test checks that undersized and garbage packets are rejected.
+func TestDecodeErrors(t *testing.T) {
+	t.Parallel()
+	net := newHandshakeTest()
+	defer net.close()
+
+	net.nodeA.expectDecodeErr(t, errTooShort, make([]byte, minPacketSize-1))
+	net.nodeA.expectDecodeErr(t, errInvalidHeader, make([]byte, MaxPacketSize))
+}
+
+// handshakeTest sets up two codecs talking to each other.
+type handshakeTest struct {
+	nodeA, nodeB handshakeTestNode
+	clock        mclock.Simulated
+}
+
+type handshakeTestNode struct {
+	ln *enode.LocalNode
+	c  *Codec
+}
+
+var testIDnonce = [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+func newHandshakeTest() *handshakeTest {
+	t := new(handshakeTest)
+	t.nodeA.init(testKeyA, net.IP{127, 0, 0, 1}, &t.clock)
+	t.nodeB.init(testKeyB, net.IP{127, 0, 0, 1}, &t.clock)
+	return t
+}
+
+func (t *handshakeTest) close() {
+	t.nodeA.ln.Database().Close()
+	t.nodeB.ln.Database().Close()
+}
+
+func (n *handshakeTestNode) init(key *ecdsa.PrivateKey, ip net.IP, clock mclock.Clock) {
+	db, _ := enode.OpenDB("")
+	n.ln = enode.NewLocalNode(db, key)
+	n.ln.SetStaticIP(ip)
+	n.c = NewCodec(n.ln, key, clock)
+}
+
+func (n *handshakeTestNode) encode(t testing.TB, to handshakeTestNode, p Packet) ([]byte, Nonce) {
+	t.Helper()
+	return n.encodeWithChallenge(t, to, nil, p)
+}
+
+func (n *handshakeTestNode) encodeWithChallenge(t testing.TB, to handshakeTestNode, c *Whoareyou, p Packet) ([]byte, Nonce) {
+	t.Helper()
+
+	// Copy challenge and add destination node. This avoids sharing 'c' among the two codecs.
+	var challenge *Whoareyou
+	if c != nil {
+		challengeCopy := *c
+		challenge = &challengeCopy
+		challenge.Node = to.n()
+	}
+	// Encode to destination.
+	enc, nonce, err := n.c.Encode(to.id(), to.addr(), p, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("(%s) -> (%s)   %s\n%s", n.ln.ID().TerminalString(), to.id().TerminalString(), p.Name(), hex.Dump(enc))
+	return append([]byte{}, enc...), nonce
+}
+
+func (n *handshakeTestNode) expectDecode(t *testing.T, ptype byte, p []byte) Packet {
+	t.Helper()
+
+	dec, err := n.decode(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("(%s) %#v", n.ln.ID().TerminalString(), dec)
+	if dec.Kind() != ptype {
+		t.Fatalf("expected packet type %d, got %d", ptype, dec.Kind())
+	}
+	return dec
+}
+
+func (n *handshakeTestNode) expectDecodeErr(t *testing.T, wantErr error, p []byte) {
+	t.Helper()
+	if _, err := n.decode(p); !reflect.DeepEqual(err, wantErr) {
+		t.Fatalf("(%s) got err %q, want %q", n.ln.ID().TerminalString(), err, wantErr)
+	}
+}
+
+func (n *handshakeTestNode) decode(input []byte) (Packet, error) {
+	_, _, p, err := n.c.Decode(input, "127.0.0.1")
+	return p, err
+}
+
+func (n *handshakeTestNode) n() *enode.Node {
+	return n.ln.Node()
+}
+
+func (n *handshakeTestNode) addr() string {
+	return n.ln.Node().IP().String()
+}
+
+func (n *handshakeTestNode) id() enode.ID {
+	return n.ln.ID()
+}
+
+func newKey() *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func hexPrivkey(input string) *ecdsa.PrivateKey {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(input, "0x"))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func hexPubkey(input string) *ecdsa.PublicKey {
+	key, err := DecodePubkey(crypto.S256(), hexutil.MustDecode(input))
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// hexFile reads the hex encoded packet of a test vector file, skipping the
+// comment lines describing its inputs.
+func hexFile(file string) []byte {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(err)
+	}
+	var text []string
+	for _, line := range strings.Split(string(blob), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			text = append(text, line)
+		}
+	}
+	return hexBytes(strings.Join(text, ""))
+}
+
+func checkVector(t *testing.T, name string, have, want []byte) {
+	t.Helper()
+	if !bytes.Equal(have, want) {
+		t.Errorf("wrong %s\nhave: %x\nwant: %x", name, have, want)
+	}
+}
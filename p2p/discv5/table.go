@@ -20,6 +20,9 @@
 // can be connected to. It uses a Kademlia-like protocol to maintain a
 // distributed database of the IDs and endpoints of all listening
 // nodes.
+//
+// This is an experimental protocol which is not compatible with the final discovery v5
+// specification. Package p2p/discover implements the final specification (ListenV5).
 package discv5
 
 import (
@@ -49,6 +49,10 @@ const (
 	dbNodePong      = "lastpong"
 	dbNodeSeq       = "seq"
 
+	// Peer reputation is stored per ID, the full key is "n:<ID>:v4:<zero IP>:score".
+	dbNodeScore     = "score"
+	dbNodeScoreTime = "scoretime"
+
 	// Local information is keyed by ID only, the full key is "local:<ID>:seq".
 	// Use localItemKey to create those keys.
 	dbLocalSeq = "seq"
@@ -378,6 +382,24 @@ func (db *DB) UpdateFindFails(id ID, ip net.IP, fails int) error {
 	return db.storeInt64(nodeItemKey(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// PeerScore retrieves the reputation of a node as a peer, along with the time it
+// was last updated.
+func (db *DB) PeerScore(id ID) (float64, time.Time) {
+	updated := db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime))
+	if updated == 0 {
+		return 0, time.Time{}
+	}
+	return float64(db.fetchInt64(nodeItemKey(id, zeroIP, dbNodeScore))) / 1000, time.Unix(updated, 0)
+}
+
+// UpdatePeerScore stores the reputation of a node as a peer.
+func (db *DB) UpdatePeerScore(id ID, score float64, updated time.Time) error {
+	if err := db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScore), int64(score*1000)); err != nil {
+		return err
+	}
+	return db.storeInt64(nodeItemKey(id, zeroIP, dbNodeScoreTime), updated.Unix())
+}
+
 // LocalSeq retrieves the local record sequence counter.
 func (db *DB) localSeq(id ID) uint64 {
 	return db.fetchUint64(localItemKey(id, dbLocalSeq))
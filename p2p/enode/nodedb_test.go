@@ -154,6 +154,16 @@ func TestDBFetchStore(t *testing.T) {
 	if stored := db.FindFails(node.ID(), node.IP()); stored != num {
 		t.Errorf("find-node fails: value mismatch: have %v, want %v", stored, num)
 	}
+	// Check fetch/store operations on a node reputation object
+	if score, updated := db.PeerScore(node.ID()); score != 0 || !updated.IsZero() {
+		t.Errorf("score: non-existing object: %v, %v", score, updated)
+	}
+	if err := db.UpdatePeerScore(node.ID(), -12.5, inst); err != nil {
+		t.Errorf("score: failed to update: %v", err)
+	}
+	if score, updated := db.PeerScore(node.ID()); score != -12.5 || updated.Unix() != inst.Unix() {
+		t.Errorf("score: value mismatch: have %v, %v, want %v, %v", score, updated, -12.5, inst)
+	}
 	// Check fetch/store operations on an actual node object
 	if stored := db.Node(node.ID()); stored != nil {
 		t.Errorf("node: non-existing object: %v", stored)
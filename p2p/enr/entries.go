@@ -65,6 +65,12 @@ type TCP6 uint16
 
 func (v TCP6) ENRKey() string { return "tcp6" }
 
+// TLS is the "tls" key, which holds the port of the node's TLS-over-TCP
+// RLPx endpoint.
+type TLS uint16
+
+func (v TLS) ENRKey() string { return "tls" }
+
 // UDP is the "udp" key, which holds the UDP port of the node.
 type UDP uint16
 
@@ -322,3 +322,105 @@ func (ev *msgEventer) Close() error {
 	}
 	return nil
 }
+
+// MsgRecord describes a protocol message exchanged with a peer.
+type MsgRecord struct {
+	Time     time.Time // when the message was read or written
+	Protocol string    // name of the protocol
+	Version  uint      // version of the protocol
+	Code     uint64    // protocol-relative message code
+	Size     uint32    // size of the RLP-encoded payload
+	Inbound  bool      // whether the message was received from the peer
+	Payload  []byte    // payload, only set if the recorder requests payloads
+}
+
+// MsgRecorder observes the protocol messages exchanged with peers. See
+// package p2p/msgrec for a recorder which writes messages to a file.
+type MsgRecorder interface {
+	// RecordPayloads reports whether message payloads should be recorded.
+	RecordPayloads() bool
+
+	// RecordMsg is called for every message read or written by a protocol.
+	// It is called concurrently for different peers and protocols and must
+	// not retain the record.
+	RecordMsg(p *Peer, rec *MsgRecord)
+}
+
+// msgRecorder wraps a MsgReadWriter and passes all messages to a recorder.
+type msgRecorder struct {
+	MsgReadWriter
+
+	rec     MsgRecorder
+	peer    *Peer
+	proto   string
+	version uint
+}
+
+func newMsgRecorder(rw MsgReadWriter, rec MsgRecorder, peer *Peer, proto string, version uint) *msgRecorder {
+	return &msgRecorder{MsgReadWriter: rw, rec: rec, peer: peer, proto: proto, version: version}
+}
+
+// ReadMsg reads a message from the underlying MsgReadWriter and records it.
+func (mr *msgRecorder) ReadMsg() (Msg, error) {
+	msg, err := mr.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	rec := mr.newRecord(msg, true)
+	if mr.rec.RecordPayloads() {
+		if msg, err = mr.capturePayload(msg, rec); err != nil {
+			return msg, err
+		}
+	}
+	mr.rec.RecordMsg(mr.peer, rec)
+	return msg, nil
+}
+
+// WriteMsg writes a message to the underlying MsgReadWriter and records it
+// if the write succeeded.
+func (mr *msgRecorder) WriteMsg(msg Msg) error {
+	rec := mr.newRecord(msg, false)
+	if mr.rec.RecordPayloads() {
+		var err error
+		if msg, err = mr.capturePayload(msg, rec); err != nil {
+			return err
+		}
+	}
+	if err := mr.MsgReadWriter.WriteMsg(msg); err != nil {
+		return err
+	}
+	mr.rec.RecordMsg(mr.peer, rec)
+	return nil
+}
+
+func (mr *msgRecorder) newRecord(msg Msg, inbound bool) *MsgRecord {
+	return &MsgRecord{
+		Time:     time.Now(),
+		Protocol: mr.proto,
+		Version:  mr.version,
+		Code:     msg.Code,
+		Size:     msg.Size,
+		Inbound:  inbound,
+	}
+}
+
+// capturePayload reads the payload of msg into rec and returns a message
+// with an equivalent payload reader.
+func (mr *msgRecorder) capturePayload(msg Msg, rec *MsgRecord) (Msg, error) {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	rec.Payload = payload
+	return msg, nil
+}
+
+// Close closes the underlying MsgReadWriter if it implements the io.Closer
+// interface
+func (mr *msgRecorder) Close() error {
+	if v, ok := mr.MsgReadWriter.(io.Closer); ok {
+		return v.Close()
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/rlp"
 )
 
 func ExampleMsgPipe() {
@@ -142,6 +144,49 @@ func TestEOFSignal(t *testing.T) {
 	}
 }
 
+type testMsgRecorder struct {
+	payloads bool
+	recs     chan *MsgRecord
+}
+
+func (r *testMsgRecorder) RecordPayloads() bool { return r.payloads }
+
+func (r *testMsgRecorder) RecordMsg(p *Peer, rec *MsgRecord) { r.recs <- rec }
+
+func TestMsgRecorder(t *testing.T) {
+	for _, payloads := range []bool{false, true} {
+		rec := &testMsgRecorder{payloads: payloads, recs: make(chan *MsgRecord, 2)}
+		rw1, rw2 := MsgPipe()
+		mr := newMsgRecorder(rw1, rec, nil, "test", 2)
+
+		go Send(rw2, 5, []uint{1, 2})
+		if err := ExpectMsg(mr, 5, []uint{1, 2}); err != nil {
+			t.Fatal(err)
+		}
+		go ExpectMsg(rw2, 6, "foo")
+		if err := Send(mr, 6, "foo"); err != nil {
+			t.Fatal(err)
+		}
+		rw1.Close()
+
+		in, out := <-rec.recs, <-rec.recs
+		if !in.Inbound || in.Code != 5 || in.Protocol != "test" || in.Version != 2 || in.Time.IsZero() {
+			t.Errorf("wrong inbound record: %+v", in)
+		}
+		if out.Inbound || out.Code != 6 {
+			t.Errorf("wrong outbound record: %+v", out)
+		}
+		wantIn, _ := rlp.EncodeToBytes([]uint{1, 2})
+		wantOut, _ := rlp.EncodeToBytes("foo")
+		if !payloads {
+			wantIn, wantOut = nil, nil
+		}
+		if !bytes.Equal(in.Payload, wantIn) || !bytes.Equal(out.Payload, wantOut) {
+			t.Errorf("wrong payloads (payloads=%t): in %x, out %x", payloads, in.Payload, out.Payload)
+		}
+	}
+}
+
 func unhex(str string) []byte {
 	r := strings.NewReplacer("\t", "", " ", "", "\n", "")
 	b, err := hex.DecodeString(r.Replace(str))
@@ -37,6 +37,11 @@ const (
 	MetricsOutboundConnects = "p2p/dials"   // Name for the registered outbound connects meter
 	MetricsInboundConnects  = "p2p/serves"  // Name for the registered inbound connects meter
 
+	MetricsInboundCompressed  = "p2p/snappy/ingress/compressed" // Name for the registered compressed inbound payload meter
+	MetricsInboundRaw         = "p2p/snappy/ingress/raw"        // Name for the registered decompressed inbound payload meter
+	MetricsOutboundCompressed = "p2p/snappy/egress/compressed"  // Name for the registered compressed outbound payload meter
+	MetricsOutboundRaw        = "p2p/snappy/egress/raw"         // Name for the registered uncompressed outbound payload meter
+
 	MeteredPeerLimit = 1024 // This amount of peers are individually metered
 )
 
@@ -47,6 +52,11 @@ var (
 	egressTrafficMeter  = metrics.NewRegisteredMeter(MetricsOutboundTraffic, nil)  // Meter metering the cumulative egress traffic
 	activePeerGauge     = metrics.NewRegisteredGauge("p2p/peers", nil)             // Gauge tracking the current peer count
 
+	ingressCompressedMeter = metrics.NewRegisteredMeter(MetricsInboundCompressed, nil)  // Meter metering snappy payloads as received
+	ingressRawMeter        = metrics.NewRegisteredMeter(MetricsInboundRaw, nil)         // Meter metering snappy payloads after decompression
+	egressCompressedMeter  = metrics.NewRegisteredMeter(MetricsOutboundCompressed, nil) // Meter metering snappy payloads as sent
+	egressRawMeter         = metrics.NewRegisteredMeter(MetricsOutboundRaw, nil)        // Meter metering snappy payloads before compression
+
 	PeerIngressRegistry = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsInboundTraffic+"/")  // Registry containing the peer ingress
 	PeerEgressRegistry  = metrics.NewPrefixedChildRegistry(metrics.EphemeralRegistry, MetricsOutboundTraffic+"/") // Registry containing the peer egress
 
@@ -0,0 +1,200 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package msgrec records devp2p protocol messages to capture files.
+//
+// A capture file starts with a fixed header followed by a stream of
+// RLP-encoded records, one per message. Records carry the metadata of the
+// message and, if enabled when recording, its payload. Captures can be
+// re-fed into protocol handlers with Replay.
+package msgrec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/log"
+	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/rlp"
+)
+
+// fileMagic identifies capture files. The last byte is the format version.
+var fileMagic = []byte("devp2prec\x01")
+
+var errBadMagic = errors.New("not a devp2p capture file")
+
+// Record is a message in a capture file.
+type Record struct {
+	Time     uint64   // unix time in nanoseconds
+	Peer     enode.ID // remote node
+	Remote   string   // remote address of the connection
+	Protocol string
+	Version  uint
+	Code     uint64 // protocol-relative message code
+	Size     uint32 // size of the payload
+	Inbound  bool   // whether the message was received from the peer
+	Payload  []byte // empty unless payloads were recorded
+}
+
+// Timestamp returns the time at which the message was read or written.
+func (r *Record) Timestamp() time.Time {
+	return time.Unix(0, int64(r.Time))
+}
+
+// HasPayload reports whether the payload of the message was recorded.
+func (r *Record) HasPayload() bool {
+	return len(r.Payload) == int(r.Size)
+}
+
+// Msg returns the recorded message. It fails if the payload was not recorded.
+func (r *Record) Msg() (p2p.Msg, error) {
+	if !r.HasPayload() {
+		return p2p.Msg{}, fmt.Errorf("payload of %s/%d message %d not recorded", r.Protocol, r.Version, r.Code)
+	}
+	return p2p.Msg{Code: r.Code, Size: r.Size, Payload: bytes.NewReader(r.Payload)}, nil
+}
+
+// Recorder writes messages to a capture file. It implements p2p.MsgRecorder
+// and can be set as the Recorder of a p2p.Server.
+type Recorder struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closer   io.Closer
+	payloads bool
+	err      error
+}
+
+// Create creates a capture file and returns a recorder writing to it.
+func Create(file string, payloads bool) (*Recorder, error) {
+	fd, err := os.Create(file)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := NewRecorder(fd, payloads)
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	rec.closer = fd
+	return rec, nil
+}
+
+// NewRecorder creates a recorder writing to w. Message payloads are only
+// recorded if payloads is true.
+func NewRecorder(w io.Writer, payloads bool) (*Recorder, error) {
+	if _, err := w.Write(fileMagic); err != nil {
+		return nil, err
+	}
+	return &Recorder{w: w, payloads: payloads}, nil
+}
+
+// RecordPayloads implements p2p.MsgRecorder.
+func (r *Recorder) RecordPayloads() bool {
+	return r.payloads
+}
+
+// RecordMsg implements p2p.MsgRecorder.
+func (r *Recorder) RecordMsg(p *p2p.Peer, m *p2p.MsgRecord) {
+	rec := &Record{
+		Time:     uint64(m.Time.UnixNano()),
+		Peer:     p.ID(),
+		Remote:   p.RemoteAddr().String(),
+		Protocol: m.Protocol,
+		Version:  m.Version,
+		Code:     m.Code,
+		Size:     m.Size,
+		Inbound:  m.Inbound,
+		Payload:  m.Payload,
+	}
+	r.Write(rec)
+}
+
+// Write adds a record to the capture. Errors are logged once, the capture
+// is not written to after a failed write.
+func (r *Recorder) Write(rec *Record) {
+	enc, err := rlp.EncodeToBytes(rec)
+	if err != nil {
+		log.Error("Can't encode message record", "err", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if _, r.err = r.w.Write(enc); r.err != nil {
+		log.Warn("Message recording failed", "err", r.err)
+	}
+}
+
+// Close closes the capture file if the recorder was created by Create.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == nil {
+		r.err = errors.New("recorder closed")
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// Reader reads records from a capture.
+type Reader struct {
+	s *rlp.Stream
+}
+
+// Open opens a capture file. The returned closer closes the file.
+func Open(file string) (*Reader, io.Closer, error) {
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := NewReader(bufio.NewReader(fd))
+	if err != nil {
+		fd.Close()
+		return nil, nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return r, fd, nil
+}
+
+// NewReader creates a reader for the capture in r.
+func NewReader(r io.Reader) (*Reader, error) {
+	magic := make([]byte, len(fileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, fileMagic) {
+		return nil, errBadMagic
+	}
+	return &Reader{s: rlp.NewStream(r, 0)}, nil
+}
+
+// Next returns the next record. It returns io.EOF at the end of the capture.
+func (r *Reader) Next() (*Record, error) {
+	rec := new(Record)
+	if err := r.s.Decode(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package msgrec
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/rlp"
+)
+
+var (
+	peerA = enode.ID{1}
+	peerB = enode.ID{2}
+)
+
+func testRecord(peer enode.ID, proto string, code uint64, inbound bool, val interface{}) *Record {
+	payload, _ := rlp.EncodeToBytes(val)
+	return &Record{
+		Time:     uint64(time.Now().UnixNano()),
+		Peer:     peer,
+		Remote:   "pipe",
+		Protocol: proto,
+		Version:  1,
+		Code:     code,
+		Size:     uint32(len(payload)),
+		Inbound:  inbound,
+		Payload:  payload,
+	}
+}
+
+func readAll(t *testing.T, r *Reader) []*Record {
+	var recs []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return recs
+		} else if err != nil {
+			t.Fatal("read error:", err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	for _, payloads := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		rec, err := NewRecorder(buf, payloads)
+		if err != nil {
+			t.Fatal(err)
+		}
+		peer := p2p.NewPeer(peerA, "test", nil)
+		payload, _ := rlp.EncodeToBytes("foo")
+		msg := &p2p.MsgRecord{
+			Time:     time.Unix(100, 5),
+			Protocol: "test",
+			Version:  3,
+			Code:     7,
+			Size:     uint32(len(payload)),
+			Inbound:  true,
+		}
+		if rec.RecordPayloads() {
+			msg.Payload = payload
+		}
+		rec.RecordMsg(peer, msg)
+
+		r, err := NewReader(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs := readAll(t, r)
+		if len(recs) != 1 {
+			t.Fatalf("wrong number of records: %d", len(recs))
+		}
+		got := recs[0]
+		if got.Peer != peerA || got.Protocol != "test" || got.Version != 3 || got.Code != 7 || !got.Inbound {
+			t.Errorf("wrong record: %+v", got)
+		}
+		if !got.Timestamp().Equal(msg.Time) {
+			t.Errorf("wrong timestamp %v, want %v", got.Timestamp(), msg.Time)
+		}
+		if got.HasPayload() != payloads {
+			t.Errorf("HasPayload = %t, want %t", got.HasPayload(), payloads)
+		}
+		if _, err := got.Msg(); (err == nil) != payloads {
+			t.Errorf("wrong Msg error: %v", err)
+		}
+	}
+}
+
+func TestReaderBadMagic(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte("foo"))); err != errBadMagic {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	buf := new(bytes.Buffer)
+	rec, _ := NewRecorder(buf, true)
+	rec.Write(testRecord(peerA, "test", 0, true, "a1"))
+	rec.Write(testRecord(peerB, "test", 0, true, "b1"))
+	rec.Write(testRecord(peerA, "test", 1, false, "sent"))
+	rec.Write(testRecord(peerA, "other", 0, true, "other"))
+	rec.Write(testRecord(peerA, "test", 2, true, "a2"))
+
+	var (
+		received []string
+		sent     []uint64
+	)
+	proto := p2p.Protocol{
+		Name:    "test",
+		Version: 1,
+		Length:  3,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if p.ID() != peerA {
+				t.Errorf("wrong peer ID %v", p.ID())
+			}
+			for {
+				msg, err := rw.ReadMsg()
+				if err != nil {
+					return err
+				}
+				var s string
+				if err := msg.Decode(&s); err != nil {
+					return err
+				}
+				received = append(received, s)
+				if err := p2p.Send(rw, 1, s); err != nil {
+					return err
+				}
+			}
+		},
+	}
+	r, _ := NewReader(buf)
+	err := Replay(r, proto, peerA, func(msg p2p.Msg) { sent = append(sent, msg.Code) })
+	if err != nil {
+		t.Fatal("replay failed:", err)
+	}
+	if want := []string{"a1", "a2"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("handler received %q, want %q", received, want)
+	}
+	if want := []uint64{1, 1}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("handler sent %v, want %v", sent, want)
+	}
+}
+
+// This test checks that a server with a recorder captures the messages
+// exchanged with its peers.
+func TestServerRecording(t *testing.T) {
+	var (
+		network = p2p.NewMemoryNetwork()
+		buf     = new(bytes.Buffer)
+		done    = make(chan struct{}, 2) // Signalled by the handlers of both servers
+	)
+	rec, _ := NewRecorder(buf, true)
+	proto := p2p.Protocol{
+		Name:    "test",
+		Version: 1,
+		Length:  2,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			if err := p2p.Send(rw, 0, "hello"); err != nil {
+				return err
+			}
+			if err := p2p.ExpectMsg(rw, 0, "hello"); err != nil {
+				return err
+			}
+			if err := p2p.Send(rw, 1, "bye"); err != nil {
+				return err
+			}
+			err := p2p.ExpectMsg(rw, 1, "bye")
+			done <- struct{}{}
+			if err != nil {
+				return err
+			}
+			_, err = rw.ReadMsg()
+			return err
+		},
+	}
+	newServer := func(recorder p2p.MsgRecorder) *p2p.Server {
+		key, _ := crypto.GenerateKey()
+		srv := &p2p.Server{
+			Config: p2p.Config{
+				PrivateKey:  key,
+				MaxPeers:    1,
+				ListenAddr:  "mem",
+				NoDiscovery: true,
+				Protocols:   []p2p.Protocol{proto},
+				Transport:   network,
+				Recorder:    recorder,
+			},
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatal("can't start server:", err)
+		}
+		return srv
+	}
+	srv1 := newServer(rec)
+	defer srv1.Stop()
+	srv2 := newServer(nil)
+	defer srv2.Stop()
+	srv1.AddPeer(srv2.Self())
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < cap(done); i++ {
+		select {
+		case <-done:
+		case <-timeout:
+			t.Fatal("protocol exchange did not complete")
+		}
+	}
+	// Stop the server to flush all records.
+	srv1.Stop()
+
+	r, err := NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, rec := range readAll(t, r) {
+		if rec.Peer != srv2.Self().ID() || rec.Protocol != "test" {
+			t.Errorf("unexpected record: %+v", rec)
+		}
+		var s string
+		rlp.DecodeBytes(rec.Payload, &s)
+		dir := "out"
+		if rec.Inbound {
+			dir = "in"
+		}
+		msgs = append(msgs, dir+":"+s)
+	}
+	// The order of concurrent sends and receives is not deterministic,
+	// so only check that all messages were recorded.
+	if len(msgs) != 4 {
+		t.Fatalf("wrong records: %q", msgs)
+	}
+	for _, want := range []string{"in:hello", "out:hello", "in:bye", "out:bye"} {
+		found := false
+		for _, m := range msgs {
+			found = found || m == want
+		}
+		if !found {
+			t.Errorf("missing record %q in %q", want, msgs)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package msgrec
+
+import (
+	"io"
+
+	"github.com/Fantom-foundation/go-ethereum/p2p"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+)
+
+// Filter selects records of a capture.
+type Filter struct {
+	Peer     enode.ID // if non-zero, only messages of this peer match
+	Protocol string   // if non-empty, only messages of this protocol match
+	Version  uint     // if non-zero, only messages of this protocol version match
+}
+
+// Match reports whether rec is selected by the filter.
+func (f Filter) Match(rec *Record) bool {
+	switch {
+	case f.Peer != (enode.ID{}) && rec.Peer != f.Peer:
+		return false
+	case f.Protocol != "" && rec.Protocol != f.Protocol:
+		return false
+	case f.Version != 0 && rec.Version != f.Version:
+		return false
+	}
+	return true
+}
+
+// Feed writes the inbound messages of the capture selected by f to w, in
+// recorded order. It returns the number of messages written.
+func Feed(r *Reader, w p2p.MsgWriter, f Filter) (int, error) {
+	count := 0
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+		if !rec.Inbound || !f.Match(rec) {
+			continue
+		}
+		msg, err := rec.Msg()
+		if err != nil {
+			return count, err
+		}
+		if err := w.WriteMsg(msg); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// Replay runs the handler of proto against a simulated peer which sends the
+// messages that were received from peer in the capture. Messages of other
+// peers, protocols and protocol versions are skipped. If peer is the zero ID,
+// messages of all peers are replayed.
+//
+// Messages written by the handler are passed to sent, or discarded if sent is
+// nil. Replay returns when the capture is exhausted or the handler returns.
+// The error of the handler is returned unless it was caused by the end of
+// the replay.
+func Replay(r *Reader, proto p2p.Protocol, peer enode.ID, sent func(p2p.Msg)) error {
+	var (
+		rw1, rw2 = p2p.MsgPipe()
+		p        = p2p.NewPeer(peer, "replay", []p2p.Cap{{Name: proto.Name, Version: proto.Version}})
+		runErr   = make(chan error, 1)
+	)
+	go func() {
+		err := proto.Run(p, rw2)
+		rw2.Close()
+		runErr <- err
+	}()
+	go func() {
+		for {
+			msg, err := rw1.ReadMsg()
+			if err != nil {
+				return
+			}
+			if sent != nil {
+				sent(msg)
+			}
+			msg.Discard()
+		}
+	}()
+
+	_, feedErr := Feed(r, rw1, Filter{Peer: peer, Protocol: proto.Name, Version: proto.Version})
+	rw1.Close()
+	err := <-runErr
+	if feedErr != nil && feedErr != p2p.ErrPipeClosed {
+		return feedErr
+	}
+	if err == p2p.ErrPipeClosed {
+		return nil
+	}
+	return err
+}
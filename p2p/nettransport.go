@@ -0,0 +1,264 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
+)
+
+// NetTransport establishes the network connections that RLPx sessions run
+// on. Implementations must provide reliable, ordered streams. Peers are
+// authenticated and traffic is encrypted by the RLPx handshake above the
+// transport, so a transport is only concerned with reaching the remote node.
+type NetTransport interface {
+	NodeDialer
+
+	// Listen creates a listener for inbound connections on addr.
+	Listen(addr string) (net.Listener, error)
+
+	// Endpoint returns the node record entries which advertise the
+	// given listener to other nodes.
+	Endpoint(l net.Listener) []enr.Entry
+}
+
+var (
+	errNoTLSEndpoint     = errors.New("node has no TLS endpoint")
+	errNoMemEndpoint     = errors.New("node has no in-memory endpoint")
+	errMemConnRefused    = errors.New("connection refused")
+	errMemListenerClosed = errors.New("listener closed")
+)
+
+// TCPTransport carries RLPx connections on plain TCP. This is the default
+// transport of Server.
+type TCPTransport struct {
+	TCPDialer
+}
+
+// NewTCPTransport creates a TCP transport.
+func NewTCPTransport() *TCPTransport {
+	return &TCPTransport{TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}}
+}
+
+// Listen creates a TCP listener.
+func (t *TCPTransport) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// Endpoint returns the "tcp" entry for the listener.
+func (t *TCPTransport) Endpoint(l net.Listener) []enr.Entry {
+	if tcp, ok := l.Addr().(*net.TCPAddr); ok {
+		return []enr.Entry{enr.TCP(tcp.Port)}
+	}
+	return nil
+}
+
+// TLSTransport carries RLPx connections in TLS over TCP. This lets nodes
+// communicate through middleboxes which only pass TLS traffic. Nodes using
+// this transport advertise their listening port in the "tls" record entry.
+//
+// Since only node records can carry the "tls" entry, peers are reachable only if
+// their record is known, i.e. found through discovery v5.1 or configured as enr:
+// URLs. Nodes found through discovery v4 or given as enode:// URLs are not.
+//
+// Certificates are self-signed and not verified because the remote node is
+// authenticated by the RLPx handshake.
+type TLSTransport struct {
+	dialer *net.Dialer
+	config *tls.Config
+}
+
+// NewTLSTransport creates a TLS transport with a fresh self-signed
+// certificate.
+func NewTLSTransport() (*TLSTransport, error) {
+	cert, err := selfSignedCert()
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+	}
+	return &TLSTransport{dialer: &net.Dialer{Timeout: defaultDialTimeout}, config: config}, nil
+}
+
+// Dial connects to the TLS endpoint of dest.
+func (t *TLSTransport) Dial(dest *enode.Node) (net.Conn, error) {
+	var port enr.TLS
+	if err := dest.Load(&port); err != nil {
+		return nil, errNoTLSEndpoint
+	}
+	addr := &net.TCPAddr{IP: dest.IP(), Port: int(port)}
+	return tls.DialWithDialer(t.dialer, "tcp", addr.String(), t.config)
+}
+
+// Listen creates a TLS listener.
+func (t *TLSTransport) Listen(addr string) (net.Listener, error) {
+	return tls.Listen("tcp", addr, t.config)
+}
+
+// Endpoint returns the "tls" entry for the listener.
+func (t *TLSTransport) Endpoint(l net.Listener) []enr.Entry {
+	if tcp, ok := l.Addr().(*net.TCPAddr); ok {
+		return []enr.Entry{enr.TLS(tcp.Port)}
+	}
+	return nil
+}
+
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "devp2p"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// MemoryNetwork is an in-memory transport for tests. Servers sharing a
+// MemoryNetwork can dial each other using the "mem" entry of their records.
+type MemoryNetwork struct {
+	mu        sync.Mutex
+	listeners map[memAddr]*memListener
+	counter   int
+}
+
+// NewMemoryNetwork creates an empty in-memory network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{listeners: make(map[memAddr]*memListener)}
+}
+
+// Listen creates a listener on the network. The requested address is
+// ignored, every listener gets a fresh address.
+func (n *MemoryNetwork) Listen(string) (net.Listener, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.counter++
+	l := &memListener{
+		net:    n,
+		addr:   memAddr(fmt.Sprintf("mem-%d", n.counter)),
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	n.listeners[l.addr] = l
+	return l, nil
+}
+
+// Dial connects to the listener advertised by dest. It blocks until the
+// connection is accepted.
+func (n *MemoryNetwork) Dial(dest *enode.Node) (net.Conn, error) {
+	var ep memEndpoint
+	if err := dest.Load(&ep); err != nil {
+		return nil, errNoMemEndpoint
+	}
+	n.mu.Lock()
+	l := n.listeners[memAddr(ep)]
+	n.mu.Unlock()
+	if l == nil {
+		return nil, errMemConnRefused
+	}
+
+	c1, c2 := net.Pipe()
+	select {
+	case l.conns <- c2:
+		return c1, nil
+	case <-l.closed:
+		c1.Close()
+		c2.Close()
+		return nil, errMemConnRefused
+	}
+}
+
+// Endpoint returns the "mem" entry for the listener.
+func (n *MemoryNetwork) Endpoint(l net.Listener) []enr.Entry {
+	if addr, ok := l.Addr().(memAddr); ok {
+		return []enr.Entry{memEndpoint(addr)}
+	}
+	return nil
+}
+
+// memEndpoint is the "mem" record entry, which holds the address of an
+// in-memory listener.
+type memEndpoint string
+
+func (memEndpoint) ENRKey() string { return "mem" }
+
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+type memListener struct {
+	net       *MemoryNetwork
+	addr      memAddr
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, errMemListenerClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.net.mu.Lock()
+		delete(l.net.listeners, l.addr)
+		l.net.mu.Unlock()
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *memListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/internal/testlog"
+	"github.com/Fantom-foundation/go-ethereum/log"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
+)
+
+func TestServerMemoryTransport(t *testing.T) {
+	network := NewMemoryNetwork()
+	srv1, srv2 := testTransportConnect(t, network, network)
+	defer srv1.Stop()
+	defer srv2.Stop()
+
+	var ep memEndpoint
+	if err := srv1.Self().Load(&ep); err != nil {
+		t.Fatal("record has no mem entry:", err)
+	}
+	if srv1.Self().TCP() != 0 {
+		t.Fatal("record has tcp entry")
+	}
+}
+
+func TestServerTLSTransport(t *testing.T) {
+	tr1, err := NewTLSTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr2, err := NewTLSTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv1, srv2 := testTransportConnect(t, tr1, tr2)
+	defer srv1.Stop()
+	defer srv2.Stop()
+
+	var port enr.TLS
+	if err := srv1.Self().Load(&port); err != nil {
+		t.Fatal("record has no tls entry:", err)
+	}
+	if srv1.Self().TCP() != 0 {
+		t.Fatal("record has tcp entry")
+	}
+	if have, want := srv1.NodeInfo().Ports.Listener, int(port); have != want {
+		t.Fatalf("wrong listener port in node info: have %d, want %d", have, want)
+	}
+	// Plain TCP nodes can't reach the TLS endpoint.
+	if _, err := NewTCPTransport().Dial(srv1.Self()); err == nil {
+		t.Fatal("TCP dial to TLS-only node succeeded")
+	}
+}
+
+// testTransportConnect starts two servers on the given transports and waits
+// until the second one has connected to the first.
+func testTransportConnect(t *testing.T, tr1, tr2 NetTransport) (*Server, *Server) {
+	newServer := func(tr NetTransport) *Server {
+		srv := &Server{
+			Config: Config{
+				PrivateKey:  newkey(),
+				MaxPeers:    10,
+				ListenAddr:  "127.0.0.1:0",
+				NoDiscovery: true,
+				Protocols:   []Protocol{discard},
+				Transport:   tr,
+				Logger:      testlog.Logger(t, log.LvlTrace),
+			},
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start: %v", err)
+		}
+		return srv
+	}
+	srv1 := newServer(tr1)
+	srv2 := newServer(tr2)
+
+	events := make(chan *PeerEvent, 10)
+	sub := srv2.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+	srv2.AddPeer(srv1.Self())
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == PeerEventTypeAdd && ev.Peer == srv1.Self().ID() {
+				return srv1, srv2
+			}
+		case <-timeout:
+			srv1.Stop()
+			srv2.Stop()
+			t.Fatal("peer not connected")
+		}
+	}
+}
@@ -114,8 +114,14 @@ type Peer struct {
 	closed   chan struct{}
 	disc     chan DiscReason
 
+	score   *peerScore // Reputation tracking of the peer
+	evicted bool       // Whether the peer is being evicted (only accessed by Server.run)
+
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// recorder observes all protocol messages if set
+	recorder MsgRecorder
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -188,6 +194,7 @@ func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 		disc:     make(chan DiscReason),
 		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
 		closed:   make(chan struct{}),
+		score:    new(peerScore),
 		log:      log.New("id", conn.node.ID(), "conn", conn.flags),
 	}
 	return p
@@ -362,6 +369,9 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
 		}
+		if p.recorder != nil {
+			rw = newMsgRecorder(rw, p.recorder, p, proto.Name, proto.Version)
+		}
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			err := proto.Run(p, rw)
@@ -448,6 +458,7 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Score     *PeerScoreInfo         `json:"score"`     // Reputation summary of the peer
 }
 
 // Info gathers and returns a collection of metadata known about a peer.
@@ -464,6 +475,7 @@ func (p *Peer) Info() *PeerInfo {
 		Name:      p.Name(),
 		Caps:      caps,
 		Protocols: make(map[string]interface{}),
+		Score:     p.score.info(time.Now()),
 	}
 	if p.Node().Seq() > 0 {
 		info.ENR = p.Node().String()
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -375,6 +376,89 @@ func TestRLPXFrameRW(t *testing.T) {
 	}
 }
 
+// newTestFramePair creates two frame readers/writers sharing conn, with
+// matching secrets so that messages written by one can be read by the other.
+func newTestFramePair(conn io.ReadWriter) (*rlpxFrameRW, *rlpxFrameRW) {
+	var (
+		aesSecret      = make([]byte, 16)
+		macSecret      = make([]byte, 16)
+		egressMACinit  = make([]byte, 32)
+		ingressMACinit = make([]byte, 32)
+	)
+	for _, s := range [][]byte{aesSecret, macSecret, egressMACinit, ingressMACinit} {
+		rand.Read(s)
+	}
+	s1 := secrets{
+		AES:        aesSecret,
+		MAC:        macSecret,
+		EgressMAC:  sha3.NewLegacyKeccak256(),
+		IngressMAC: sha3.NewLegacyKeccak256(),
+	}
+	s1.EgressMAC.Write(egressMACinit)
+	s1.IngressMAC.Write(ingressMACinit)
+
+	s2 := secrets{
+		AES:        aesSecret,
+		MAC:        macSecret,
+		EgressMAC:  sha3.NewLegacyKeccak256(),
+		IngressMAC: sha3.NewLegacyKeccak256(),
+	}
+	s2.EgressMAC.Write(ingressMACinit)
+	s2.IngressMAC.Write(egressMACinit)
+	return newRLPXFrameRW(conn, s1), newRLPXFrameRW(conn, s2)
+}
+
+func TestRLPXFrameRWSnappy(t *testing.T) {
+	conn := new(bytes.Buffer)
+	rw1, rw2 := newTestFramePair(conn)
+	rw1.snappy, rw2.snappy = true, true
+
+	for i := 0; i < 10; i++ {
+		wmsg := []interface{}{"foo", "bar", strings.Repeat("test", i*100)}
+		wantPayload, _ := rlp.EncodeToBytes(wmsg)
+		if err := Send(rw1, uint64(i), wmsg); err != nil {
+			t.Fatalf("WriteMsg error (i=%d): %v", i, err)
+		}
+		// Repetitive payloads must shrink on the wire.
+		if i > 0 && conn.Len() >= len(wantPayload) {
+			t.Fatalf("payload not compressed (i=%d): %d bytes on wire, %d raw", i, conn.Len(), len(wantPayload))
+		}
+		msg, err := rw2.ReadMsg()
+		if err != nil {
+			t.Fatalf("ReadMsg error (i=%d): %v", i, err)
+		}
+		if msg.Code != uint64(i) {
+			t.Fatalf("msg code mismatch: got %d, want %d", msg.Code, i)
+		}
+		if msg.Size != uint32(len(wantPayload)) {
+			t.Fatalf("msg size mismatch: got %d, want %d", msg.Size, len(wantPayload))
+		}
+		payload, _ := ioutil.ReadAll(msg.Payload)
+		if !bytes.Equal(payload, wantPayload) {
+			t.Fatalf("msg payload mismatch:\ngot  %x\nwant %x", payload, wantPayload)
+		}
+	}
+}
+
+// This test checks that a compressed message announcing a decoded size
+// above the limit is rejected before it is decompressed.
+func TestRLPXFrameRWSnappyTooLarge(t *testing.T) {
+	conn := new(bytes.Buffer)
+	rw1, rw2 := newTestFramePair(conn)
+	rw2.snappy = true
+
+	// Snappy blocks start with the uvarint-encoded decoded length.
+	bomb := make([]byte, binary.MaxVarintLen32+8)
+	n := binary.PutUvarint(bomb, uint64(maxUint24)+1)
+	bomb = bomb[:n+8]
+	if err := rw1.WriteMsg(Msg{Code: 1, Size: uint32(len(bomb)), Payload: bytes.NewReader(bomb)}); err != nil {
+		t.Fatalf("WriteMsg error: %v", err)
+	}
+	if _, err := rw2.ReadMsg(); err != errPlainMessageTooLarge {
+		t.Fatalf("wrong error: got %v, want %v", err, errPlainMessageTooLarge)
+	}
+}
+
 type handshakeAuthTest struct {
 	input       string
 	isPlain     bool
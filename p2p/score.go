@@ -0,0 +1,259 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/mclock"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+)
+
+// ScoreEvent is a peer behaviour reported by a protocol, adjusting the
+// reputation of the peer.
+type ScoreEvent int
+
+const (
+	// ScoreUsefulResponse is reported when a peer answers a request with data
+	// that is actually used.
+	ScoreUsefulResponse ScoreEvent = iota
+
+	// ScoreUselessResponse is reported when a peer answers a request with empty,
+	// stale or unrequested data.
+	ScoreUselessResponse
+
+	// ScoreTimeout is reported when a peer fails to answer requests in time,
+	// stalling synchronisation.
+	ScoreTimeout
+
+	// ScoreInvalidBlock is reported when a peer propagates or delivers a block
+	// failing validation.
+	ScoreInvalidBlock
+
+	// ScoreDropped is reported when a synchroniser drops a peer for any kind of
+	// misbehaviour, e.g. stalling or delivering an invalid chain.
+	ScoreDropped
+)
+
+// scoreWeights is the reputation change caused by each peer behaviour.
+var scoreWeights = [...]float64{
+	ScoreUsefulResponse:  1,
+	ScoreUselessResponse: -2,
+	ScoreTimeout:         -10,
+	ScoreInvalidBlock:    -50,
+	ScoreDropped:         -20,
+}
+
+const (
+	maxReputation      = 100                    // Bound of the accumulated reputation in either direction
+	reputationHalfLife = 6 * time.Hour          // Time for a reputation to decay half way back to neutral
+	latencyPenaltyUnit = 100 * time.Millisecond // Average response latency costing a point of score
+	maxLatencyPenalty  = 20                     // Upper bound of the score lost to slow responses
+	latencySmoothing   = 8                      // Weight of the previous average when adding a latency sample
+
+	minDialScore     = -50         // Score below which discovered nodes are not dialed
+	evictMargin      = 10          // Score a new connection needs above the worst peer to replace it
+	evictGracePeriod = time.Minute // Time a peer gets to prove itself before it may be evicted
+)
+
+// PeerScoreInfo is the reputation summary of a connected peer.
+type PeerScoreInfo struct {
+	Score      float64 `json:"score"`      // Overall score, the reputation less the latency penalty
+	Reputation float64 `json:"reputation"` // Decaying reputation accumulated across connections
+	Latency    string  `json:"latency"`    // Average response latency in this connection
+	Useful     uint64  `json:"useful"`     // Useful responses in this connection
+	Useless    uint64  `json:"useless"`    // Useless responses in this connection
+	Timeouts   uint64  `json:"timeouts"`   // Timeouts in this connection
+	Invalid    uint64  `json:"invalid"`    // Invalid blocks in this connection
+	Dropped    uint64  `json:"dropped"`    // Drops by the synchronisers in this connection
+}
+
+// peerScore tracks the behaviour of a single peer. The reputation survives the
+// connection through the node database, while the latency and event counters
+// only cover the current connection.
+type peerScore struct {
+	reputation float64                   // Accumulated event weights, decaying towards zero
+	updated    time.Time                 // Time the reputation was last decayed
+	latency    time.Duration             // Moving average of response latencies
+	events     [len(scoreWeights)]uint64 // Number of events of each kind reported
+	lock       sync.Mutex
+}
+
+// decayReputation returns the reputation last updated at the given time, decayed
+// to the present.
+func decayReputation(reputation float64, updated, now time.Time) float64 {
+	if updated.IsZero() || !now.After(updated) {
+		return reputation
+	}
+	return reputation * math.Exp2(-float64(now.Sub(updated))/float64(reputationHalfLife))
+}
+
+// latencyPenalty returns the score lost due to an average response latency.
+func latencyPenalty(latency time.Duration) float64 {
+	return math.Min(float64(latency)/float64(latencyPenaltyUnit), maxLatencyPenalty)
+}
+
+// load seeds the reputation from a previous connection.
+func (s *peerScore) load(reputation float64, updated time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reputation, s.updated = reputation, updated
+}
+
+// snapshot returns the reputation along with its update time, for persisting.
+func (s *peerScore) snapshot() (float64, time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.reputation, s.updated
+}
+
+// report records a behaviour of the peer.
+func (s *peerScore) report(event ScoreEvent, now time.Time) {
+	if event < 0 || int(event) >= len(scoreWeights) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reputation = decayReputation(s.reputation, s.updated, now) + scoreWeights[event]
+	s.reputation = math.Max(-maxReputation, math.Min(maxReputation, s.reputation))
+	s.updated = now
+	s.events[event]++
+}
+
+// reportLatency adds a response latency sample to the moving average.
+func (s *peerScore) reportLatency(latency time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.latency == 0 {
+		s.latency = latency
+	} else {
+		s.latency += (latency - s.latency) / latencySmoothing
+	}
+}
+
+// score returns the overall score of the peer.
+func (s *peerScore) score(now time.Time) float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return decayReputation(s.reputation, s.updated, now) - latencyPenalty(s.latency)
+}
+
+// info returns the reputation summary of the peer.
+func (s *peerScore) info(now time.Time) *PeerScoreInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	reputation := decayReputation(s.reputation, s.updated, now)
+	return &PeerScoreInfo{
+		Score:      reputation - latencyPenalty(s.latency),
+		Reputation: reputation,
+		Latency:    common.PrettyDuration(s.latency).String(),
+		Useful:     s.events[ScoreUsefulResponse],
+		Useless:    s.events[ScoreUselessResponse],
+		Timeouts:   s.events[ScoreTimeout],
+		Invalid:    s.events[ScoreInvalidBlock],
+		Dropped:    s.events[ScoreDropped],
+	}
+}
+
+// ReportScore records a behaviour of the peer, adjusting its reputation.
+func (p *Peer) ReportScore(event ScoreEvent) {
+	p.score.report(event, time.Now())
+}
+
+// ReportLatency records the time the peer took to answer a request.
+func (p *Peer) ReportLatency(latency time.Duration) {
+	p.score.reportLatency(latency)
+}
+
+// Score returns the current score of the peer. Peers with higher scores are
+// preferred when dialing and when making room for new connections.
+func (p *Peer) Score() float64 {
+	return p.score.score(time.Now())
+}
+
+// loadScore seeds the reputation of a new peer from the node database.
+func (srv *Server) loadScore(p *Peer) {
+	if srv.nodedb == nil {
+		return
+	}
+	p.score.load(srv.nodedb.PeerScore(p.ID()))
+}
+
+// storeScore persists the reputation of a disconnecting peer.
+func (srv *Server) storeScore(p *Peer) {
+	reputation, updated := p.score.snapshot()
+	if srv.nodedb == nil || updated.IsZero() {
+		return
+	}
+	if err := srv.nodedb.UpdatePeerScore(p.ID(), reputation, updated); err != nil {
+		p.log.Debug("Failed to store peer score", "err", err)
+	}
+}
+
+// nodeScore returns the persisted reputation of a node, which is the score of a
+// node that is not connected.
+func (srv *Server) nodeScore(id enode.ID) float64 {
+	if srv.nodedb == nil {
+		return 0
+	}
+	reputation, updated := srv.nodedb.PeerScore(id)
+	return decayReputation(reputation, updated, time.Now())
+}
+
+// evictPeer makes room for a new connection when the peer limit is reached, by
+// disconnecting the lowest scoring peer if the new node's reputation is
+// sufficiently better. Trusted and static peers, and peers connected only
+// recently are never evicted. If inbound is set, only inbound peers are
+// considered.
+func (srv *Server) evictPeer(peers map[enode.ID]*Peer, c *conn, inbound bool) bool {
+	var (
+		victim      *Peer
+		victimScore float64
+		now         = time.Now()
+	)
+	for _, p := range peers {
+		if p.evicted || p.rw.is(trustedConn|staticDialedConn) || (inbound && !p.Inbound()) {
+			continue
+		}
+		if time.Duration(mclock.Now()-p.created) < evictGracePeriod {
+			continue
+		}
+		if score := p.score.score(now); victim == nil || score < victimScore {
+			victim, victimScore = p, score
+		}
+	}
+	if victim == nil {
+		return false
+	}
+	score := srv.nodeScore(c.node.ID())
+	if victimScore+evictMargin > score {
+		return false
+	}
+	victim.log.Debug("Evicting low scoring peer", "score", victimScore, "replacement", c.node.ID(), "replacementScore", score)
+	victim.evicted = true
+	victim.Disconnect(DiscUselessPeer)
+	return true
+}
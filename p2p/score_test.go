@@ -0,0 +1,170 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common/mclock"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
+	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
+)
+
+func TestPeerScore(t *testing.T) {
+	var (
+		score = new(peerScore)
+		now   = time.Now()
+	)
+	score.report(ScoreUsefulResponse, now)
+	score.report(ScoreUsefulResponse, now)
+	score.report(ScoreUselessResponse, now)
+	if have := score.score(now); have != 0 {
+		t.Errorf("score mismatch: have %v, want 0", have)
+	}
+	// Reputation is bounded
+	for i := 0; i < 10; i++ {
+		score.report(ScoreInvalidBlock, now)
+	}
+	if have := score.score(now); have != -maxReputation {
+		t.Errorf("bounded score mismatch: have %v, want %v", have, -maxReputation)
+	}
+	// Reputation decays towards neutral
+	if have := score.score(now.Add(reputationHalfLife)); math.Abs(have+maxReputation/2) > 1e-9 {
+		t.Errorf("decayed score mismatch: have %v, want %v", have, -maxReputation/2)
+	}
+	// Slow responses cost score
+	score = new(peerScore)
+	score.reportLatency(time.Second)
+	if have := score.score(now); have != -10 {
+		t.Errorf("latency penalty mismatch: have %v, want -10", have)
+	}
+	score.reportLatency(time.Hour)
+	if have := score.score(now); have != -maxLatencyPenalty {
+		t.Errorf("bounded latency penalty mismatch: have %v, want %v", have, -maxLatencyPenalty)
+	}
+	info := score.info(now)
+	if info.Useful != 0 || info.Score != -maxLatencyPenalty {
+		t.Errorf("info mismatch: %+v", info)
+	}
+}
+
+func TestDialRanking(t *testing.T) {
+	var (
+		good, bad, unknown = randomID(), randomID(), randomID()
+		scores             = map[enode.ID]float64{good: 20, bad: -5}
+	)
+	s := newDialState(enode.ID{}, 3, &Config{})
+	s.score = func(id enode.ID) float64 { return scores[id] }
+
+	s.taskDone(&discoverTask{results: []*enode.Node{newNode(bad, nil), newNode(unknown, nil), newNode(good, nil)}}, time.Now())
+	want := []enode.ID{good, unknown, bad}
+	for i, n := range s.lookupBuf {
+		if n.ID() != want[i] {
+			t.Fatalf("candidate %d mismatch: have %x, want %x", i, n.ID(), want[i])
+		}
+	}
+	// Nodes with very bad reputation are not dialed at all
+	scores[bad] = minDialScore - 1
+	for _, task := range s.newTasks(0, nil, time.Now()) {
+		if dial, ok := task.(*dialTask); ok && dial.dest.ID() == bad {
+			t.Fatalf("low reputation node dialed")
+		}
+	}
+}
+
+func TestServerEviction(t *testing.T) {
+	remote := newkey()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    2,
+			NoDial:      true,
+			NoDiscovery: true,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	events := make(chan *PeerEvent, 10)
+	sub := srv.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remote.PublicKey, fd)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	// Fill up the peer set and make the peers old enough to be evicted
+	for i := 0; i < 2; i++ {
+		if err := srv.checkpoint(newconn(randomID()), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	srv.peerOp <- func(peers map[enode.ID]*Peer) {
+		for _, p := range peers {
+			p.created = mclock.Now() - mclock.AbsTime(2*evictGracePeriod)
+		}
+	}
+	<-srv.peerOpDone
+
+	// Unknown nodes must not replace peers of neutral reputation
+	if err := srv.checkpoint(newconn(randomID()), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Fatalf("wrong error for unknown node: %v", err)
+	}
+	// A node with good reputation must replace a misbehaving peer
+	peers := srv.Peers()
+	victim := peers[0]
+	victim.ReportScore(ScoreTimeout)
+	victim.ReportScore(ScoreTimeout)
+
+	good := randomID()
+	srv.nodedb.UpdatePeerScore(good, 5, time.Now())
+	if err := srv.checkpoint(newconn(good), srv.checkpointPostHandshake); err != nil {
+		t.Fatalf("unexpected error for reputable node: %v", err)
+	}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != PeerEventTypeDrop {
+				continue
+			}
+			if ev.Peer != victim.ID() {
+				t.Fatalf("wrong peer evicted: have %x, want %x", ev.Peer, victim.ID())
+			}
+		case <-timeout:
+			t.Fatalf("peer not evicted")
+		}
+		break
+	}
+	// The reputation of the evicted peer must be persisted
+	for i := 0; ; i++ {
+		if score, _ := srv.nodedb.PeerScore(victim.ID()); score < -19 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("victim reputation not persisted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -37,7 +37,6 @@ import (
 	"github.com/Fantom-foundation/go-ethereum/p2p/discover"
 	"github.com/Fantom-foundation/go-ethereum/p2p/discv5"
 	"github.com/Fantom-foundation/go-ethereum/p2p/enode"
-	"github.com/Fantom-foundation/go-ethereum/p2p/enr"
 	"github.com/Fantom-foundation/go-ethereum/p2p/nat"
 	"github.com/Fantom-foundation/go-ethereum/p2p/netutil"
 )
@@ -95,6 +94,11 @@ type Config struct {
 	// protocol should be started or not.
 	DiscoveryV5 bool `toml:",omitempty"`
 
+	// DiscoveryV51 specifies whether the ENR-based discovery v5.1 protocol should be
+	// started. It shares the UDP port with v4 discovery and cannot be combined with
+	// the topic-discovery based DiscoveryV5.
+	DiscoveryV51 bool `toml:",omitempty"`
+
 	// Name sets the node name of this server.
 	// Use common.MakeName to create a name that follows existing conventions.
 	Name string `toml:"-"`
@@ -108,6 +112,11 @@ type Config struct {
 	// protocol.
 	BootstrapNodesV5 []*discv5.Node `toml:",omitempty"`
 
+	// BootstrapNodesV51 are used to establish connectivity
+	// with the rest of the network using the v5.1 discovery
+	// protocol.
+	BootstrapNodesV51 []*enode.Node `toml:",omitempty"`
+
 	// Static nodes are used as pre-configured connections which are always
 	// maintained and re-connected on disconnects.
 	StaticNodes []*enode.Node
@@ -147,6 +156,10 @@ type Config struct {
 	// is used to dial outbound peer connections.
 	Dialer NodeDialer `toml:"-"`
 
+	// Transport carries RLPx connections. If nil, plain TCP is used.
+	// The transport also dials outbound connections unless Dialer is set.
+	Transport NetTransport `toml:"-"`
+
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
@@ -154,6 +167,10 @@ type Config struct {
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
 
+	// If Recorder is set, all protocol messages sent to or received
+	// from peers are passed to it.
+	Recorder MsgRecorder `toml:"-"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -167,7 +184,7 @@ type Server struct {
 	// the whole protocol stack.
 	newTransport func(net.Conn) transport
 	newPeerHook  func(*Peer)
-	listenFunc   func(network, addr string) (net.Listener, error)
+	listenFunc   func(addr string) (net.Listener, error)
 
 	lock    sync.Mutex // protects running
 	running bool
@@ -182,6 +199,7 @@ type Server struct {
 	localnode *enode.LocalNode
 	ntab      *discover.UDPv4
 	DiscV5    *discv5.Network
+	discV51   *discover.UDPv5
 	discmix   *enode.FairMix
 
 	staticNodeResolver nodeResolver
@@ -449,11 +467,14 @@ func (srv *Server) Start() (err error) {
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
 	}
+	if srv.Transport == nil {
+		srv.Transport = NewTCPTransport()
+	}
 	if srv.listenFunc == nil {
-		srv.listenFunc = net.Listen
+		srv.listenFunc = srv.Transport.Listen
 	}
 	if srv.Dialer == nil {
-		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+		srv.Dialer = srv.Transport
 	}
 	srv.quit = make(chan struct{})
 	srv.delpeer = make(chan peerDrop)
@@ -480,6 +501,7 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.localnode.ID(), dynPeers, &srv.Config)
+	dialer.score = srv.nodeScore
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	return nil
@@ -542,9 +564,12 @@ func (srv *Server) setupDiscovery() error {
 	}
 
 	// Don't listen on UDP endpoint if DHT is disabled.
-	if srv.NoDiscovery && !srv.DiscoveryV5 {
+	if srv.NoDiscovery && !srv.DiscoveryV5 && !srv.DiscoveryV51 {
 		return nil
 	}
+	if srv.DiscoveryV5 && srv.DiscoveryV51 {
+		return errors.New("DiscoveryV5 and DiscoveryV51 can't be enabled at the same time")
+	}
 
 	addr, err := net.ResolveUDPAddr("udp", srv.ListenAddr)
 	if err != nil {
@@ -567,7 +592,7 @@ func (srv *Server) setupDiscovery() error {
 	var unhandled chan discover.ReadPacket
 	var sconn *sharedUDPConn
 	if !srv.NoDiscovery {
-		if srv.DiscoveryV5 {
+		if srv.DiscoveryV5 || srv.DiscoveryV51 {
 			unhandled = make(chan discover.ReadPacket, 100)
 			sconn = &sharedUDPConn{conn, unhandled}
 		}
@@ -604,12 +629,37 @@ func (srv *Server) setupDiscovery() error {
 		}
 		srv.DiscV5 = ntab
 	}
+
+	// Discovery V5.1
+	if srv.DiscoveryV51 {
+		cfg := discover.Config{
+			PrivateKey:  srv.PrivateKey,
+			NetRestrict: srv.NetRestrict,
+			Bootnodes:   srv.BootstrapNodesV51,
+			Log:         srv.log,
+		}
+		var ntab *discover.UDPv5
+		var err error
+		if sconn != nil {
+			ntab, err = discover.ListenV5(sconn, srv.localnode, cfg)
+		} else {
+			ntab, err = discover.ListenV5(conn, srv.localnode, cfg)
+		}
+		if err != nil {
+			return err
+		}
+		srv.discV51 = ntab
+		srv.discmix.AddSource(ntab.RandomNodes())
+		if srv.staticNodeResolver == nil {
+			srv.staticNodeResolver = ntab
+		}
+	}
 	return nil
 }
 
 func (srv *Server) setupListening() error {
 	// Launch the listener.
-	listener, err := srv.listenFunc("tcp", srv.ListenAddr)
+	listener, err := srv.listenFunc(srv.ListenAddr)
 	if err != nil {
 		return err
 	}
@@ -617,8 +667,10 @@ func (srv *Server) setupListening() error {
 	srv.ListenAddr = listener.Addr().String()
 
 	// Update the local node record and map the TCP listening port if NAT is configured.
+	for _, e := range srv.Transport.Endpoint(listener) {
+		srv.localnode.Set(e)
+	}
 	if tcp, ok := listener.Addr().(*net.TCPAddr); ok {
-		srv.localnode.Set(enr.TCP(tcp.Port))
 		if !tcp.IP.IsLoopback() && srv.NAT != nil {
 			srv.loopWG.Add(1)
 			go func() {
@@ -767,11 +819,13 @@ running:
 			if err == nil {
 				// The handshakes are done and it passed all checks.
 				p := newPeer(srv.log, c, srv.Protocols)
+				srv.loadScore(p)
 				// If message events are enabled, pass the peerFeed
 				// to the peer
 				if srv.EnableMsgEvents {
 					p.events = &srv.peerFeed
 				}
+				p.recorder = srv.Recorder
 				name := truncateName(c.name)
 				p.log.Debug("Adding p2p peer", "addr", p.RemoteAddr(), "peers", len(peers)+1, "name", name)
 				go srv.runPeer(p)
@@ -802,6 +856,9 @@ running:
 	if srv.ntab != nil {
 		srv.ntab.Close()
 	}
+	if srv.discV51 != nil {
+		srv.discV51.Close()
+	}
 	if srv.DiscV5 != nil {
 		srv.DiscV5.Close()
 	}
@@ -820,18 +877,29 @@ running:
 }
 
 func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
-	switch {
-	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
-		return DiscTooManyPeers
-	case !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
-		return DiscTooManyPeers
-	case peers[c.node.ID()] != nil:
+	if peers[c.node.ID()] != nil {
 		return DiscAlreadyConnected
-	case c.node.ID() == srv.localnode.ID():
+	}
+	if c.node.ID() == srv.localnode.ID() {
 		return DiscSelf
-	default:
-		return nil
 	}
+	// Peers already being evicted don't count against the limits
+	count := len(peers)
+	for _, p := range peers {
+		if p.evicted {
+			count--
+			if p.Inbound() {
+				inboundCount--
+			}
+		}
+	}
+	inboundFull := !c.is(trustedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns()
+	if inboundFull || (!c.is(trustedConn|staticDialedConn) && count >= srv.MaxPeers) {
+		if !srv.evictPeer(peers, c, inboundFull) {
+			return DiscTooManyPeers
+		}
+	}
+	return nil
 }
 
 func (srv *Server) addPeerChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
@@ -863,7 +931,7 @@ func (srv *Server) maxDialedConns() int {
 // inbound connections.
 func (srv *Server) listenLoop() {
 	defer srv.loopWG.Done()
-	srv.log.Debug("TCP listener up", "addr", srv.listener.Addr())
+	srv.log.Debug("RLPx listener up", "addr", srv.listener.Addr())
 
 	tokens := defaultMaxPendingPeers
 	if srv.MaxPendingPeers > 0 {
@@ -1053,6 +1121,7 @@ func (srv *Server) runPeer(p *Peer) {
 
 	// run the protocol
 	remoteRequested, err := p.run()
+	srv.storeScore(p)
 
 	// broadcast peer drop
 	srv.peerFeed.Send(&PeerEvent{
@@ -1097,6 +1166,12 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = node.UDP()
 	info.Ports.Listener = node.TCP()
+	if srv.listener != nil {
+		// Report the actual port, the record lacks "tcp" on other transports
+		if tcp, ok := srv.listener.Addr().(*net.TCPAddr); ok {
+			info.Ports.Listener = tcp.Port
+		}
+	}
 	info.ENR = node.String()
 
 	// Gather all the running protocol infos (only once per protocol type)
@@ -646,9 +646,9 @@ func TestServerInboundThrottle(t *testing.T) {
 			newTransportCalled <- struct{}{}
 			return newRLPX(fd)
 		},
-		listenFunc: func(network, laddr string) (net.Listener, error) {
+		listenFunc: func(laddr string) (net.Listener, error) {
 			fakeAddr := &net.TCPAddr{IP: net.IP{95, 33, 21, 2}, Port: 4444}
-			return listenFakeAddr(network, laddr, fakeAddr)
+			return listenFakeAddr("tcp", laddr, fakeAddr)
 		},
 	}
 	if err := srv.Start(); err != nil {
@@ -724,3 +724,52 @@ func (l *fakeAddrListener) Accept() (net.Conn, error) {
 func (c *fakeAddrConn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
+
+// This test checks that discovery v5.1 can run alongside v4 on the shared UDP socket.
+func TestServerDiscoveryV51(t *testing.T) {
+	newServer := func(bootnodes []*enode.Node) *Server {
+		srv := &Server{
+			Config: Config{
+				PrivateKey:        newkey(),
+				MaxPeers:          10,
+				ListenAddr:        "127.0.0.1:0",
+				DiscoveryV51:      true,
+				BootstrapNodesV51: bootnodes,
+				Logger:            testlog.Logger(t, log.LvlTrace),
+			},
+		}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("could not start: %v", err)
+		}
+		return srv
+	}
+	srv1 := newServer(nil)
+	defer srv1.Stop()
+	srv2 := newServer([]*enode.Node{srv1.Self()})
+	defer srv2.Stop()
+
+	if srv1.ntab == nil || srv1.discV51 == nil {
+		t.Fatal("discovery v4 and v5.1 should both be running")
+	}
+	if err := srv2.discV51.Ping(srv1.Self()); err != nil {
+		t.Fatal("v5.1 ping over shared socket failed:", err)
+	}
+	if err := srv2.ntab.Ping(srv1.Self()); err != nil {
+		t.Fatal("v4 ping over shared socket failed:", err)
+	}
+
+	// Running both v5 protocols at once is not supported.
+	srv3 := &Server{
+		Config: Config{
+			PrivateKey:   newkey(),
+			ListenAddr:   "127.0.0.1:0",
+			DiscoveryV5:  true,
+			DiscoveryV51: true,
+			Logger:       testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv3.Start(); err == nil {
+		srv3.Stop()
+		t.Fatal("expected error when enabling DiscoveryV5 and DiscoveryV51")
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtExpiryTimeout is the maximum allowed difference between the issuance time
+// of a token and the local clock.
+const jwtExpiryTimeout = 60 * time.Second
+
+var (
+	errMissingToken     = errors.New("missing token")
+	errMalformedToken   = errors.New("malformed token")
+	errUnsupportedAlg   = errors.New("unsupported signing algorithm")
+	errInvalidSignature = errors.New("invalid token signature")
+	errStaleToken       = errors.New("stale token")
+	errFutureToken      = errors.New("token issued in the future")
+	errExpiredToken     = errors.New("token is expired")
+	errMissingIssuedAt  = errors.New("missing issued-at claim")
+)
+
+// jwtHeader is the only JOSE header accepted and generated: HS256 signed JWTs.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// jwtClaims are the registered claims inspected when validating a token.
+type jwtClaims struct {
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+}
+
+// jwtSubjectKey is the context key of the subject of an authenticated request.
+type jwtSubjectKey struct{}
+
+// HTTPAuth is a function that decorates the headers of outgoing HTTP requests,
+// including the handshake of websocket connections, with credentials.
+type HTTPAuth func(h http.Header) error
+
+// NewJWTAuth creates an HTTPAuth which attaches a freshly issued HS256 token,
+// signed with the given shared secret, to every request.
+func NewJWTAuth(secret []byte) HTTPAuth {
+	return NewJWTSubjectAuth(secret, "")
+}
+
+// NewJWTSubjectAuth is like NewJWTAuth, but the tokens identify the client by
+// the given subject claim. Servers apply rate limits per subject instead of per
+// client IP to requests carrying a subject.
+func NewJWTSubjectAuth(secret []byte, subject string) HTTPAuth {
+	return func(h http.Header) error {
+		token, err := newJWTToken(secret, jwtClaims{IssuedAt: time.Now().Unix(), Subject: subject})
+		if err != nil {
+			return err
+		}
+		h.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// newJWTToken creates a HS256 signed token with the given claims.
+func newJWTToken(secret []byte, c jwtClaims) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signing + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(secret, signing)), nil
+}
+
+// verifyJWTToken checks that the token is a HS256 token signed with the given
+// secret, issued within jwtExpiryTimeout of now and not yet expired. It returns
+// the claims of valid tokens.
+func verifyJWTToken(secret []byte, token string, now time.Time) (jwtClaims, error) {
+	var claims jwtClaims
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errMalformedToken
+	}
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return claims, err
+	}
+	if header.Alg != "HS256" {
+		return claims, errUnsupportedAlg
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errMalformedToken
+	}
+	if !hmac.Equal(signature, jwtSignature(secret, parts[0]+"."+parts[1])) {
+		return claims, errInvalidSignature
+	}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return claims, err
+	}
+	if claims.IssuedAt == 0 {
+		return claims, errMissingIssuedAt
+	}
+	issued := time.Unix(claims.IssuedAt, 0)
+	switch {
+	case issued.Before(now.Add(-jwtExpiryTimeout)):
+		return claims, errStaleToken
+	case issued.After(now.Add(jwtExpiryTimeout)):
+		return claims, errFutureToken
+	case claims.ExpiresAt != 0 && now.After(time.Unix(claims.ExpiresAt, 0)):
+		return claims, errExpiredToken
+	}
+	return claims, nil
+}
+
+// jwtSubject returns the subject of the token which authenticated the request
+// of the given context, or the empty string if it has none.
+func jwtSubject(ctx context.Context) string {
+	sub, _ := ctx.Value(jwtSubjectKey{}).(string)
+	return sub
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedToken
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return errMalformedToken
+	}
+	return nil
+}
+
+// jwtSignature calculates the HMAC-SHA256 of the signing input.
+func jwtSignature(secret []byte, signing string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signing))
+	return mac.Sum(nil)
+}
+
+// jwtHandler is a handler which authenticates incoming requests by validating
+// the bearer token in their Authorization header against a shared secret.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// newJWTHandler wraps next with JWT authentication. Authentication is disabled
+// if no secret is given.
+func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+	if len(secret) == 0 {
+		return next
+	}
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP validates the request token and forwards it if valid, implements http.Handler
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, errMissingToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifyJWTToken(h.secret, strings.TrimPrefix(auth, "Bearer "), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if claims.Subject != "" {
+		r = r.WithContext(context.WithValue(r.Context(), jwtSubjectKey{}, claims.Subject))
+	}
+	h.next.ServeHTTP(w, r)
+}
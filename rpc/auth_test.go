@@ -0,0 +1,123 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTTokenVerification(t *testing.T) {
+	var (
+		secret = []byte("0123456789abcdef0123456789abcdef")
+		now    = time.Unix(1500000000, 0)
+	)
+	sign := func(header, claims string) string {
+		signing := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+		return signing + "." + base64.RawURLEncoding.EncodeToString(jwtSignature(secret, signing))
+	}
+	valid, _ := newJWTToken(secret, jwtClaims{IssuedAt: now.Unix()})
+	stale, _ := newJWTToken(secret, jwtClaims{IssuedAt: now.Add(-2 * jwtExpiryTimeout).Unix()})
+	future, _ := newJWTToken(secret, jwtClaims{IssuedAt: now.Add(2 * jwtExpiryTimeout).Unix()})
+	forged, _ := newJWTToken([]byte("wrong secret"), jwtClaims{IssuedAt: now.Unix()})
+
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{valid, nil},
+		{stale, errStaleToken},
+		{future, errFutureToken},
+		{forged, errInvalidSignature},
+		{"", errMalformedToken},
+		{"a.b", errMalformedToken},
+		{sign(`{"alg":"none"}`, `{"iat":1500000000}`), errUnsupportedAlg},
+		{sign(`{"alg":"HS256"}`, `{}`), errMissingIssuedAt},
+		{sign(`{"alg":"HS256"}`, `{"iat":1500000000,"exp":1499999999}`), errExpiredToken},
+		{sign(`{"alg":"HS256"}`, `{"iat":1500000000,"exp":1500000001}`), nil},
+	}
+	for i, tt := range tests {
+		if _, err := verifyJWTToken(secret, tt.token, now); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+func TestHTTPJWTAuth(t *testing.T) {
+	var (
+		secret  = []byte("0123456789abcdef0123456789abcdef")
+		srv     = newTestServer()
+		httpsrv = httptest.NewServer(newJWTHandler(secret, srv))
+	)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	// Unauthenticated and wrongly authenticated requests must be rejected
+	for _, auth := range []HTTPAuth{nil, NewJWTAuth([]byte("wrong secret"))} {
+		client, err := DialHTTPWithAuth(httpsrv.URL, new(http.Client), auth)
+		if err != nil {
+			t.Fatalf("can't dial: %v", err)
+		}
+		var result Result
+		if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("expected unauthorized error, got %v", err)
+		}
+		client.Close()
+	}
+	// Requests authenticated with the shared secret must go through
+	client, err := DialHTTPWithAuth(httpsrv.URL, new(http.Client), NewJWTAuth(secret))
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+}
+
+func TestWebsocketJWTAuth(t *testing.T) {
+	var (
+		secret  = []byte("0123456789abcdef0123456789abcdef")
+		srv     = newTestServer()
+		httpsrv = httptest.NewServer(newJWTHandler(secret, srv.WebsocketHandler([]string{"*"})))
+		wsURL   = "ws:" + strings.TrimPrefix(httpsrv.URL, "http:")
+	)
+	defer srv.Stop()
+	defer httpsrv.Close()
+
+	if client, err := DialWebsocket(context.Background(), wsURL, ""); err == nil {
+		client.Close()
+		t.Fatal("no error for unauthenticated handshake")
+	}
+	client, err := DialWebsocketWithAuth(context.Background(), wsURL, "", NewJWTAuth(secret))
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("authenticated call failed: %v", err)
+	}
+}
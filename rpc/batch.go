@@ -0,0 +1,149 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Batch is a builder for batch requests. Calls are added one by one, each of them
+// returning a future which resolves once the response for the call has arrived.
+//
+// Batches larger than the configured limit are split into multiple requests,
+// which are sent one after the other. The futures of each request are resolved as
+// soon as its responses are decoded, without waiting for the rest of the batch.
+type Batch struct {
+	client  *Client
+	limit   int
+	futures []*BatchFuture
+}
+
+// BatchFuture is the pending result of a call added to a Batch.
+type BatchFuture struct {
+	elem   BatchElem
+	result interface{}     // result pointer given to Add, nil if ignored
+	raw    json.RawMessage // undecoded result
+	done   chan struct{}
+
+	decode sync.Once
+	err    error // error of the call or of decoding its result
+}
+
+// NewBatch creates an empty batch request builder.
+func (c *Client) NewBatch() *Batch {
+	return &Batch{client: c}
+}
+
+// SetLimit sets the maximum number of calls sent in a single request. Larger
+// batches are split into multiple requests. Zero means unlimited.
+func (b *Batch) SetLimit(limit int) *Batch {
+	b.limit = limit
+	return b
+}
+
+// Add adds a call to the batch. The result must be a pointer so that package json
+// can unmarshal into it, or nil if the result should be ignored. The response is
+// decoded into result by the Result and Wait methods of the returned future.
+func (b *Batch) Add(result interface{}, method string, args ...interface{}) *BatchFuture {
+	f := &BatchFuture{result: result, done: make(chan struct{})}
+	f.elem = BatchElem{Method: method, Args: args, Result: &f.raw}
+	b.futures = append(b.futures, f)
+	return f
+}
+
+// Len returns the number of calls which have not been sent yet.
+func (b *Batch) Len() int {
+	return len(b.futures)
+}
+
+// Execute sends all calls added since the last execution and waits for their
+// responses. The builder may be reused afterwards.
+//
+// Like BatchCallContext, Execute only returns errors that have occurred while
+// sending the requests. Errors specific to a call are reported by its future. If
+// a request fails, the futures of all calls not yet answered resolve with the
+// same error.
+func (b *Batch) Execute(ctx context.Context) error {
+	futures := b.futures
+	b.futures = nil
+
+	limit := b.limit
+	if limit <= 0 {
+		limit = len(futures)
+	}
+	for len(futures) > 0 {
+		n := limit
+		if n > len(futures) {
+			n = len(futures)
+		}
+		elems := make([]BatchElem, n)
+		for i, f := range futures[:n] {
+			elems[i] = f.elem
+		}
+		if err := b.client.BatchCallContext(ctx, elems); err != nil {
+			for _, f := range futures {
+				f.resolve(err)
+			}
+			return err
+		}
+		for i, f := range futures[:n] {
+			f.resolve(elems[i].Error)
+		}
+		futures = futures[n:]
+	}
+	return nil
+}
+
+// resolve sets the outcome of the call and releases all waiters.
+func (f *BatchFuture) resolve(err error) {
+	f.elem.Error = err
+	close(f.done)
+}
+
+// Done returns a channel which is closed when the call has completed.
+func (f *BatchFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the call has completed or the context is canceled. Once the
+// call has completed, it behaves like Result.
+func (f *BatchFuture) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.Result()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Result blocks until the call has completed and unmarshals its result into the
+// pointer given to Add. It returns the error of the call, or the error of
+// unmarshaling the result.
+func (f *BatchFuture) Result() error {
+	<-f.done
+	f.decode.Do(func() {
+		switch {
+		case f.elem.Error != nil:
+			f.err = f.elem.Error
+		case f.result != nil && len(f.raw) > 0:
+			f.err = json.Unmarshal(f.raw, f.result)
+		}
+	})
+	return f.err
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestBatchSplitting(t *testing.T) {
+	// The server rejects batches above two elements, so the batch must be split.
+	server := newTestServer()
+	server.SetPolicy(&Policy{MaxBatchSize: 2})
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	batch := client.NewBatch().SetLimit(2)
+	var (
+		results = make([]Result, 5)
+		futures = make([]*BatchFuture, len(results))
+	)
+	for i := range results {
+		futures[i] = batch.Add(&results[i], "test_echo", "hello", i, &Args{"world"})
+	}
+	failed := batch.Add(nil, "no_such_method")
+	mistyped := batch.Add(new(int), "test_echo", "hello", 1, &Args{"world"})
+	if batch.Len() != 7 {
+		t.Fatalf("wrong batch length: got %d, want 7", batch.Len())
+	}
+	if err := batch.Execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if batch.Len() != 0 {
+		t.Fatalf("batch not reset after execution")
+	}
+	for i, f := range futures {
+		if err := f.Result(); err != nil {
+			t.Fatalf("call %d failed: %v", i, err)
+		}
+		want := Result{"hello", i, &Args{"world"}}
+		if !reflect.DeepEqual(results[i], want) {
+			t.Errorf("call %d: wrong result %+v, want %+v", i, results[i], want)
+		}
+	}
+	if err := failed.Wait(context.Background()); err == nil {
+		t.Error("expected error for unknown method")
+	}
+	if err := mistyped.Result(); err == nil {
+		t.Error("expected error for result of wrong type")
+	}
+}
+
+func TestBatchFailure(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	client.Close()
+
+	batch := client.NewBatch()
+	f := batch.Add(nil, "test_echo", "hello", 1, &Args{"world"})
+	err := batch.Execute(context.Background())
+	if err == nil {
+		t.Fatal("expected error on closed client")
+	}
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("future not resolved after failed request")
+	}
+	if ferr := f.Wait(context.Background()); ferr != err {
+		t.Errorf("wrong future error: got %v, want %v", ferr, err)
+	}
+}
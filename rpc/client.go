@@ -77,6 +77,8 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	policy   *policyEnforcer // restrictions on calls served to the remote side
+	slowLog  *slowCallLog    // slow call logging of calls served to the remote side
 
 	idCounter uint32
 
@@ -111,7 +113,7 @@ type clientConn struct {
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.policy, c.slowLog)
 	return &clientConn{conn, handler}
 }
 
@@ -197,17 +199,19 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), nil, nil)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, policy *policyEnforcer, slowLog *slowCallLog) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		policy:      policy,
+		slowLog:     slowLog,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
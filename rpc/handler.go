@@ -61,6 +61,9 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	policy         *policyEnforcer // access restrictions, nil if unrestricted
+	client         string          // client identity for rate limiting
+	slowLog        *slowCallLog    // slow call logging settings, nil if disabled
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
@@ -71,7 +74,7 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, policy *policyEnforcer, slowLog *slowCallLog) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -84,6 +87,9 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		policy:         policy,
+		slowLog:        slowLog,
+		client:         clientKey(connCtx, conn),
 	}
 	if conn.RemoteAddr() != "" {
 		h.log = h.log.New("conn", conn.RemoteAddr())
@@ -101,6 +107,12 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if h.policy.batchTooLarge(len(msgs)) {
+		h.startCallProc(func(cp *callProc) {
+			h.conn.Write(cp.ctx, errorMessage(&invalidRequestError{"batch too large"}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -296,11 +308,23 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		elapsed := time.Since(start)
 		if resp.Error != nil {
-			h.log.Warn("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start), "err", resp.Error.Message)
+			h.log.Warn("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", elapsed, "err", resp.Error.Message)
 		} else {
-			h.log.Debug("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start))
+			h.log.Debug("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", elapsed)
+		}
+		if h.slowLog.isSlow(elapsed) {
+			h.log.Warn("Served slow "+msg.Method, "reqid", idForLog{msg.ID}, "t", elapsed)
+			if h.slowLog.logParams(msg.Method) {
+				params := string(msg.Params)
+				if len(params) > maxLoggedParamsSize {
+					params = params[:maxLoggedParamsSize] + "..."
+				}
+				h.log.Debug("Slow call parameters", "method", msg.Method, "reqid", idForLog{msg.ID}, "params", params)
+			}
 		}
+		updateServeMetrics(msg.Method, h.isKnownMethod(msg), resp.Error != nil, elapsed)
 		return resp
 	case msg.hasValidID():
 		return msg.errorResponse(&invalidRequestError{"invalid request"})
@@ -309,8 +333,22 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
+// isKnownMethod checks whether the method of the message is served by the handler.
+func (h *handler) isKnownMethod(msg *jsonrpcMessage) bool {
+	if msg.isSubscribe() || msg.isUnsubscribe() {
+		return h.reg.hasSubscriptions(msg.namespace())
+	}
+	return h.reg.callback(msg.Method) != nil
+}
+
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if !h.policy.allowed(msg.Method) {
+		return msg.errorResponse(&accessDeniedError{msg.Method})
+	}
+	if !h.policy.limit(h.client) {
+		return msg.errorResponse(&rateLimitError{})
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -366,11 +404,44 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 
 // runMethod runs the Go callback for an RPC method.
 func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
+	// Subscriptions return immediately and must not be abandoned, as their
+	// notifier is activated only after the callback has finished.
+	if timeout := h.policy.timeout(msg.Method); timeout > 0 && !msg.isSubscribe() {
+		return h.runMethodWithTimeout(ctx, msg, callb, args, timeout)
+	}
+	return h.callMethod(ctx, msg, callb, args)
+}
+
+// runMethodWithTimeout runs the Go callback for an RPC method, answering with an
+// error if it doesn't finish within the given time. The context passed to the
+// callback is canceled on timeout, but the callback is not waited for.
+func (h *handler) runMethodWithTimeout(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value, timeout time.Duration) *jsonrpcMessage {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan *jsonrpcMessage, 1)
+	go func() {
+		done <- h.callMethod(ctx, msg, callb, args)
+	}()
+	select {
+	case resp := <-done:
+		return resp
+	case <-ctx.Done():
+		return msg.errorResponse(&timeoutError{msg.Method, timeout})
+	}
+}
+
+// callMethod invokes the Go callback for an RPC method and encodes its result.
+func (h *handler) callMethod(ctx context.Context, msg *jsonrpcMessage, callb *callback, args []reflect.Value) *jsonrpcMessage {
 	result, err := callb.call(ctx, msg.Method, args)
 	if err != nil {
 		return msg.errorResponse(err)
 	}
-	return msg.response(result)
+	resp := msg.response(result)
+	if h.policy.responseTooLarge(len(resp.Result)) {
+		return msg.errorResponse(&responseTooLargeError{len(resp.Result), h.policy.policy.MaxResponseSize})
+	}
+	return resp
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
@@ -46,6 +46,7 @@ var acceptedContentTypes = []string{contentType, "application/json-rpc", "applic
 type httpConn struct {
 	client    *http.Client
 	req       *http.Request
+	auth      HTTPAuth
 	closeOnce sync.Once
 	closed    chan interface{}
 }
@@ -107,6 +108,13 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return DialHTTPWithAuth(endpoint, client, nil)
+}
+
+// DialHTTPWithAuth creates a new RPC client that connects to an RPC server over HTTP
+// using the provided HTTP Client, decorating every request with the credentials
+// provided by auth.
+func DialHTTPWithAuth(endpoint string, client *http.Client, auth HTTPAuth) (*Client, error) {
 	req, err := http.NewRequest(http.MethodPost, endpoint, nil)
 	if err != nil {
 		return nil, err
@@ -116,7 +124,7 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 
 	initctx := context.Background()
 	return newClient(initctx, func(context.Context) (ServerCodec, error) {
-		return &httpConn{client: client, req: req, closed: make(chan interface{})}, nil
+		return &httpConn{client: client, req: req, auth: auth, closed: make(chan interface{})}, nil
 	})
 }
 
@@ -175,6 +183,18 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	req.ContentLength = int64(len(body))
 
+	if hc.auth != nil {
+		// The header is shared between concurrent requests, copy before modifying
+		header := make(http.Header, len(hc.req.Header)+1)
+		for key, values := range hc.req.Header {
+			header[key] = values
+		}
+		if err := hc.auth(header); err != nil {
+			return nil, err
+		}
+		req.Header = header
+	}
+
 	resp, err := hc.client.Do(req)
 	if err != nil {
 		return nil, err
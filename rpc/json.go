@@ -164,13 +164,14 @@ func (c connWithRemoteAddr) RemoteAddr() string { return c.addr }
 // jsonCodec reads and writes JSON-RPC messages to the underlying connection. It also has
 // support for parsing arguments and serializing (result) objects.
 type jsonCodec struct {
-	remoteAddr string
-	closer     sync.Once                 // close closed channel once
-	closed     chan interface{}          // closed on Close
-	decode     func(v interface{}) error // decoder to allow multiple transports
-	encMu      sync.Mutex                // guards the encoder
-	encode     func(v interface{}) error // encoder to allow multiple transports
-	conn       deadlineCloser
+	remoteAddr  string
+	authSubject string                    // subject of the token authenticating the connection
+	closer      sync.Once                 // close closed channel once
+	closed      chan interface{}          // closed on Close
+	decode      func(v interface{}) error // decoder to allow multiple transports
+	encMu       sync.Mutex                // guards the encoder
+	encode      func(v interface{}) error // encoder to allow multiple transports
+	conn        deadlineCloser
 }
 
 func newCodec(conn deadlineCloser, encode, decode func(v interface{}) error) ServerCodec {
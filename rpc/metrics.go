@@ -0,0 +1,99 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the meters and timers used by the RPC server.
+
+package rpc
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/metrics"
+)
+
+const (
+	MetricsRequests = "rpc/requests" // Name prefix for the registered request meters
+	MetricsErrors   = "rpc/errors"   // Name prefix for the registered failed request meters
+	MetricsDuration = "rpc/duration" // Name prefix for the registered serving time timers
+
+	// maxLoggedParamsSize is the number of bytes of the parameters included in
+	// slow call logs.
+	maxLoggedParamsSize = 1024
+)
+
+// sensitiveNamespaces are the namespaces whose call parameters may contain
+// passphrases or keys. Their parameters are never logged.
+var sensitiveNamespaces = map[string]bool{
+	"personal": true,
+	"account":  true,
+	"clef":     true,
+}
+
+var (
+	requestMeter = metrics.NewRegisteredMeter(MetricsRequests+"/all", nil) // Meter counting all served calls
+	errorMeter   = metrics.NewRegisteredMeter(MetricsErrors+"/all", nil)   // Meter counting all failed calls
+	servingTimer = metrics.NewRegisteredTimer(MetricsDuration+"/all", nil) // Timer measuring the serving time of all calls
+)
+
+// slowCallLog configures the logging of slow calls by a server. It may be
+// changed while the server is running.
+type slowCallLog struct {
+	threshold int64 // Serving time above which calls are logged, 0 if disabled (atomic)
+	params    int32 // Whether call parameters are logged, 1 if enabled (atomic)
+}
+
+// isSlow checks whether a call served within the given time is to be logged
+// as a slow call.
+func (l *slowCallLog) isSlow(elapsed time.Duration) bool {
+	if l == nil {
+		return false
+	}
+	threshold := time.Duration(atomic.LoadInt64(&l.threshold))
+	return threshold > 0 && elapsed >= threshold
+}
+
+// logParams checks whether the parameters of a slow call to the given method
+// may be logged.
+func (l *slowCallLog) logParams(method string) bool {
+	if l == nil || atomic.LoadInt32(&l.params) == 0 {
+		return false
+	}
+	return !sensitiveNamespaces[strings.SplitN(method, serviceMethodSeparator, 2)[0]]
+}
+
+// updateServeMetrics updates the RPC metrics with a call served within the given
+// time. To bound the number of metrics clients can create, the per-method ones
+// are only updated if the method is known.
+func updateServeMetrics(method string, known bool, failed bool, elapsed time.Duration) {
+	if !metrics.Enabled {
+		return
+	}
+	requestMeter.Mark(1)
+	servingTimer.Update(elapsed)
+	if failed {
+		errorMeter.Mark(1)
+	}
+	if !known {
+		return
+	}
+	metrics.GetOrRegisterMeter(MetricsRequests+"/"+method, nil).Mark(1)
+	metrics.GetOrRegisterTimer(MetricsDuration+"/"+method, nil).Update(elapsed)
+	if failed {
+		metrics.GetOrRegisterMeter(MetricsErrors+"/"+method, nil).Mark(1)
+	}
+}
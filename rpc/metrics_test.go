@@ -0,0 +1,103 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/metrics"
+)
+
+// This test checks that per-method metrics are only created for known methods.
+// The aggregate meters are not checked as they are registered at init time, before
+// metrics could be enabled here.
+func TestServeMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var (
+		requests = metrics.GetOrRegisterMeter(MetricsRequests+"/test_echo", nil).Count()
+		errors   = metrics.GetOrRegisterMeter(MetricsErrors+"/test_echo", nil).Count()
+	)
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(&result, "test_echo", "hello"); err == nil {
+		t.Fatal("expected error for missing arguments")
+	}
+	if err := client.Call(nil, "test_unknownMethod"); err == nil {
+		t.Fatal("expected error for unknown method")
+	}
+	if n := metrics.GetOrRegisterMeter(MetricsRequests+"/test_echo", nil).Count() - requests; n != 2 {
+		t.Errorf("wrong method request count: got %d, want 2", n)
+	}
+	if n := metrics.GetOrRegisterMeter(MetricsErrors+"/test_echo", nil).Count() - errors; n != 1 {
+		t.Errorf("wrong method error count: got %d, want 1", n)
+	}
+	if n := metrics.GetOrRegisterTimer(MetricsDuration+"/test_echo", nil).Count(); n < 2 {
+		t.Errorf("wrong method timer count: got %d, want at least 2", n)
+	}
+	if metrics.DefaultRegistry.Get(MetricsRequests+"/test_unknownMethod") != nil {
+		t.Error("metric registered for unknown method")
+	}
+}
+
+func TestSlowCallThreshold(t *testing.T) {
+	server1, server2 := NewServer(), NewServer()
+	defer server1.Stop()
+	defer server2.Stop()
+
+	if server1.slowLog.isSlow(time.Hour) {
+		t.Error("slow call logged while disabled")
+	}
+	server1.SetSlowCallThreshold(time.Second)
+	if server1.slowLog.isSlow(500 * time.Millisecond) {
+		t.Error("fast call considered slow")
+	}
+	if !server1.slowLog.isSlow(time.Second) {
+		t.Error("slow call not detected")
+	}
+	if server2.slowLog.isSlow(time.Hour) {
+		t.Error("threshold leaked into other server")
+	}
+}
+
+func TestSlowCallParams(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+
+	if server.slowLog.logParams("eth_call") {
+		t.Error("params logged while disabled")
+	}
+	server.SetSlowCallParams(true)
+	if !server.slowLog.logParams("eth_call") {
+		t.Error("params not logged while enabled")
+	}
+	for _, method := range []string{"personal_unlockAccount", "personal_importRawKey", "account_signTransaction", "clef_listWallets"} {
+		if server.slowLog.logParams(method) {
+			t.Errorf("params of %s logged", method)
+		}
+	}
+}
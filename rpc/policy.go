@@ -0,0 +1,246 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// maxRateLimiters is the number of client rate limiters tracked before idle ones
+// are evicted.
+const maxRateLimiters = 4096
+
+// Policy restricts which requests a Server serves and how many resources they
+// may consume. The zero value imposes no restrictions.
+type Policy struct {
+	// Allow is the list of methods which may be called. Entries are either full
+	// method names (e.g. "eth_call") or namespace wildcards (e.g. "eth_*"). If
+	// the list is empty, all methods are allowed.
+	Allow []string `toml:",omitempty"`
+
+	// Deny is the list of methods which may never be called, using the same
+	// format as Allow. Denials take precedence over the allow list.
+	Deny []string `toml:",omitempty"`
+
+	// RateLimit is the number of requests per second a single client may
+	// issue, each element of a batch counting as one request. Clients are
+	// identified by the subject of their authentication token, or by their IP
+	// if they have none. Zero disables rate limiting.
+	RateLimit float64 `toml:",omitempty"`
+
+	// RateBurst is the number of requests a client may issue at once before the
+	// rate limit is applied. It defaults to the rate limit rounded up.
+	RateBurst int `toml:",omitempty"`
+
+	// MaxBatchSize is the maximum number of requests in a single batch. Zero
+	// means unlimited.
+	MaxBatchSize int `toml:",omitempty"`
+
+	// MaxResponseSize is the maximum size in bytes of the encoded result of a
+	// single call. Zero means unlimited.
+	MaxResponseSize int `toml:",omitempty"`
+
+	// MaxExecutionTime is the maximum time a method may run before the request
+	// is answered with an error. Zero means unlimited.
+	MaxExecutionTime time.Duration `toml:",omitempty"`
+
+	// MethodExecutionTimes overrides MaxExecutionTime for individual methods,
+	// keyed by the same format as Allow.
+	MethodExecutionTimes map[string]time.Duration `toml:",omitempty"`
+}
+
+// policyEnforcer is the compiled, shared form of a Policy.
+type policyEnforcer struct {
+	policy Policy
+	allow  methodSet
+	deny   methodSet
+
+	lock     sync.Mutex
+	limiters map[string]*clientLimiter // rate limiters keyed by client
+}
+
+// clientLimiter is the rate limiter of a single client.
+type clientLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// newPolicyEnforcer compiles the given policy, returning nil if it imposes no
+// restrictions.
+func newPolicyEnforcer(policy *Policy) *policyEnforcer {
+	if policy == nil || policy.isZero() {
+		return nil
+	}
+	return &policyEnforcer{
+		policy:   *policy,
+		allow:    newMethodSet(policy.Allow),
+		deny:     newMethodSet(policy.Deny),
+		limiters: make(map[string]*clientLimiter),
+	}
+}
+
+// isZero reports whether the policy imposes no restrictions.
+func (p *Policy) isZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && p.RateLimit <= 0 &&
+		p.MaxBatchSize == 0 && p.MaxResponseSize == 0 &&
+		p.MaxExecutionTime == 0 && len(p.MethodExecutionTimes) == 0
+}
+
+// clientKey identifies the client of a connection for rate limiting. Clients
+// authenticated by a token with a subject are keyed by the subject, so clients
+// sharing an IP address don't share a limit. All others are keyed by IP.
+func clientKey(ctx context.Context, conn jsonWriter) string {
+	if sub := jwtSubject(ctx); sub != "" {
+		return "sub:" + sub
+	}
+	if c, ok := conn.(*jsonCodec); ok && c.authSubject != "" {
+		return "sub:" + c.authSubject
+	}
+	remote := conn.RemoteAddr()
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	return "ip:" + remote
+}
+
+// allowed checks whether the method may be called according to the allow and
+// deny lists.
+func (p *policyEnforcer) allowed(method string) bool {
+	if p == nil {
+		return true
+	}
+	if p.deny.contains(method) {
+		return false
+	}
+	return len(p.policy.Allow) == 0 || p.allow.contains(method)
+}
+
+// limit reserves a request from the rate limit of the given client, returning
+// whether the request may be served.
+func (p *policyEnforcer) limit(client string) bool {
+	if p == nil || p.policy.RateLimit <= 0 {
+		return true
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	limiter := p.limiters[client]
+	if limiter == nil {
+		if len(p.limiters) >= maxRateLimiters {
+			p.evictLimiters(now)
+		}
+		limiter = &clientLimiter{Limiter: rate.NewLimiter(rate.Limit(p.policy.RateLimit), p.burst())}
+		p.limiters[client] = limiter
+	}
+	limiter.lastSeen = now
+	return limiter.AllowN(now, 1)
+}
+
+// burst returns the configured burst size, defaulting to the rate limit.
+func (p *policyEnforcer) burst() int {
+	if p.policy.RateBurst > 0 {
+		return p.policy.RateBurst
+	}
+	return int(math.Ceil(p.policy.RateLimit))
+}
+
+// evictLimiters drops the limiters of all clients which have been idle long
+// enough to refill their bucket, as recreating them is equivalent. If none are
+// idle, all limiters are dropped to bound memory use.
+func (p *policyEnforcer) evictLimiters(now time.Time) {
+	refill := time.Duration(float64(p.burst()) / p.policy.RateLimit * float64(time.Second))
+	for client, limiter := range p.limiters {
+		if now.Sub(limiter.lastSeen) >= refill {
+			delete(p.limiters, client)
+		}
+	}
+	if len(p.limiters) >= maxRateLimiters {
+		p.limiters = make(map[string]*clientLimiter)
+	}
+}
+
+// batchTooLarge checks whether a batch of the given size exceeds the limit.
+func (p *policyEnforcer) batchTooLarge(size int) bool {
+	return p != nil && p.policy.MaxBatchSize > 0 && size > p.policy.MaxBatchSize
+}
+
+// responseTooLarge checks whether an encoded result of the given size exceeds
+// the limit.
+func (p *policyEnforcer) responseTooLarge(size int) bool {
+	return p != nil && p.policy.MaxResponseSize > 0 && size > p.policy.MaxResponseSize
+}
+
+// timeout returns the maximum execution time of the given method, or zero if
+// it is unlimited.
+func (p *policyEnforcer) timeout(method string) time.Duration {
+	if p == nil {
+		return 0
+	}
+	if timeout, ok := lookupMethod(p.policy.MethodExecutionTimes, method); ok {
+		return timeout
+	}
+	return p.policy.MaxExecutionTime
+}
+
+// methodSet is a set of method names and namespace wildcards.
+type methodSet struct {
+	methods    map[string]struct{}
+	namespaces map[string]struct{}
+}
+
+func newMethodSet(entries []string) methodSet {
+	set := methodSet{
+		methods:    make(map[string]struct{}),
+		namespaces: make(map[string]struct{}),
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, serviceMethodSeparator+"*") {
+			set.namespaces[strings.TrimSuffix(entry, serviceMethodSeparator+"*")] = struct{}{}
+		} else {
+			set.methods[entry] = struct{}{}
+		}
+	}
+	return set
+}
+
+// contains checks whether the method is in the set, either by name or by its
+// namespace.
+func (s methodSet) contains(method string) bool {
+	if _, ok := s.methods[method]; ok {
+		return true
+	}
+	_, ok := s.namespaces[strings.SplitN(method, serviceMethodSeparator, 2)[0]]
+	return ok
+}
+
+// lookupMethod finds the value configured for the method in a map keyed by method
+// names or namespace wildcards, preferring exact matches.
+func lookupMethod(values map[string]time.Duration, method string) (time.Duration, bool) {
+	if value, ok := values[method]; ok {
+		return value, true
+	}
+	value, ok := values[strings.SplitN(method, serviceMethodSeparator, 2)[0]+serviceMethodSeparator+"*"]
+	return value, ok
+}
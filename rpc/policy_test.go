@@ -0,0 +1,177 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPolicyTestClient starts a test server restricted by the given policy and
+// returns an in-process client connected to it.
+func newPolicyTestClient(policy *Policy) (*Server, *Client) {
+	server := newTestServer()
+	server.SetPolicy(policy)
+	return server, DialInProc(server)
+}
+
+// checkErrorCode verifies that err is an RPC error with the given code.
+func checkErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected error with code %d, got nil", code)
+	}
+	rpcErr, ok := err.(Error)
+	if !ok || rpcErr.ErrorCode() != code {
+		t.Fatalf("expected error with code %d, got %v", code, err)
+	}
+}
+
+func TestPolicyAccessControl(t *testing.T) {
+	server, client := newPolicyTestClient(&Policy{
+		Allow: []string{"test_*", "nftest_subscribe"},
+		Deny:  []string{"test_sleep"},
+	})
+	defer server.Stop()
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "hello", 10, &Args{"world"}); err != nil {
+		t.Fatalf("allowed call failed: %v", err)
+	}
+	checkErrorCode(t, client.Call(nil, "test_sleep", 0), -32001)
+	checkErrorCode(t, client.Call(nil, "rpc_modules"), -32001)
+}
+
+func TestPolicyRateLimit(t *testing.T) {
+	server, client := newPolicyTestClient(&Policy{RateLimit: 0.001, RateBurst: 2})
+	defer server.Stop()
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d within burst failed: %v", i, err)
+		}
+	}
+	checkErrorCode(t, client.Call(nil, "test_noArgsRets"), -32005)
+}
+
+// This test checks that clients authenticated with different token subjects
+// have separate rate limits, even when connecting from the same IP.
+func TestPolicyRateLimitSubject(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	server := newTestServer()
+	server.SetPolicy(&Policy{RateLimit: 0.001, RateBurst: 1})
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(newJWTHandler(secret, server))
+	defer httpsrv.Close()
+	wssrv := httptest.NewServer(newJWTHandler(secret, server.WebsocketHandler([]string{"*"})))
+	defer wssrv.Close()
+
+	dial := func(url, subject string) *Client {
+		var (
+			client *Client
+			err    error
+			auth   = NewJWTSubjectAuth(secret, subject)
+		)
+		if strings.HasPrefix(url, "ws:") {
+			client, err = DialWebsocketWithAuth(context.Background(), url, "", auth)
+		} else {
+			client, err = DialHTTPWithAuth(url, new(http.Client), auth)
+		}
+		if err != nil {
+			t.Fatalf("can't dial %s: %v", url, err)
+		}
+		return client
+	}
+	wsURL := "ws:" + strings.TrimPrefix(wssrv.URL, "http:")
+	for _, url := range []string{httpsrv.URL, wsURL} {
+		alice, bob := dial(url, "alice-"+url), dial(url, "bob-"+url)
+		if err := alice.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("%s: first call of alice failed: %v", url, err)
+		}
+		if err := bob.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("%s: first call of bob failed: %v", url, err)
+		}
+		checkErrorCode(t, alice.Call(nil, "test_noArgsRets"), -32005)
+		alice.Close()
+		bob.Close()
+	}
+}
+
+func TestPolicyExecutionTime(t *testing.T) {
+	server, client := newPolicyTestClient(&Policy{
+		MaxExecutionTime:     time.Second,
+		MethodExecutionTimes: map[string]time.Duration{"test_sleep": 50 * time.Millisecond},
+	})
+	defer server.Stop()
+	defer client.Close()
+
+	checkErrorCode(t, client.Call(nil, "test_sleep", 200*time.Millisecond), -32002)
+	if err := client.Call(nil, "test_sleep", time.Millisecond); err != nil {
+		t.Fatalf("fast call failed: %v", err)
+	}
+}
+
+func TestPolicyResponseSize(t *testing.T) {
+	server, client := newPolicyTestClient(&Policy{MaxResponseSize: 100})
+	defer server.Stop()
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1, &Args{"y"}); err != nil {
+		t.Fatalf("small response failed: %v", err)
+	}
+	checkErrorCode(t, client.Call(&result, "test_echo", strings.Repeat("x", 100), 1, &Args{"y"}), -32003)
+}
+
+func TestPolicyBatchSize(t *testing.T) {
+	server := newTestServer()
+	server.SetPolicy(&Policy{MaxBatchSize: 2})
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer client.Close()
+
+	batch := []BatchElem{
+		{Method: "test_noArgsRets", Result: new(interface{})},
+		{Method: "test_noArgsRets", Result: new(interface{})},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch within limit failed: %v", err)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d failed: %v", i, elem.Error)
+		}
+	}
+	batch = append(batch, BatchElem{Method: "test_noArgsRets", Result: new(interface{})})
+	if err := client.BatchCall(batch); err == nil {
+		t.Fatal("oversized batch succeeded")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"io"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/Fantom-foundation/go-ethereum/log"
@@ -46,6 +47,8 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	policy   *policyEnforcer
+	slowLog  slowCallLog
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -66,6 +69,29 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// SetPolicy restricts the requests served according to the given policy. It must
+// be called before the server starts serving requests.
+func (s *Server) SetPolicy(policy *Policy) {
+	s.policy = newPolicyEnforcer(policy)
+}
+
+// SetSlowCallThreshold sets the serving time above which calls are logged as
+// slow. Zero disables logging slow calls.
+func (s *Server) SetSlowCallThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&s.slowLog.threshold, int64(threshold))
+}
+
+// SetSlowCallParams sets whether the parameters of slow calls are logged, at
+// debug level. Parameters of methods which may carry credentials, such as those
+// in the personal namespace, are never logged.
+func (s *Server) SetSlowCallParams(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&s.slowLog.params, v)
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -83,7 +109,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.policy, &s.slowLog)
 	<-codec.Closed()
 	c.Close()
 }
@@ -97,7 +123,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.policy, &s.slowLog)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -111,6 +111,13 @@ func (r *serviceRegistry) subscription(service, name string) *callback {
 	return r.services[service].subscriptions[name]
 }
 
+// hasSubscriptions checks whether the given service offers any subscriptions.
+func (r *serviceRegistry) hasSubscriptions(service string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.services[service].subscriptions) > 0
+}
+
 // suitableCallbacks iterates over the methods of the given type. It determines if a method
 // satisfies the criteria for a RPC callback or a subscription callback and adds it to the
 // collection of callbacks. See server documentation for a summary of these criteria.
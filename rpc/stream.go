@@ -0,0 +1,140 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var errNotArray = errors.New("result is not an array")
+
+// StreamCallContext performs a JSON-RPC call whose result is an array, invoking fn
+// with every element of the array in order. A null result is treated as an empty
+// array. If fn returns an error, decoding stops and the error is returned.
+//
+// Over HTTP the response is decoded while it is being read, so at most a single
+// element is held in memory at any time. Other transports deliver whole messages,
+// in which case the elements are still decoded one by one from the raw result.
+func (c *Client) StreamCallContext(ctx context.Context, fn func(json.RawMessage) error, method string, args ...interface{}) error {
+	if c.isHTTP {
+		return c.streamHTTP(ctx, fn, method, args...)
+	}
+	var result json.RawMessage
+	if err := c.CallContext(ctx, &result, method, args...); err != nil {
+		return err
+	}
+	return decodeArray(json.NewDecoder(bytes.NewReader(result)), fn)
+}
+
+// streamHTTP sends a call over HTTP and decodes the response incrementally.
+func (c *Client) streamHTTP(ctx context.Context, fn func(json.RawMessage) error, method string, args ...interface{}) error {
+	msg, err := c.newMessage(method, args...)
+	if err != nil {
+		return err
+	}
+	hc := c.writeConn.(*httpConn)
+	respBody, err := hc.doRequest(ctx, msg)
+	if respBody != nil {
+		defer respBody.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return decodeStreamResponse(json.NewDecoder(respBody), fn)
+}
+
+// decodeStreamResponse decodes a JSON-RPC response object, passing the elements of
+// its result to fn as soon as they are decoded.
+func decodeStreamResponse(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	var hasResult bool
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case "result":
+			if err := decodeArray(dec, fn); err != nil {
+				return err
+			}
+			hasResult = true
+		case "error":
+			resp := new(jsonError)
+			if err := dec.Decode(resp); err != nil {
+				return err
+			}
+			return resp
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return err
+	}
+	if !hasResult {
+		return ErrNoResult
+	}
+	return nil
+}
+
+// decodeArray decodes the next value of the decoder, which must be an array or
+// null, passing each element to fn.
+func decodeArray(dec *json.Decoder, fn func(json.RawMessage) error) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('[') {
+		return errNotArray
+	}
+	for dec.More() {
+		var elem json.RawMessage
+		if err := dec.Decode(&elem); err != nil {
+			return err
+		}
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token of the decoder, which must be the given
+// delimiter.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("invalid response: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamCall(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	httpclient, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpclient.Close()
+	inprocclient := DialInProc(server)
+	defer inprocclient.Close()
+
+	for name, client := range map[string]*Client{"http": httpclient, "inproc": inprocclient} {
+		var count int
+		err := client.StreamCallContext(context.Background(), func(elem json.RawMessage) error {
+			var s string
+			if err := json.Unmarshal(elem, &s); err != nil {
+				return err
+			}
+			if s != "x" {
+				t.Errorf("%s: wrong element %q", name, s)
+			}
+			count++
+			return nil
+		}, "test_repeat", "x", 1000)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if count != 1000 {
+			t.Errorf("%s: wrong element count: got %d, want 1000", name, count)
+		}
+
+		// Errors of the callback abort decoding.
+		stop := errors.New("stop")
+		count = 0
+		err = client.StreamCallContext(context.Background(), func(json.RawMessage) error {
+			if count++; count == 10 {
+				return stop
+			}
+			return nil
+		}, "test_repeat", "x", 1000)
+		if err != stop || count != 10 {
+			t.Errorf("%s: callback error not propagated: err %v, count %d", name, err, count)
+		}
+
+		// Server errors and non-array results are reported.
+		noop := func(json.RawMessage) error { return nil }
+		if err := client.StreamCallContext(context.Background(), noop, "test_repeat"); err == nil {
+			t.Errorf("%s: expected error for missing arguments", name)
+		} else if _, ok := err.(Error); !ok {
+			t.Errorf("%s: expected RPC error, got %v", name, err)
+		}
+		if err := client.StreamCallContext(context.Background(), noop, "test_echo", "x", 1, nil); err != errNotArray {
+			t.Errorf("%s: wrong error for non-array result: %v", name, err)
+		}
+	}
+}
@@ -73,6 +73,14 @@ func (s *testService) EchoWithCtx(ctx context.Context, str string, i int, args *
 	return Result{str, i, args}
 }
 
+func (s *testService) Repeat(str string, n int) []string {
+	result := make([]string, n)
+	for i := range result {
+		result[i] = str
+	}
+	return result
+}
+
 func (s *testService) Sleep(ctx context.Context, duration time.Duration) {
 	time.Sleep(duration)
 }
@@ -63,6 +63,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
+		codec.authSubject = jwtSubject(r.Context())
 		s.ServeCodec(codec, OptionMethodInvocation|OptionSubscriptions)
 	})
 }
@@ -130,6 +131,13 @@ func (e wsHandshakeError) Error() string {
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+	return DialWebsocketWithAuth(ctx, endpoint, origin, nil)
+}
+
+// DialWebsocketWithAuth creates a new RPC client that communicates with a JSON-RPC
+// server that is listening on the given endpoint, decorating the handshake of every
+// (re)connection with the credentials provided by auth.
+func DialWebsocketWithAuth(ctx context.Context, endpoint, origin string, auth HTTPAuth) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
@@ -140,7 +148,18 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 		WriteBufferPool: wsBufferPool,
 	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
-		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
+		dialHeader := header
+		if auth != nil {
+			// Credentials may be time bound, issue new ones for every connection
+			dialHeader = make(http.Header, len(header)+1)
+			for key, values := range header {
+				dialHeader[key] = values
+			}
+			if err := auth(dialHeader); err != nil {
+				return nil, err
+			}
+		}
+		conn, resp, err := dialer.DialContext(ctx, endpoint, dialHeader)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}
 			if resp != nil {
@@ -169,7 +188,9 @@ func wsClientHeaders(endpoint, origin string) (string, http.Header, error) {
 	return endpointURL.String(), header, nil
 }
 
-func newWebsocketCodec(conn *websocket.Conn) ServerCodec {
+func newWebsocketCodec(conn *websocket.Conn) *jsonCodec {
 	conn.SetReadLimit(maxRequestContentLength)
-	return newCodec(conn, conn.WriteJSON, conn.ReadJSON)
+	codec := newCodec(conn, conn.WriteJSON, conn.ReadJSON).(*jsonCodec)
+	codec.remoteAddr = conn.RemoteAddr().String()
+	return codec
 }
@@ -43,7 +43,7 @@ const (
 	// ExternalAPIVersion -- see extapi_changelog.md
 	ExternalAPIVersion = "6.0.0"
 	// InternalAPIVersion -- see intapi_changelog.md
-	InternalAPIVersion = "7.0.0"
+	InternalAPIVersion = "7.1.0"
 )
 
 // ExternalAPI defines the external API through which signing requests are made.
@@ -93,6 +93,15 @@ type UIClientAPI interface {
 	RegisterUIServer(api *UIServerAPI)
 }
 
+// SignTxObserver is an optional interface of UIs which need to learn whether an
+// approved transaction was eventually signed, e.g. to release the allowances
+// reserved while approving it.
+type SignTxObserver interface {
+	// OnSignTxDone is invoked with the approved request once signing finished,
+	// with a non-nil error if it failed.
+	OnSignTxDone(request *SignTxRequest, err error)
+}
+
 // Validator defines the methods required to validate a transaction against some
 // sanity defaults as well as any underlying 4byte method database.
 //
@@ -106,12 +115,24 @@ type Validator interface {
 	ValidateTransaction(selector *string, tx *SendTxArgs) (*ValidationMessages, error)
 }
 
+// Simulator defines the methods required to simulate a transaction before it's
+// presented for approval, showing its effects to the user and to rules.
+//
+// Use simulation.Simulator as an implementation. It is separated out of this
+// package as it needs the full blockchain implementation to execute transactions.
+type Simulator interface {
+	// SimulateTransaction executes the transaction on the current state of the
+	// chain, returning its outcome. An error means the simulation itself failed.
+	SimulateTransaction(ctx context.Context, tx *SendTxArgs) (*SimulationResult, error)
+}
+
 // SignerAPI defines the actual implementation of ExternalAPI
 type SignerAPI struct {
 	chainID     *big.Int
 	am          *accounts.Manager
 	UI          UIClientAPI
 	validator   Validator
+	simulator   Simulator
 	rejectMode  bool
 	credentials storage.Storage
 }
@@ -125,7 +146,9 @@ type Metadata struct {
 	Origin    string `json:"Origin"`
 }
 
-func StartClefAccountManager(ksLocation string, nousb, lightKDF bool, scpath string) *accounts.Manager {
+// StartClefAccountManager creates the account manager of Clef, with any extra
+// backends (e.g. remote keys) added to the configured local ones.
+func StartClefAccountManager(ksLocation string, nousb, lightKDF bool, scpath string, extra ...accounts.Backend) *accounts.Manager {
 	var (
 		backends []accounts.Backend
 		n, p     = keystore.StandardScryptN, keystore.StandardScryptP
@@ -180,6 +203,8 @@ func StartClefAccountManager(ksLocation string, nousb, lightKDF bool, scpath str
 		}
 	}
 
+	backends = append(backends, extra...)
+
 	// Clef doesn't allow insecure http account unlock.
 	return accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: false}, backends...)
 }
@@ -219,9 +244,10 @@ func (m Metadata) String() string {
 type (
 	// SignTxRequest contains info about a Transaction to sign
 	SignTxRequest struct {
-		Transaction SendTxArgs       `json:"transaction"`
-		Callinfo    []ValidationInfo `json:"call_info"`
-		Meta        Metadata         `json:"meta"`
+		Transaction SendTxArgs        `json:"transaction"`
+		Callinfo    []ValidationInfo  `json:"call_info"`
+		Simulation  *SimulationResult `json:"simulation,omitempty"`
+		Meta        Metadata          `json:"meta"`
 	}
 	// SignTxResponse result from SignTxRequest
 	SignTxResponse struct {
@@ -276,11 +302,12 @@ var ErrRequestDenied = errors.New("Request denied")
 // key that is generated when a new Account is created.
 // noUSB disables USB support that is required to support hardware devices such as
 // ledger and trezor.
-func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAPI, validator Validator, advancedMode bool, credentials storage.Storage) *SignerAPI {
+// simulator optionally executes transactions before they are presented for approval.
+func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAPI, validator Validator, simulator Simulator, advancedMode bool, credentials storage.Storage) *SignerAPI {
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, simulator, !advancedMode, credentials}
 	if !noUSB {
 		signer.startUSBListener()
 	}
@@ -512,10 +539,21 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 			return nil, err
 		}
 	}
+	// Show what the transaction would do, without rejecting failing ones
+	var simulation *SimulationResult
+	if api.simulator != nil {
+		if simulation, err = api.simulator.SimulateTransaction(ctx, &args); err != nil {
+			log.Warn("Transaction simulation failed", "err", err)
+			msgs.Warn(fmt.Sprintf("Transaction could not be simulated: %v", err))
+		} else if !simulation.Success {
+			msgs.Warn(fmt.Sprintf("Transaction fails in simulation: %s", simulation.Error))
+		}
+	}
 	req := SignTxRequest{
 		Transaction: args,
 		Meta:        MetadataFromContext(ctx),
 		Callinfo:    msgs.Messages,
+		Simulation:  simulation,
 	}
 	// Process approval
 	result, err = api.UI.ApproveTx(&req)
@@ -525,6 +563,9 @@ func (api *SignerAPI) SignTransaction(ctx context.Context, args SendTxArgs, meth
 	if !result.Approved {
 		return nil, ErrRequestDenied
 	}
+	if observer, ok := api.UI.(SignTxObserver); ok {
+		defer func() { observer.OnSignTxDone(&req, err) }()
+	}
 	// Log changes made by the UI to the signing-request
 	logDiff(&req, &result)
 	var (
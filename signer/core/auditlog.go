@@ -92,6 +92,50 @@ func (l *AuditLogger) Version(ctx context.Context) (string, error) {
 
 }
 
+// ApprovalAuditLogger records the votes cast through the quorum approval API.
+type ApprovalAuditLogger struct {
+	log log.Logger
+	api ApprovalAPI
+}
+
+func (l *ApprovalAuditLogger) Challenge(ctx context.Context) (string, error) {
+	l.log.Info("Challenge", "type", "request", "metadata", MetadataFromContext(ctx).String())
+	res, e := l.api.Challenge(ctx)
+	l.log.Info("Challenge", "type", "response", "challenge", res, "error", e)
+	return res, e
+}
+
+func (l *ApprovalAuditLogger) Pending(ctx context.Context, challenge string, sig hexutil.Bytes) ([]*PendingRequest, error) {
+	l.log.Info("Pending", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"challenge", challenge, "sig", common.Bytes2Hex(sig))
+	res, e := l.api.Pending(ctx, challenge, sig)
+	l.log.Info("Pending", "type", "response", "count", len(res), "error", e)
+	return res, e
+}
+
+func (l *ApprovalAuditLogger) Approve(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error) {
+	l.log.Info("Approve", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"id", id, "sig", common.Bytes2Hex(sig))
+	res, e := l.api.Approve(ctx, id, sig)
+	l.log.Info("Approve", "type", "response", "error", e)
+	return res, e
+}
+
+func (l *ApprovalAuditLogger) Reject(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error) {
+	l.log.Info("Reject", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"id", id, "sig", common.Bytes2Hex(sig))
+	res, e := l.api.Reject(ctx, id, sig)
+	l.log.Info("Reject", "type", "response", "error", e)
+	return res, e
+}
+
+// NewApprovalAuditLogger wraps the approval API of the given quorum, writing both
+// the API calls and the lifecycle of the pending requests into the audit log.
+func NewApprovalAuditLogger(audit *AuditLogger, quorum *QuorumUI) *ApprovalAuditLogger {
+	quorum.SetAuditLog(audit)
+	return &ApprovalAuditLogger{audit.log, quorum.API()}
+}
+
 func NewAuditLogger(path string, api ExternalAPI) (*AuditLogger, error) {
 	l := log.New("api", "signer")
 	handler, err := log.FileHandler(path, log.LogfmtFormat())
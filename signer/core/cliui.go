@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"strings"
 	"sync"
@@ -153,6 +154,27 @@ func (ui *CommandlineUI) ApproveTx(request *SignTxRequest) (SignTxResponse, erro
 		fmt.Println()
 
 	}
+	if sim := request.Simulation; sim != nil {
+		fmt.Printf("\nTransaction simulation:\n")
+		if sim.Success {
+			fmt.Printf("  status:   success\n")
+		} else {
+			fmt.Printf("  status:   FAILED (%s)\n", sim.Error)
+		}
+		fmt.Printf("  gas used: %d\n", uint64(sim.GasUsed))
+		for _, change := range sim.BalanceChanges {
+			diff := new(big.Int).Sub(change.After.ToInt(), change.Before.ToInt())
+			fmt.Printf("  balance:  %v %+v wei\n", change.Address.Hex(), diff)
+		}
+		for _, transfer := range sim.TokenTransfers {
+			if transfer.TokenID != nil {
+				fmt.Printf("  token:    %v #%v from %v to %v\n", transfer.Token.Hex(), transfer.TokenID.ToInt(), transfer.From.Hex(), transfer.To.Hex())
+			} else {
+				fmt.Printf("  token:    %v %v from %v to %v\n", transfer.Token.Hex(), transfer.Value.ToInt(), transfer.From.Hex(), transfer.To.Hex())
+			}
+		}
+		fmt.Println()
+	}
 	fmt.Printf("\n")
 	showMetadata(request.Meta)
 	fmt.Printf("-------------------------------------------\n")
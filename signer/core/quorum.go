@@ -0,0 +1,417 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/internal/ethapi"
+	"github.com/Fantom-foundation/go-ethereum/log"
+)
+
+var (
+	// ErrUnknownRequest is returned if a vote is cast on a request which is not (or
+	// no longer) pending.
+	ErrUnknownRequest = errors.New("unknown or resolved request")
+
+	// ErrNotApprover is returned if a vote is signed by a key not in the approver set.
+	ErrNotApprover = errors.New("signer is not an approver")
+
+	// ErrAlreadyVoted is returned if an approver casts a second vote on a request.
+	ErrAlreadyVoted = errors.New("approver already voted")
+
+	// ErrApprovalExpired is returned if a request did not reach quorum in time.
+	ErrApprovalExpired = errors.New("approval expired")
+
+	// ErrInvalidChallenge is returned if the pending requests are listed with a
+	// challenge not issued by the quorum, or an expired one.
+	ErrInvalidChallenge = errors.New("invalid or expired challenge")
+)
+
+// challengeExpiry is the time an approver has to sign a listing challenge.
+const challengeExpiry = time.Minute
+
+// Decisions an approver can sign on a pending request.
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// QuorumConfig is the approver set and threshold of the quorum approval mode.
+type QuorumConfig struct {
+	Threshold int              // Number of approvals needed to sign a request (M)
+	Approvers []common.Address // Addresses of the keys allowed to approve (N)
+	Expiry    time.Duration    // Time after which a pending request is rejected
+}
+
+// PendingRequest is a sign request awaiting quorum approval, as presented to the
+// approvers.
+type PendingRequest struct {
+	ID          string           `json:"id"`
+	Transaction *SignTxRequest   `json:"transaction,omitempty"`
+	Data        *SignDataRequest `json:"data,omitempty"`
+	Created     time.Time        `json:"created"`
+	Expires     time.Time        `json:"expires"`
+	Threshold   int              `json:"threshold"`
+	Approvals   []common.Address `json:"approvals"`
+	Rejections  []common.Address `json:"rejections"`
+}
+
+// ApprovalHash returns the hash an approver needs to sign to cast the given
+// decision on a pending request. It is compatible with personal_sign, so any
+// wallet can be used to approve, with the message being
+//
+//	clef request <id>: <decision>
+func ApprovalHash(id, decision string) []byte {
+	return accounts.TextHash([]byte(fmt.Sprintf("clef request %s: %s", id, decision)))
+}
+
+// PendingHash returns the hash an approver needs to sign to list the pending
+// requests with a challenge obtained from the approval API. Like ApprovalHash,
+// it is compatible with personal_sign, the message being
+//
+//	clef pending: <challenge>
+func PendingHash(challenge string) []byte {
+	return accounts.TextHash([]byte(fmt.Sprintf("clef pending: %s", challenge)))
+}
+
+// pendingRequest is the internal bookkeeping of a request awaiting approval.
+type pendingRequest struct {
+	PendingRequest
+	voted    map[common.Address]bool
+	approved bool
+	done     chan struct{} // Closed when the request is resolved
+}
+
+// QuorumUI is a UIClientAPI which requires M-of-N approver signatures to grant
+// sign requests. Pending requests are queued and exposed to the approvers via
+// the ApprovalAPI, everything else is delegated to the wrapped UI.
+type QuorumUI struct {
+	next   UIClientAPI
+	config QuorumConfig
+	audit  log.Logger
+
+	approvers map[common.Address]bool
+	pending   map[string]*pendingRequest
+	secret    []byte // Key authenticating the issued listing challenges
+	lock      sync.Mutex
+}
+
+// NewQuorumUI creates a quorum approval UI on top of the given UI.
+func NewQuorumUI(next UIClientAPI, config QuorumConfig) (*QuorumUI, error) {
+	approvers := make(map[common.Address]bool)
+	for _, addr := range config.Approvers {
+		if approvers[addr] {
+			return nil, fmt.Errorf("duplicate approver %s", addr.Hex())
+		}
+		approvers[addr] = true
+	}
+	if config.Threshold < 1 || config.Threshold > len(approvers) {
+		return nil, fmt.Errorf("invalid threshold %d of %d approvers", config.Threshold, len(approvers))
+	}
+	if config.Expiry <= 0 {
+		return nil, fmt.Errorf("invalid expiry %v", config.Expiry)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &QuorumUI{
+		next:      next,
+		config:    config,
+		audit:     log.New("api", "quorum"),
+		approvers: approvers,
+		pending:   make(map[string]*pendingRequest),
+		secret:    secret,
+	}, nil
+}
+
+// SetAuditLog makes the quorum write the lifecycle of requests into the audit log.
+func (q *QuorumUI) SetAuditLog(l *AuditLogger) {
+	q.audit = l.log
+}
+
+// API returns the approval API through which approvers vote on pending requests.
+func (q *QuorumUI) API() ApprovalAPI {
+	return &quorumAPI{q}
+}
+
+// queue adds a new request to the pending set, and blocks until it is either
+// resolved by the approvers or expires.
+func (q *QuorumUI) queue(tx *SignTxRequest, data *SignDataRequest) (bool, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	req := &pendingRequest{
+		PendingRequest: PendingRequest{
+			ID:          hexutil.Encode(id[:]),
+			Transaction: tx,
+			Data:        data,
+			Created:     now,
+			Expires:     now.Add(q.config.Expiry),
+			Threshold:   q.config.Threshold,
+		},
+		voted: make(map[common.Address]bool),
+		done:  make(chan struct{}),
+	}
+	q.lock.Lock()
+	q.pending[req.ID] = req
+	q.lock.Unlock()
+
+	if tx != nil {
+		q.audit.Info("Quorum", "type", "queued", "id", req.ID, "metadata", tx.Meta.String(), "tx", tx.Transaction.String())
+	} else {
+		q.audit.Info("Quorum", "type", "queued", "id", req.ID, "metadata", data.Meta.String(), "addr", data.Address.String(), "hash", data.Hash)
+	}
+	q.next.ShowInfo(fmt.Sprintf("Request %s awaiting approval by %d of %d approvers", req.ID, q.config.Threshold, len(q.approvers)))
+
+	timer := time.NewTimer(q.config.Expiry)
+	defer timer.Stop()
+
+	select {
+	case <-req.done:
+		return req.approved, nil
+	case <-timer.C:
+		q.lock.Lock()
+		defer q.lock.Unlock()
+
+		// The request might have been resolved while waiting for the lock
+		select {
+		case <-req.done:
+			return req.approved, nil
+		default:
+		}
+		delete(q.pending, req.ID)
+		close(req.done)
+		q.audit.Info("Quorum", "type", "expired", "id", req.ID, "approvals", req.Approvals, "rejections", req.Rejections)
+		return false, ErrApprovalExpired
+	}
+}
+
+// recoverApprover returns the approver who signed the hash.
+func (q *QuorumUI) recoverApprover(hash []byte, sig hexutil.Bytes) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	approver := crypto.PubkeyToAddress(*pub)
+	if !q.approvers[approver] {
+		return common.Address{}, ErrNotApprover
+	}
+	return approver, nil
+}
+
+// challenge issues a new listing challenge. Challenges are not tracked, they
+// carry their expiry time, authenticated by the secret of the quorum.
+func (q *QuorumUI) challenge() string {
+	blob := make([]byte, 8, 8+sha256.Size)
+	binary.BigEndian.PutUint64(blob, uint64(time.Now().Add(challengeExpiry).Unix()))
+
+	mac := hmac.New(sha256.New, q.secret)
+	mac.Write(blob)
+	return hexutil.Encode(mac.Sum(blob))
+}
+
+// verifyChallenge checks that the challenge was issued by the quorum and has not
+// expired yet.
+func (q *QuorumUI) verifyChallenge(challenge string) error {
+	blob, err := hexutil.Decode(challenge)
+	if err != nil || len(blob) != 8+sha256.Size {
+		return ErrInvalidChallenge
+	}
+	mac := hmac.New(sha256.New, q.secret)
+	mac.Write(blob[:8])
+	if !hmac.Equal(mac.Sum(nil), blob[8:]) {
+		return ErrInvalidChallenge
+	}
+	if time.Now().Unix() > int64(binary.BigEndian.Uint64(blob[:8])) {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// vote verifies and records an approver's decision on a pending request,
+// resolving it if the quorum was reached or became unreachable.
+func (q *QuorumUI) vote(id string, decision string, sig hexutil.Bytes) (*PendingRequest, error) {
+	approver, err := q.recoverApprover(ApprovalHash(id, decision), sig)
+	if err != nil {
+		return nil, err
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	req, ok := q.pending[id]
+	if !ok {
+		return nil, ErrUnknownRequest
+	}
+	if req.voted[approver] {
+		return nil, ErrAlreadyVoted
+	}
+	req.voted[approver] = true
+	if decision == DecisionApprove {
+		req.Approvals = append(req.Approvals, approver)
+	} else {
+		req.Rejections = append(req.Rejections, approver)
+	}
+	q.audit.Info("Quorum", "type", decision, "id", id, "approver", approver)
+
+	switch {
+	case len(req.Approvals) >= q.config.Threshold:
+		req.approved = true
+		q.audit.Info("Quorum", "type", "granted", "id", id, "approvals", req.Approvals)
+	case len(q.approvers)-len(req.Rejections) < q.config.Threshold:
+		q.audit.Info("Quorum", "type", "denied", "id", id, "rejections", req.Rejections)
+	default:
+		return req.copy(), nil
+	}
+	delete(q.pending, id)
+	close(req.done)
+	return req.copy(), nil
+}
+
+// copy returns a snapshot of the public fields of a pending request.
+func (req *pendingRequest) copy() *PendingRequest {
+	cpy := req.PendingRequest
+	cpy.Approvals = append([]common.Address{}, req.Approvals...)
+	cpy.Rejections = append([]common.Address{}, req.Rejections...)
+	return &cpy
+}
+
+// authorizedList returns the pending requests to an approver who signed a valid
+// listing challenge.
+func (q *QuorumUI) authorizedList(challenge string, sig hexutil.Bytes) ([]*PendingRequest, error) {
+	if err := q.verifyChallenge(challenge); err != nil {
+		return nil, err
+	}
+	if _, err := q.recoverApprover(PendingHash(challenge), sig); err != nil {
+		return nil, err
+	}
+	return q.list(), nil
+}
+
+// list returns a snapshot of all pending requests, oldest first.
+func (q *QuorumUI) list() []*PendingRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	list := make([]*PendingRequest, 0, len(q.pending))
+	for _, req := range q.pending {
+		list = append(list, req.copy())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.Before(list[j].Created)
+	})
+	return list
+}
+
+func (q *QuorumUI) ApproveTx(request *SignTxRequest) (SignTxResponse, error) {
+	approved, err := q.queue(request, nil)
+	return SignTxResponse{Transaction: request.Transaction, Approved: approved}, err
+}
+
+func (q *QuorumUI) ApproveSignData(request *SignDataRequest) (SignDataResponse, error) {
+	approved, err := q.queue(nil, request)
+	return SignDataResponse{Approved: approved}, err
+}
+
+func (q *QuorumUI) ApproveListing(request *ListRequest) (ListResponse, error) {
+	return q.next.ApproveListing(request)
+}
+
+func (q *QuorumUI) ApproveNewAccount(request *NewAccountRequest) (NewAccountResponse, error) {
+	return q.next.ApproveNewAccount(request)
+}
+
+func (q *QuorumUI) ShowError(message string) {
+	q.next.ShowError(message)
+}
+
+func (q *QuorumUI) ShowInfo(message string) {
+	q.next.ShowInfo(message)
+}
+
+func (q *QuorumUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	q.next.OnApprovedTx(tx)
+}
+
+func (q *QuorumUI) OnSignerStartup(info StartupInfo) {
+	q.next.OnSignerStartup(info)
+}
+
+func (q *QuorumUI) OnInputRequired(info UserInputRequest) (UserInputResponse, error) {
+	return q.next.OnInputRequired(info)
+}
+
+func (q *QuorumUI) RegisterUIServer(api *UIServerAPI) {
+	q.next.RegisterUIServer(api)
+}
+
+// ApprovalAPI defines the API through which approvers vote on requests pending
+// quorum approval.
+type ApprovalAPI interface {
+	// Challenge returns a challenge to sign for listing the pending requests
+	Challenge(ctx context.Context) (string, error)
+	// Pending lists the requests awaiting approval, signed over PendingHash(challenge)
+	Pending(ctx context.Context, challenge string, sig hexutil.Bytes) ([]*PendingRequest, error)
+	// Approve casts an approval, signed over ApprovalHash(id, "approve")
+	Approve(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error)
+	// Reject casts a rejection, signed over ApprovalHash(id, "reject")
+	Reject(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error)
+}
+
+// quorumAPI implements ApprovalAPI on top of a QuorumUI.
+type quorumAPI struct {
+	quorum *QuorumUI
+}
+
+func (api *quorumAPI) Challenge(ctx context.Context) (string, error) {
+	return api.quorum.challenge(), nil
+}
+
+func (api *quorumAPI) Pending(ctx context.Context, challenge string, sig hexutil.Bytes) ([]*PendingRequest, error) {
+	return api.quorum.authorizedList(challenge, sig)
+}
+
+func (api *quorumAPI) Approve(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error) {
+	return api.quorum.vote(id, DecisionApprove, sig)
+}
+
+func (api *quorumAPI) Reject(ctx context.Context, id string, sig hexutil.Bytes) (*PendingRequest, error) {
+	return api.quorum.vote(id, DecisionReject, sig)
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/signer/core"
+)
+
+type quorumResult struct {
+	resp core.SignTxResponse
+	err  error
+}
+
+// setupQuorum creates a quorum UI with n approvers and the given threshold.
+func setupQuorum(t *testing.T, threshold, n int, expiry time.Duration) (*core.QuorumUI, []*ecdsa.PrivateKey) {
+	var (
+		keys  []*ecdsa.PrivateKey
+		addrs []common.Address
+	)
+	for i := 0; i < n; i++ {
+		key, _ := crypto.GenerateKey()
+		keys = append(keys, key)
+		addrs = append(addrs, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	quorum, err := core.NewQuorumUI(&headlessUi{}, core.QuorumConfig{Threshold: threshold, Approvers: addrs, Expiry: expiry})
+	if err != nil {
+		t.Fatalf("failed to create quorum: %v", err)
+	}
+	return quorum, keys
+}
+
+// listPending lists the pending requests, authenticated as the given approver.
+func listPending(t *testing.T, api core.ApprovalAPI, key *ecdsa.PrivateKey) []*core.PendingRequest {
+	challenge, err := api.Challenge(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+	sig, _ := crypto.Sign(core.PendingHash(challenge), key)
+	pending, err := api.Pending(context.Background(), challenge, sig)
+	if err != nil {
+		t.Fatalf("failed to list pending requests: %v", err)
+	}
+	return pending
+}
+
+// submitTx requests approval of a test transaction in the background and waits
+// until it shows up in the pending set.
+func submitTx(t *testing.T, quorum *core.QuorumUI, key *ecdsa.PrivateKey) (string, chan quorumResult) {
+	result := make(chan quorumResult, 1)
+	go func() {
+		resp, err := quorum.ApproveTx(&core.SignTxRequest{Transaction: mkTestTx(common.NewMixedcaseAddress(common.Address{1}))})
+		result <- quorumResult{resp, err}
+	}()
+	for i := 0; i < 100; i++ {
+		if pending := listPending(t, quorum.API(), key); len(pending) > 0 {
+			return pending[0].ID, result
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("request not queued")
+	return "", nil
+}
+
+func signDecision(key *ecdsa.PrivateKey, id, decision string) hexutil.Bytes {
+	sig, _ := crypto.Sign(core.ApprovalHash(id, decision), key)
+	sig[64] += 27 // personal_sign style V
+	return sig
+}
+
+func TestQuorumApproval(t *testing.T) {
+	quorum, keys := setupQuorum(t, 2, 3, time.Minute)
+	api := quorum.API()
+	id, result := submitTx(t, quorum, keys[0])
+
+	// Votes from strangers and votes signed for another decision must be refused
+	stranger, _ := crypto.GenerateKey()
+	if _, err := api.Approve(context.Background(), id, signDecision(stranger, id, core.DecisionApprove)); err != core.ErrNotApprover {
+		t.Fatalf("stranger vote: have %v, want %v", err, core.ErrNotApprover)
+	}
+	if _, err := api.Approve(context.Background(), id, signDecision(keys[0], id, core.DecisionReject)); err != core.ErrNotApprover {
+		t.Fatalf("mismatched decision: have %v, want %v", err, core.ErrNotApprover)
+	}
+	// A single approval is not enough, and may not be repeated
+	req, err := api.Approve(context.Background(), id, signDecision(keys[0], id, core.DecisionApprove))
+	if err != nil {
+		t.Fatalf("first approval failed: %v", err)
+	}
+	if len(req.Approvals) != 1 || req.Approvals[0] != crypto.PubkeyToAddress(keys[0].PublicKey) {
+		t.Fatalf("approvals mismatch: %v", req.Approvals)
+	}
+	if _, err := api.Approve(context.Background(), id, signDecision(keys[0], id, core.DecisionApprove)); err != core.ErrAlreadyVoted {
+		t.Fatalf("repeated vote: have %v, want %v", err, core.ErrAlreadyVoted)
+	}
+	select {
+	case res := <-result:
+		t.Fatalf("request resolved before quorum: %+v", res)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The second approval reaches quorum
+	if _, err := api.Approve(context.Background(), id, signDecision(keys[2], id, core.DecisionApprove)); err != nil {
+		t.Fatalf("second approval failed: %v", err)
+	}
+	if res := <-result; res.err != nil || !res.resp.Approved {
+		t.Fatalf("request not approved: %+v", res)
+	}
+	if pending := listPending(t, api, keys[1]); len(pending) != 0 {
+		t.Fatalf("resolved request still pending: %v", pending)
+	}
+	if _, err := api.Approve(context.Background(), id, signDecision(keys[1], id, core.DecisionApprove)); err != core.ErrUnknownRequest {
+		t.Fatalf("vote on resolved request: have %v, want %v", err, core.ErrUnknownRequest)
+	}
+}
+
+func TestQuorumRejection(t *testing.T) {
+	quorum, keys := setupQuorum(t, 2, 3, time.Minute)
+	api := quorum.API()
+	id, result := submitTx(t, quorum, keys[0])
+
+	if _, err := api.Reject(context.Background(), id, signDecision(keys[0], id, core.DecisionReject)); err != nil {
+		t.Fatalf("first rejection failed: %v", err)
+	}
+	if _, err := api.Approve(context.Background(), id, signDecision(keys[1], id, core.DecisionApprove)); err != nil {
+		t.Fatalf("approval failed: %v", err)
+	}
+	// With two rejections, the quorum can no longer be reached
+	if _, err := api.Reject(context.Background(), id, signDecision(keys[2], id, core.DecisionReject)); err != nil {
+		t.Fatalf("second rejection failed: %v", err)
+	}
+	if res := <-result; res.err != nil || res.resp.Approved {
+		t.Fatalf("request not denied: %+v", res)
+	}
+}
+
+func TestQuorumExpiry(t *testing.T) {
+	quorum, keys := setupQuorum(t, 1, 2, 100*time.Millisecond)
+	id, result := submitTx(t, quorum, keys[0])
+
+	if res := <-result; res.err != core.ErrApprovalExpired || res.resp.Approved {
+		t.Fatalf("expiry mismatch: %+v", res)
+	}
+	if _, err := quorum.API().Approve(context.Background(), id, signDecision(keys[0], id, core.DecisionApprove)); err != core.ErrUnknownRequest {
+		t.Fatalf("vote on expired request: have %v, want %v", err, core.ErrUnknownRequest)
+	}
+}
+
+func TestQuorumPendingAuth(t *testing.T) {
+	quorum, keys := setupQuorum(t, 1, 2, time.Minute)
+	api := quorum.API()
+	submitTx(t, quorum, keys[0])
+
+	challenge, err := api.Challenge(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get challenge: %v", err)
+	}
+	// Listing requires an approver's signature over an issued challenge
+	stranger, _ := crypto.GenerateKey()
+	sig, _ := crypto.Sign(core.PendingHash(challenge), stranger)
+	if _, err := api.Pending(context.Background(), challenge, sig); err != core.ErrNotApprover {
+		t.Fatalf("stranger listing: have %v, want %v", err, core.ErrNotApprover)
+	}
+	forged := challenge[:len(challenge)-2] + "00"
+	if forged == challenge {
+		forged = challenge[:len(challenge)-2] + "01"
+	}
+	sig, _ = crypto.Sign(core.PendingHash(forged), keys[0])
+	if _, err := api.Pending(context.Background(), forged, sig); err != core.ErrInvalidChallenge {
+		t.Fatalf("forged challenge: have %v, want %v", err, core.ErrInvalidChallenge)
+	}
+	sig, _ = crypto.Sign(core.PendingHash("0x"), keys[0])
+	if _, err := api.Pending(context.Background(), "0x", sig); err != core.ErrInvalidChallenge {
+		t.Fatalf("malformed challenge: have %v, want %v", err, core.ErrInvalidChallenge)
+	}
+	sig, _ = crypto.Sign(core.PendingHash(challenge), keys[1])
+	if pending, err := api.Pending(context.Background(), challenge, sig); err != nil || len(pending) != 1 {
+		t.Fatalf("approver listing failed: %v (%d requests)", err, len(pending))
+	}
+}
+
+func TestQuorumConfig(t *testing.T) {
+	addr := common.Address{1}
+	for i, config := range []core.QuorumConfig{
+		{Threshold: 0, Approvers: []common.Address{addr}, Expiry: time.Minute},
+		{Threshold: 2, Approvers: []common.Address{addr}, Expiry: time.Minute},
+		{Threshold: 1, Approvers: []common.Address{addr, addr}, Expiry: time.Minute},
+		{Threshold: 1, Approvers: []common.Address{addr}},
+	} {
+		if _, err := core.NewQuorumUI(&headlessUi{}, config); err == nil {
+			t.Errorf("test %d: invalid config accepted", i)
+		}
+	}
+}
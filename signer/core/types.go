@@ -78,6 +78,38 @@ type SendTxArgs struct {
 	Input *hexutil.Bytes `json:"input,omitempty"`
 }
 
+// SimulationResult is the outcome of executing a transaction on the current state
+// of the chain before signing it. The transaction is executed in a block following
+// the latest one, whose number and ancestor hashes match the chain, but whose
+// timestamp, coinbase and difficulty are only estimated, so the actual outcome
+// may still differ.
+type SimulationResult struct {
+	Success        bool            `json:"success"`
+	Error          string          `json:"error,omitempty"`
+	GasUsed        hexutil.Uint64  `json:"gasUsed"`
+	BalanceChanges []BalanceChange `json:"balanceChanges"`
+	TokenTransfers []TokenTransfer `json:"tokenTransfers"`
+}
+
+// BalanceChange is the change of an account's ether balance caused by a
+// simulated transaction, including the fees paid.
+type BalanceChange struct {
+	Address common.Address `json:"address"`
+	Before  *hexutil.Big   `json:"before"`
+	After   *hexutil.Big   `json:"after"`
+}
+
+// TokenTransfer is an ERC20 or ERC721 transfer made by a simulated transaction,
+// as reported by the token's Transfer event. ERC20 transfers carry a value,
+// ERC721 ones a token id.
+type TokenTransfer struct {
+	Token   common.Address `json:"token"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"`
+	Value   *hexutil.Big   `json:"value,omitempty"`
+	TokenID *hexutil.Big   `json:"tokenId,omitempty"`
+}
+
 func (args SendTxArgs) String() string {
 	s, err := json.Marshal(args)
 	if err == nil {
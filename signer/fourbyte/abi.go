@@ -75,6 +75,13 @@ func verifySelector(selector string, calldata []byte) (*decodedCallData, error)
 	return parseCallData(calldata, string(abidata))
 }
 
+// VerifyCallData checks whether the ABI encoded data blob matches the requested
+// function signature, including its 4 byte selector.
+func VerifyCallData(selector string, calldata []byte) error {
+	_, err := verifySelector(selector, calldata)
+	return err
+}
+
 // selectorRegexp is used to validate that a 4byte database selector corresponds
 // to a valid ABI function declaration.
 //
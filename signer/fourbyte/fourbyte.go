@@ -118,6 +118,19 @@ func (db *Database) Selector(id []byte) (string, error) {
 	return "", fmt.Errorf("signature %v not found", sig)
 }
 
+// Method resolves the method signature of the call data from the database, and
+// verifies that the call data can be decoded according to it.
+func (db *Database) Method(calldata []byte) (string, error) {
+	selector, err := db.Selector(calldata)
+	if err != nil {
+		return "", err
+	}
+	if _, err := verifySelector(selector, calldata); err != nil {
+		return "", err
+	}
+	return selector, nil
+}
+
 // AddSelector inserts a new 4byte entry into the database. If custom database
 // saving is enabled, the new dataset is also persisted to disk.
 //
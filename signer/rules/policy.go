@@ -0,0 +1,528 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/math"
+	"github.com/Fantom-foundation/go-ethereum/internal/ethapi"
+	"github.com/Fantom-foundation/go-ethereum/log"
+	"github.com/Fantom-foundation/go-ethereum/signer/core"
+	"github.com/Fantom-foundation/go-ethereum/signer/fourbyte"
+	"github.com/Fantom-foundation/go-ethereum/signer/storage"
+)
+
+// Verdict is the outcome of evaluating a request against a policy.
+type Verdict string
+
+const (
+	VerdictApprove Verdict = "Approve" // Request is permitted by the policy
+	VerdictReject  Verdict = "Reject"  // Request violates the policy
+	VerdictManual  Verdict = "Manual"  // Request is not covered, defer to the user
+)
+
+// Policy is a declarative ruleset to automatically approve or reject signing
+// requests, as an auditable alternative to JavaScript rules.
+//
+// A transaction is evaluated against the first transaction rule matching both
+// its sender and recipient. It is approved if it satisfies all constraints of
+// the rule, and rejected otherwise. Transactions not matching any rule receive
+// the default verdict.
+type Policy struct {
+	Default        Verdict   `json:"default"`        // Verdict for unmatched transactions, Manual if unset
+	ApproveListing bool      `json:"approveListing"` // Whether to approve account listing requests
+	Transactions   []*TxRule `json:"transactions"`   // Rules for transaction signing requests
+}
+
+// TxRule is a set of constraints for transactions between given senders and
+// recipients.
+type TxRule struct {
+	Name        string                `json:"name"`
+	From        []common.Address      `json:"from"`        // Senders the rule applies to, any if empty
+	To          []*Recipient          `json:"to"`          // Recipients the rule applies to
+	MaxGasPrice *math.HexOrDecimal256 `json:"maxGasPrice"` // Maximum gas price of a transaction
+	MaxValue    *math.HexOrDecimal256 `json:"maxValue"`    // Maximum value of a single transaction
+	Limits      []*Limit              `json:"limits"`      // Rolling limits of the sender's transactions
+}
+
+// Recipient is an allowed recipient of transactions. Plain value transfers to
+// the recipient are always allowed, contract calls only to the listed methods.
+//
+// Methods are given by their full signature, e.g. transfer(address,uint256), which
+// the selector and call data are verified against. Names alone are not accepted,
+// as they would trust the 4byte database to resolve the selector. A method of "*"
+// allows arbitrary call data.
+type Recipient struct {
+	Address common.Address `json:"address"`
+	Methods []string       `json:"methods"`
+}
+
+// Limit caps the total value and number of transactions signed for a sender
+// within a rolling time window.
+type Limit struct {
+	Window   Duration              `json:"window"`
+	MaxValue *math.HexOrDecimal256 `json:"maxValue"` // Maximum total value, unlimited if unset
+	MaxCount uint64                `json:"maxCount"` // Maximum number of transactions, unlimited if zero
+}
+
+// Duration is a time.Duration marshalled in its textual form, e.g. "24h".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(input []byte) error {
+	var text string
+	if err := json.Unmarshal(input, &text); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+// ParsePolicy parses and validates a JSON policy.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	policy := new(Policy)
+	if err := dec.Decode(policy); err != nil {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// validate checks the policy for rules which can't be evaluated.
+func (p *Policy) validate() error {
+	switch p.Default {
+	case "":
+		p.Default = VerdictManual
+	case VerdictApprove, VerdictReject, VerdictManual:
+	default:
+		return fmt.Errorf("invalid default verdict %q", p.Default)
+	}
+	for i, rule := range p.Transactions {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i)
+		}
+		if len(rule.To) == 0 {
+			return fmt.Errorf("rule %s: no recipients", rule.Name)
+		}
+		for _, recipient := range rule.To {
+			for _, method := range recipient.Methods {
+				if method != "*" && !(strings.Contains(method, "(") && strings.HasSuffix(method, ")")) {
+					return fmt.Errorf("rule %s: method %q for recipient %s is not a full signature", rule.Name, method, recipient.Address.Hex())
+				}
+			}
+		}
+		for _, limit := range rule.Limits {
+			if limit.Window <= 0 {
+				return fmt.Errorf("rule %s: invalid limit window %v", rule.Name, time.Duration(limit.Window))
+			}
+		}
+	}
+	return nil
+}
+
+// window returns the longest limit window of the policy, which is how long the
+// transaction history needs to be retained.
+func (p *Policy) window() time.Duration {
+	var window time.Duration
+	for _, rule := range p.Transactions {
+		for _, limit := range rule.Limits {
+			if time.Duration(limit.Window) > window {
+				window = time.Duration(limit.Window)
+			}
+		}
+	}
+	return window
+}
+
+// Decision is the result of evaluating a request against a policy.
+type Decision struct {
+	Verdict Verdict  `json:"verdict"`
+	Rule    string   `json:"rule,omitempty"`    // Name of the rule the request matched
+	Method  string   `json:"method,omitempty"`  // Signature of the called contract method
+	Reasons []string `json:"reasons,omitempty"` // Violated constraints if rejected
+}
+
+// txRecord is a signed transaction retained to evaluate limits.
+type txRecord struct {
+	Time  int64                 `json:"time"` // Unix time of signing, in milliseconds
+	Value *math.HexOrDecimal256 `json:"value"`
+}
+
+// policyUI provides an implementation of UIClientAPI that evaluates a declarative
+// policy for transaction signing and account listing requests.
+type policyUI struct {
+	next    core.UIClientAPI   // The next handler, for manual processing
+	policy  *Policy            // The policy to evaluate
+	methods *fourbyte.Database // Database to resolve contract method names
+	storage storage.Storage    // Storage of the recent transaction history
+
+	now      func() time.Time                     // Wall clock, replaceable for tests
+	reserved map[*core.SignTxRequest]*reservation // Allowances of approved requests pending signing
+	lock     sync.Mutex                           // Lock serializing evaluations and history updates
+}
+
+// reservation is a history entry added for an approved request before signing,
+// so that concurrent requests can't exceed the limits together.
+type reservation struct {
+	from   common.Address
+	record txRecord
+}
+
+// NewPolicyEvaluator creates a UI evaluating the policy, and forwarding requests
+// not decided by it to the next UI. The method database may be nil, in which
+// case contract methods can only be allowed by their full signatures.
+func NewPolicyEvaluator(next core.UIClientAPI, policy *Policy, methods *fourbyte.Database, history storage.Storage) *policyUI {
+	return &policyUI{
+		next:     next,
+		policy:   policy,
+		methods:  methods,
+		storage:  history,
+		now:      time.Now,
+		reserved: make(map[*core.SignTxRequest]*reservation),
+	}
+}
+
+// EvaluateTx decides on a transaction signing request according to the policy
+// and the recent transaction history.
+func (r *policyUI) EvaluateTx(request *core.SignTxRequest) *Decision {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.evaluateTx(request)
+}
+
+func (r *policyUI) evaluateTx(request *core.SignTxRequest) *Decision {
+	tx := request.Transaction
+	if tx.To == nil {
+		return &Decision{Verdict: r.policy.Default}
+	}
+	var (
+		from = tx.From.Address()
+		to   = tx.To.Address()
+		data []byte
+	)
+	if tx.Data != nil {
+		data = *tx.Data
+	} else if tx.Input != nil {
+		data = *tx.Input
+	}
+	for _, rule := range r.policy.Transactions {
+		if !rule.matchSender(from) {
+			continue
+		}
+		recipient := rule.recipient(to)
+		if recipient == nil {
+			continue
+		}
+		decision := &Decision{Verdict: VerdictApprove, Rule: rule.Name}
+		reject := func(format string, args ...interface{}) {
+			decision.Verdict = VerdictReject
+			decision.Reasons = append(decision.Reasons, fmt.Sprintf(format, args...))
+		}
+		// Check the called method against the allowed ones
+		if len(data) > 0 {
+			method, err := r.allowedMethod(recipient, data)
+			if err != nil {
+				reject("%v", err)
+			}
+			decision.Method = method
+		}
+		// Check the transaction against the static ceilings
+		value := tx.Value.ToInt()
+		if rule.MaxGasPrice != nil && tx.GasPrice.ToInt().Cmp((*big.Int)(rule.MaxGasPrice)) > 0 {
+			reject("gas price %v exceeds maximum %v", tx.GasPrice.ToInt(), (*big.Int)(rule.MaxGasPrice))
+		}
+		if rule.MaxValue != nil && value.Cmp((*big.Int)(rule.MaxValue)) > 0 {
+			reject("value %v exceeds maximum %v", value, (*big.Int)(rule.MaxValue))
+		}
+		// Check the rolling limits against the recent transactions of the sender
+		if len(rule.Limits) > 0 {
+			history := r.history(from)
+			now := r.now()
+			for _, limit := range rule.Limits {
+				var (
+					start = now.Add(-time.Duration(limit.Window)).UnixNano() / int64(time.Millisecond)
+					count = uint64(1)
+					total = new(big.Int).Set(value)
+				)
+				for _, record := range history {
+					if record.Time > start {
+						count++
+						total.Add(total, (*big.Int)(record.Value))
+					}
+				}
+				window := time.Duration(limit.Window)
+				if limit.MaxCount > 0 && count > limit.MaxCount {
+					reject("transaction count %d in %v exceeds maximum %d", count, window, limit.MaxCount)
+				}
+				if limit.MaxValue != nil && total.Cmp((*big.Int)(limit.MaxValue)) > 0 {
+					reject("total value %v in %v exceeds maximum %v", total, window, (*big.Int)(limit.MaxValue))
+				}
+			}
+		}
+		return decision
+	}
+	return &Decision{Verdict: r.policy.Default}
+}
+
+// DryRun evaluates a sequence of transaction signing requests against a policy.
+// Approved transactions are assumed to be signed, counting towards the limits
+// of the subsequent ones.
+func DryRun(policy *Policy, methods *fourbyte.Database, requests []*core.SignTxRequest) []*Decision {
+	r := NewPolicyEvaluator(nil, policy, methods, storage.NewEphemeralStorage())
+
+	decisions := make([]*Decision, len(requests))
+	for i, request := range requests {
+		decisions[i] = r.evaluateTx(request)
+		if decisions[i].Verdict == VerdictApprove {
+			r.record(request.Transaction.From.Address(), request.Transaction.Value.ToInt())
+		}
+	}
+	return decisions
+}
+
+// matchSender reports whether the rule applies to transactions of the sender.
+func (rule *TxRule) matchSender(from common.Address) bool {
+	if len(rule.From) == 0 {
+		return true
+	}
+	for _, addr := range rule.From {
+		if addr == from {
+			return true
+		}
+	}
+	return false
+}
+
+// recipient returns the allowed recipient entry of the rule for an address.
+func (rule *TxRule) recipient(to common.Address) *Recipient {
+	for _, recipient := range rule.To {
+		if recipient.Address == to {
+			return recipient
+		}
+	}
+	return nil
+}
+
+// allowedMethod returns the signature of the contract method called by the data
+// if it is allowed for the recipient.
+func (r *policyUI) allowedMethod(recipient *Recipient, data []byte) (string, error) {
+	// Resolve the called method from the database for reporting only, it is not
+	// trusted to decide whether the call is allowed
+	var resolved string
+	if r.methods != nil {
+		resolved, _ = r.methods.Method(data)
+	}
+	for _, method := range recipient.Methods {
+		if method == "*" {
+			return resolved, nil
+		}
+		if fourbyte.VerifyCallData(method, data) == nil {
+			return method, nil
+		}
+	}
+	if resolved != "" {
+		return resolved, fmt.Errorf("method %s not allowed for %s", resolved, recipient.Address.Hex())
+	}
+	if len(data) < 4 {
+		return "", fmt.Errorf("invalid call data for %s", recipient.Address.Hex())
+	}
+	return "", fmt.Errorf("method %x not allowed for %s", data[:4], recipient.Address.Hex())
+}
+
+// historyKey returns the storage key of the transaction history of a sender.
+func historyKey(from common.Address) string {
+	return "policy_txs_" + strings.ToLower(from.Hex())
+}
+
+// history returns the recent transactions signed for the sender.
+func (r *policyUI) history(from common.Address) []txRecord {
+	blob, err := r.storage.Get(historyKey(from))
+	if err != nil {
+		return nil
+	}
+	var records []txRecord
+	if err := json.Unmarshal([]byte(blob), &records); err != nil {
+		log.Warn("Failed to decode policy transaction history", "from", from, "err", err)
+		return nil
+	}
+	return records
+}
+
+// record adds a signed transaction to the history of the sender, dropping the
+// entries older than any limit window. The added entry is returned, or nil if
+// the policy has no limits to retain history for.
+func (r *policyUI) record(from common.Address, value *big.Int) *txRecord {
+	window := r.policy.window()
+	if window == 0 {
+		return nil
+	}
+	var (
+		now     = r.now()
+		start   = now.Add(-window).UnixNano() / int64(time.Millisecond)
+		records = []txRecord{{Time: now.UnixNano() / int64(time.Millisecond), Value: (*math.HexOrDecimal256)(new(big.Int).Set(value))}}
+	)
+	for _, record := range r.history(from) {
+		if record.Time > start {
+			records = append(records, record)
+		}
+	}
+	r.store(from, records)
+	return &records[0]
+}
+
+// unrecord removes an entry added by record from the history of the sender.
+func (r *policyUI) unrecord(from common.Address, entry *txRecord) {
+	records := r.history(from)
+	for i, record := range records {
+		if record.Time == entry.Time && (*big.Int)(record.Value).Cmp((*big.Int)(entry.Value)) == 0 {
+			r.store(from, append(records[:i], records[i+1:]...))
+			return
+		}
+	}
+}
+
+// store replaces the transaction history of the sender.
+func (r *policyUI) store(from common.Address, records []txRecord) {
+	blob, err := json.Marshal(records)
+	if err != nil {
+		log.Warn("Failed to encode policy transaction history", "from", from, "err", err)
+		return
+	}
+	r.storage.Put(historyKey(from), string(blob))
+}
+
+// reserve records an approved request in the history of its sender ahead of
+// signing, until OnSignTxDone learns its outcome.
+func (r *policyUI) reserve(request *core.SignTxRequest, tx *core.SendTxArgs) {
+	from := tx.From.Address()
+	if record := r.record(from, tx.Value.ToInt()); record != nil {
+		r.reserved[request] = &reservation{from: from, record: *record}
+	}
+}
+
+func (r *policyUI) RegisterUIServer(api *core.UIServerAPI) {
+	r.next.RegisterUIServer(api)
+}
+
+// ApproveTx decides on a transaction signing request according to the policy,
+// deferring to the next UI if not covered. Approved requests count towards the
+// limits right away, the allowance is released if signing them fails.
+func (r *policyUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	r.lock.Lock()
+	decision := r.evaluateTx(request)
+	if decision.Verdict == VerdictApprove {
+		r.reserve(request, &request.Transaction)
+	}
+	r.lock.Unlock()
+
+	switch decision.Verdict {
+	case VerdictApprove:
+		log.Info("Transaction approved by policy", "rule", decision.Rule, "method", decision.Method)
+		return core.SignTxResponse{Transaction: request.Transaction, Approved: true}, nil
+
+	case VerdictReject:
+		log.Info("Transaction rejected by policy", "rule", decision.Rule, "reasons", strings.Join(decision.Reasons, "; "))
+		return core.SignTxResponse{Approved: false}, nil
+	}
+	response, err := r.next.ApproveTx(request)
+	if err == nil && response.Approved {
+		r.lock.Lock()
+		r.reserve(request, &response.Transaction)
+		r.lock.Unlock()
+	}
+	return response, err
+}
+
+// OnSignTxDone implements core.SignTxObserver, releasing the allowance reserved
+// for the request if it could not be signed.
+func (r *policyUI) OnSignTxDone(request *core.SignTxRequest, err error) {
+	r.lock.Lock()
+	if res, ok := r.reserved[request]; ok {
+		delete(r.reserved, request)
+		if err != nil {
+			r.unrecord(res.from, &res.record)
+		}
+	}
+	r.lock.Unlock()
+
+	if observer, ok := r.next.(core.SignTxObserver); ok {
+		observer.OnSignTxDone(request, err)
+	}
+}
+
+// ApproveSignData is not handled by the policy.
+func (r *policyUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return r.next.ApproveSignData(request)
+}
+
+func (r *policyUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	if r.policy.ApproveListing {
+		return core.ListResponse{Accounts: request.Accounts}, nil
+	}
+	return r.next.ApproveListing(request)
+}
+
+// ApproveNewAccount is not handled by the policy, it requires setting a password.
+func (r *policyUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return r.next.ApproveNewAccount(request)
+}
+
+// OnInputRequired is not handled by the policy.
+func (r *policyUI) OnInputRequired(info core.UserInputRequest) (core.UserInputResponse, error) {
+	return r.next.OnInputRequired(info)
+}
+
+func (r *policyUI) ShowError(message string) {
+	r.next.ShowError(message)
+}
+
+func (r *policyUI) ShowInfo(message string) {
+	r.next.ShowInfo(message)
+}
+
+func (r *policyUI) OnSignerStartup(info core.StartupInfo) {
+	r.next.OnSignerStartup(info)
+}
+
+// OnApprovedTx is not handled by the policy, signed transactions are already
+// recorded on approval.
+func (r *policyUI) OnApprovedTx(tx ethapi.SignTransactionResult) {
+	r.next.OnApprovedTx(tx)
+}
@@ -0,0 +1,286 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rules
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/internal/ethapi"
+	"github.com/Fantom-foundation/go-ethereum/signer/core"
+	"github.com/Fantom-foundation/go-ethereum/signer/fourbyte"
+	"github.com/Fantom-foundation/go-ethereum/signer/storage"
+)
+
+const testPolicy = `{
+	"approveListing": true,
+	"transactions": [
+		{
+			"name": "token",
+			"to": [
+				{"address": "0x000000000000000000000000000000000000dead", "methods": ["transfer(address,uint256)", "approve(address,uint256)"]},
+				{"address": "0x000000000000000000000000000000000000beef", "methods": ["*"]}
+			],
+			"maxGasPrice": "3000000",
+			"maxValue": "1000",
+			"limits": [
+				{"window": "1h", "maxValue": "1500", "maxCount": 3}
+			]
+		}
+	]
+}`
+
+var (
+	policyToken  = common.HexToAddress("0x000000000000000000000000000000000000dead")
+	policyWallet = common.HexToAddress("0x000000000000000000000000000000000000beef")
+	policyOther  = common.HexToAddress("0x000000000000000000000000000000000000cafe")
+)
+
+func policyTx(from, to common.Address, value int64, data []byte) *core.SignTxRequest {
+	var (
+		sender    = common.NewMixedcaseAddress(from)
+		recipient = common.NewMixedcaseAddress(to)
+		input     = hexutil.Bytes(data)
+	)
+	return &core.SignTxRequest{
+		Transaction: core.SendTxArgs{
+			From:     sender,
+			To:       &recipient,
+			Value:    hexutil.Big(*big.NewInt(value)),
+			Nonce:    3,
+			GasPrice: hexutil.Big(*big.NewInt(2000000)),
+			Gas:      100000,
+			Data:     &input,
+		},
+	}
+}
+
+func initPolicyEngine(t *testing.T, next core.UIClientAPI) *policyUI {
+	policy, err := ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	f, err := ioutil.TempFile("", "policy-4byte-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString(`{"095ea7b3": "approve(address,uint256)", "42966c68": "burn(uint256)", "b759f954": "approve(uint256)"}`)
+	f.Close()
+
+	db, err := fourbyte.NewFromFile(f.Name())
+	if err != nil {
+		t.Fatalf("failed to load 4byte database: %v", err)
+	}
+	return NewPolicyEvaluator(next, policy, db, storage.NewEphemeralStorage())
+}
+
+func TestParsePolicy(t *testing.T) {
+	invalid := []string{
+		`{"default": "Maybe"}`,
+		`{"transactions": [{"name": "empty"}]}`,
+		`{"transactions": [{"to": [{"address": "0x0000000000000000000000000000000000000001"}], "limits": [{"window": "0s"}]}]}`,
+		`{"transactions": [{"to": [{"address": "0x0000000000000000000000000000000000000001"}], "limits": [{"window": "day"}]}]}`,
+		`{"transaction": []}`,
+		`{"transactions": [{"to": [{"address": "0x0000000000000000000000000000000000000001", "methods": ["transfer"]}]}]}`,
+		`{"transactions": [{"to": [{"address": "0x0000000000000000000000000000000000000001", "methods": [""]}]}]}`,
+	}
+	for i, policy := range invalid {
+		if _, err := ParsePolicy([]byte(policy)); err == nil {
+			t.Errorf("invalid policy %d accepted", i)
+		}
+	}
+	policy, err := ParsePolicy([]byte(`{"transactions": [{"to": [{"address": "0x0000000000000000000000000000000000000001"}]}]}`))
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+	if policy.Default != VerdictManual {
+		t.Errorf("default verdict mismatch: have %s, want %s", policy.Default, VerdictManual)
+	}
+	if policy.Transactions[0].Name != "#0" {
+		t.Errorf("default rule name mismatch: have %s, want #0", policy.Transactions[0].Name)
+	}
+}
+
+func TestPolicyEvaluation(t *testing.T) {
+	r := initPolicyEngine(t, &dontCallMe{t})
+
+	var (
+		from     = common.HexToAddress("0x01")
+		transfer = append(common.Hex2Bytes("a9059cbb"), make([]byte, 64)...)
+		approve  = append(common.Hex2Bytes("095ea7b3"), make([]byte, 64)...)
+		burn     = append(common.Hex2Bytes("42966c68"), make([]byte, 32)...)
+		approve2 = append(common.Hex2Bytes("b759f954"), make([]byte, 32)...)
+	)
+	expensive := policyTx(from, policyToken, 0, nil)
+	expensive.Transaction.GasPrice = hexutil.Big(*big.NewInt(4000000))
+
+	tests := []struct {
+		request *core.SignTxRequest
+		verdict Verdict
+		method  string
+	}{
+		{policyTx(from, policyToken, 10, nil), VerdictApprove, ""},
+		{policyTx(from, policyToken, 0, transfer), VerdictApprove, "transfer(address,uint256)"},
+		{policyTx(from, policyToken, 0, approve), VerdictApprove, "approve(address,uint256)"},
+		{policyTx(from, policyToken, 0, burn), VerdictReject, "burn(uint256)"},
+		{policyTx(from, policyToken, 0, approve2), VerdictReject, "approve(uint256)"},
+		{policyTx(from, policyToken, 0, transfer[:40]), VerdictReject, ""},
+		{policyTx(from, policyWallet, 0, burn), VerdictApprove, "burn(uint256)"},
+		{policyTx(from, policyToken, 1001, nil), VerdictReject, ""},
+		{expensive, VerdictReject, ""},
+		{policyTx(from, policyOther, 0, nil), VerdictManual, ""},
+	}
+	for i, tt := range tests {
+		decision := r.EvaluateTx(tt.request)
+		if decision.Verdict != tt.verdict {
+			t.Errorf("test %d: verdict mismatch: have %s (%v), want %s", i, decision.Verdict, decision.Reasons, tt.verdict)
+		}
+		if decision.Method != tt.method {
+			t.Errorf("test %d: method mismatch: have %s, want %s", i, decision.Method, tt.method)
+		}
+		if decision.Verdict == VerdictApprove {
+			if resp, err := r.ApproveTx(tt.request); err != nil || !resp.Approved {
+				t.Errorf("test %d: transaction not approved: %v", i, err)
+			}
+			// Release the allowance, the transaction is never signed
+			r.OnSignTxDone(tt.request, errors.New("not signed"))
+		}
+		if decision.Verdict == VerdictReject {
+			if resp, err := r.ApproveTx(tt.request); err != nil || resp.Approved {
+				t.Errorf("test %d: transaction not rejected: %v", i, err)
+			}
+		}
+	}
+	// Listing should be approved without consulting the user
+	if _, err := r.ApproveListing(&core.ListRequest{}); err != nil {
+		t.Errorf("listing not approved: %v", err)
+	}
+}
+
+func TestPolicyLimits(t *testing.T) {
+	r := initPolicyEngine(t, &dummyUI{})
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	sign := func(value int64) {
+		t.Helper()
+		request := policyTx(from, policyToken, value, nil)
+		if resp, err := r.ApproveTx(request); err != nil || !resp.Approved {
+			t.Fatalf("value %d: transaction not approved: %v", value, err)
+		}
+		tx := types.NewTransaction(3, policyToken, big.NewInt(value), 21000, big.NewInt(2000000), nil)
+		signed, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(1)), key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		r.OnApprovedTx(ethapi.SignTransactionResult{Tx: signed})
+		r.OnSignTxDone(request, nil)
+	}
+	check := func(value int64, want Verdict) {
+		t.Helper()
+		if decision := r.EvaluateTx(policyTx(from, policyToken, value, nil)); decision.Verdict != want {
+			t.Errorf("value %d: verdict mismatch: have %s (%v), want %s", value, decision.Verdict, decision.Reasons, want)
+		}
+	}
+	// Spend up to the value limit within the window
+	check(1000, VerdictApprove)
+	sign(1000)
+	check(600, VerdictReject)
+	check(500, VerdictApprove)
+	sign(500)
+
+	// The count limit should kick in even for zero value transactions
+	check(0, VerdictApprove)
+	sign(0)
+	check(0, VerdictReject)
+
+	// Transactions should expire from the window
+	now = now.Add(time.Hour + time.Second)
+	check(1000, VerdictApprove)
+	if history := r.history(from); len(history) != 3 {
+		t.Errorf("history length mismatch: have %d, want 3", len(history))
+	}
+	sign(1000)
+	if history := r.history(from); len(history) != 1 {
+		t.Errorf("pruned history length mismatch: have %d, want 1", len(history))
+	}
+}
+
+func TestPolicyLimitsConcurrent(t *testing.T) {
+	r := initPolicyEngine(t, &dummyUI{})
+	from := common.Address{0x01}
+
+	// Approve many requests at once, only the limits' worth may pass
+	var (
+		requests = make([]*core.SignTxRequest, 10)
+		approved = make([]bool, len(requests))
+		wg       sync.WaitGroup
+	)
+	for i := range requests {
+		requests[i] = policyTx(from, policyToken, 500, nil)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := r.ApproveTx(requests[i])
+			approved[i] = err == nil && resp.Approved
+		}(i)
+	}
+	wg.Wait()
+
+	var signed []*core.SignTxRequest
+	for i, ok := range approved {
+		if ok {
+			signed = append(signed, requests[i])
+		}
+	}
+	if len(signed) != 3 {
+		t.Fatalf("approved request count mismatch: have %d, want 3", len(signed))
+	}
+	// Failing to sign should release the allowance for another request
+	r.OnSignTxDone(signed[0], errors.New("wallet locked"))
+	r.OnSignTxDone(signed[1], nil)
+	r.OnSignTxDone(signed[2], nil)
+
+	if history := r.history(from); len(history) != 2 {
+		t.Errorf("history length mismatch: have %d, want 2", len(history))
+	}
+	if len(r.reserved) != 0 {
+		t.Errorf("reservations leaked: %d", len(r.reserved))
+	}
+	if resp, err := r.ApproveTx(policyTx(from, policyToken, 500, nil)); err != nil || !resp.Approved {
+		t.Errorf("released allowance not available: %v", err)
+	}
+	if resp, err := r.ApproveTx(policyTx(from, policyToken, 500, nil)); err != nil || resp.Approved {
+		t.Errorf("request beyond limits approved: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package simulation executes transactions on a fork of a node's state before
+// they are signed, telling the user what the transaction would actually do.
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/core"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/crypto"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+	signer "github.com/Fantom-foundation/go-ethereum/signer/core"
+)
+
+// transferTopic is the topic of the Transfer event shared by ERC20 and ERC721.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Simulator implements signer.Simulator, executing transactions on a local fork of
+// the latest state of a node. Only the state touched by a transaction is fetched
+// from the node, which must be able to serve eth_getProof.
+type Simulator struct {
+	client *rpc.Client
+}
+
+// New creates a simulator forking the state of the node behind the client.
+func New(client *rpc.Client) *Simulator {
+	return &Simulator{client: client}
+}
+
+// SimulateTransaction implements signer.Simulator. The transaction is executed on
+// behalf of its sender without signing it, and the resulting ether balance
+// changes of its sender and recipient, as well as the token transfers reported
+// in its logs are returned.
+func (s *Simulator) SimulateTransaction(ctx context.Context, args *signer.SendTxArgs) (*signer.SimulationResult, error) {
+	sim, err := backends.NewForkedBackend(s.client, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer sim.Close()
+
+	from := args.From.Address()
+	tracked := []common.Address{from}
+	if args.To != nil && args.To.Address() != from {
+		tracked = append(tracked, args.To.Address())
+	}
+	before := make([]*big.Int, len(tracked))
+	for i, addr := range tracked {
+		if before[i], err = sim.BalanceAt(ctx, addr, nil); err != nil {
+			return nil, err
+		}
+	}
+	// Execute the transaction with the requested nonce, whatever the state says
+	if err := sim.SetNonce(from, uint64(args.Nonce)); err != nil {
+		return nil, err
+	}
+	tx, err := sim.Impersonate(from).Signer(types.HomesteadSigner{}, from, toTransaction(args))
+	if err != nil {
+		return nil, err
+	}
+	// The simulated backend can't include invalid transactions in a block, report
+	// those as failed simulations up front
+	if err := validateTx(ctx, sim, from, tx, before[0]); err != nil {
+		return &signer.SimulationResult{Error: err.Error()}, nil
+	}
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	sim.Commit()
+
+	receipt, err := sim.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, errors.New("simulated transaction not included")
+	}
+	res := &signer.SimulationResult{
+		Success:        receipt.Status == types.ReceiptStatusSuccessful,
+		GasUsed:        hexutil.Uint64(receipt.GasUsed),
+		BalanceChanges: make([]signer.BalanceChange, 0, len(tracked)),
+		TokenTransfers: parseTransfers(receipt.Logs),
+	}
+	if !res.Success {
+		res.Error = "execution reverted"
+	}
+	for i, addr := range tracked {
+		after, err := sim.BalanceAt(ctx, addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		if after.Cmp(before[i]) != 0 {
+			res.BalanceChanges = append(res.BalanceChanges, signer.BalanceChange{
+				Address: addr,
+				Before:  (*hexutil.Big)(before[i]),
+				After:   (*hexutil.Big)(after),
+			})
+		}
+	}
+	return res, nil
+}
+
+// validateTx checks that the transaction can be included in the next block of
+// the simulated chain, given the balance of its sender.
+func validateTx(ctx context.Context, sim *backends.SimulatedBackend, from common.Address, tx *types.Transaction, balance *big.Int) error {
+	var (
+		head   = sim.Blockchain().CurrentBlock()
+		config = sim.Blockchain().Config()
+		number = new(big.Int).Add(head.Number(), common.Big1)
+	)
+	if nonce, err := sim.PendingNonceAt(ctx, from); err != nil {
+		return err
+	} else if tx.Nonce() != nonce {
+		return fmt.Errorf("invalid nonce: have %d, want %d", tx.Nonce(), nonce)
+	}
+	if balance.Cmp(tx.Cost()) < 0 {
+		return core.ErrInsufficientFunds
+	}
+	if tx.Gas() > head.GasLimit() {
+		return core.ErrGasLimitReached
+	}
+	intrinsic, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, config.IsHomestead(number), config.IsIstanbul(number))
+	if err != nil {
+		return err
+	}
+	if tx.Gas() < intrinsic {
+		return core.ErrIntrinsicGas
+	}
+	return nil
+}
+
+// parseTransfers extracts the ERC20 and ERC721 transfers from the logs. The two
+// standards share the event signature, but ERC721 indexes the token id.
+func parseTransfers(logs []*types.Log) []signer.TokenTransfer {
+	transfers := make([]signer.TokenTransfer, 0)
+	for _, log := range logs {
+		if len(log.Topics) < 3 || log.Topics[0] != transferTopic {
+			continue
+		}
+		transfer := signer.TokenTransfer{
+			Token: log.Address,
+			From:  common.BytesToAddress(log.Topics[1].Bytes()),
+			To:    common.BytesToAddress(log.Topics[2].Bytes()),
+		}
+		switch {
+		case len(log.Topics) == 3 && len(log.Data) == 32:
+			transfer.Value = (*hexutil.Big)(new(big.Int).SetBytes(log.Data))
+		case len(log.Topics) == 4 && len(log.Data) == 0:
+			transfer.TokenID = (*hexutil.Big)(log.Topics[3].Big())
+		default:
+			continue
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// toTransaction converts the signing request into an unsigned transaction.
+func toTransaction(args *signer.SendTxArgs) *types.Transaction {
+	var input []byte
+	if args.Data != nil {
+		input = *args.Data
+	} else if args.Input != nil {
+		input = *args.Input
+	}
+	if args.To == nil {
+		return types.NewContractCreation(uint64(args.Nonce), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), input)
+	}
+	return types.NewTransaction(uint64(args.Nonce), args.To.Address(), args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), input)
+}
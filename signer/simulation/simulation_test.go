@@ -0,0 +1,221 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/go-ethereum/accounts/abi/bind/backends"
+	"github.com/Fantom-foundation/go-ethereum/common"
+	"github.com/Fantom-foundation/go-ethereum/common/hexutil"
+	"github.com/Fantom-foundation/go-ethereum/core"
+	"github.com/Fantom-foundation/go-ethereum/core/types"
+	"github.com/Fantom-foundation/go-ethereum/params"
+	"github.com/Fantom-foundation/go-ethereum/rpc"
+	signer "github.com/Fantom-foundation/go-ethereum/signer/core"
+)
+
+// nodeService serves the state of a simulated backend over RPC, standing in for
+// the node transactions are simulated on.
+type nodeService struct {
+	sim *backends.SimulatedBackend
+}
+
+type StorageResult struct {
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+type AccountResult struct {
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+func toHexBytes(nodes [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, len(nodes))
+	for i, node := range nodes {
+		res[i] = node
+	}
+	return res
+}
+
+func (s *nodeService) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	return s.sim.Blockchain().CurrentHeader(), nil
+}
+
+func (s *nodeService) GetProof(addr common.Address, keys []common.Hash, number string) (*AccountResult, error) {
+	statedb, err := s.sim.Blockchain().State()
+	if err != nil {
+		return nil, err
+	}
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		return nil, err
+	}
+	res := &AccountResult{AccountProof: toHexBytes(proof)}
+	for _, key := range keys {
+		proof, err := statedb.GetStorageProof(addr, key)
+		if err != nil {
+			return nil, err
+		}
+		res.StorageProof = append(res.StorageProof, StorageResult{Proof: toHexBytes(proof)})
+	}
+	return res, nil
+}
+
+func (s *nodeService) GetCode(addr common.Address, number string) (hexutil.Bytes, error) {
+	statedb, err := s.sim.Blockchain().State()
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(addr), nil
+}
+
+var (
+	sender    = common.HexToAddress("0x1000")
+	recipient = common.HexToAddress("0x2000")
+	token     = common.HexToAddress("0x3000")
+	reverter  = common.HexToAddress("0x4000")
+
+	// tokenCode emits Transfer(caller, calldata[4:36], calldata[36:68]) like
+	// an ERC20 transfer(address,uint256) call.
+	tokenCode = common.FromHex("0x60206024600037600435337f" + transferTopic.Hex()[2:] + "60206000a300")
+
+	// revertCode reverts any call.
+	revertCode = common.FromHex("0x60006000fd")
+)
+
+// newSimulator creates a simulator on top of a node holding the test state.
+func newSimulator(t *testing.T) (*Simulator, func()) {
+	node := backends.NewSimulatedBackend(core.GenesisAlloc{
+		sender:   {Balance: big.NewInt(params.Ether), Nonce: 3},
+		token:    {Balance: new(big.Int), Code: tokenCode},
+		reverter: {Balance: new(big.Int), Code: revertCode},
+	}, 8000000)
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &nodeService{sim: node}); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	return New(client), func() {
+		client.Close()
+		server.Stop()
+		node.Close()
+	}
+}
+
+func mkTx(to common.Address, value int64, data []byte) *signer.SendTxArgs {
+	recipient := common.NewMixedcaseAddress(to)
+	input := hexutil.Bytes(data)
+	return &signer.SendTxArgs{
+		From:     common.NewMixedcaseAddress(sender),
+		To:       &recipient,
+		Gas:      100000,
+		GasPrice: hexutil.Big(*big.NewInt(1)),
+		Value:    hexutil.Big(*big.NewInt(value)),
+		Nonce:    3,
+		Data:     &input,
+	}
+}
+
+func TestSimulateTransfer(t *testing.T) {
+	simulator, stop := newSimulator(t)
+	defer stop()
+
+	res, err := simulator.SimulateTransaction(context.Background(), mkTx(recipient, 1000, nil))
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if !res.Success || res.GasUsed != 21000 {
+		t.Fatalf("outcome mismatch: %+v", res)
+	}
+	if len(res.BalanceChanges) != 2 {
+		t.Fatalf("balance changes mismatch: %+v", res.BalanceChanges)
+	}
+	spent := new(big.Int).Sub(res.BalanceChanges[0].Before.ToInt(), res.BalanceChanges[0].After.ToInt())
+	if res.BalanceChanges[0].Address != sender || spent.Cmp(big.NewInt(1000+21000)) != 0 {
+		t.Errorf("sender change mismatch: %v spent %v", res.BalanceChanges[0].Address.Hex(), spent)
+	}
+	if change := res.BalanceChanges[1]; change.Address != recipient || change.Before.ToInt().Sign() != 0 || change.After.ToInt().Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("recipient change mismatch: %+v", change)
+	}
+	if len(res.TokenTransfers) != 0 {
+		t.Errorf("unexpected token transfers: %+v", res.TokenTransfers)
+	}
+}
+
+func TestSimulateTokenTransfer(t *testing.T) {
+	simulator, stop := newSimulator(t)
+	defer stop()
+
+	calldata := append(common.FromHex("0xa9059cbb"), common.LeftPadBytes(recipient.Bytes(), 32)...)
+	calldata = append(calldata, common.LeftPadBytes(big.NewInt(4242).Bytes(), 32)...)
+
+	res, err := simulator.SimulateTransaction(context.Background(), mkTx(token, 0, calldata))
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if !res.Success {
+		t.Fatalf("transaction failed: %v", res.Error)
+	}
+	if len(res.TokenTransfers) != 1 {
+		t.Fatalf("token transfers mismatch: %+v", res.TokenTransfers)
+	}
+	transfer := res.TokenTransfers[0]
+	if transfer.Token != token || transfer.From != sender || transfer.To != recipient || transfer.Value.ToInt().Int64() != 4242 || transfer.TokenID != nil {
+		t.Errorf("token transfer mismatch: %+v", transfer)
+	}
+}
+
+func TestSimulateRevert(t *testing.T) {
+	simulator, stop := newSimulator(t)
+	defer stop()
+
+	res, err := simulator.SimulateTransaction(context.Background(), mkTx(reverter, 0, nil))
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if res.Success || res.Error == "" {
+		t.Fatalf("revert not reported: %+v", res)
+	}
+	// Transactions which can't even be included are reported as failures too
+	poor := mkTx(recipient, 2*params.Ether, nil)
+	cheap := mkTx(recipient, 0, nil)
+	cheap.Gas = 20000
+	greedy := mkTx(recipient, 0, nil)
+	greedy.Gas = 10000000
+
+	tests := []struct {
+		tx  *signer.SendTxArgs
+		err error
+	}{
+		{poor, core.ErrInsufficientFunds},
+		{cheap, core.ErrIntrinsicGas},
+		{greedy, core.ErrGasLimitReached},
+	}
+	for i, tt := range tests {
+		res, err := simulator.SimulateTransaction(context.Background(), tt.tx)
+		if err != nil {
+			t.Fatalf("test %d: simulation failed: %v", i, err)
+		}
+		if res.Success || res.Error != tt.err.Error() {
+			t.Errorf("test %d: invalid transaction not reported: have %+v, want %v", i, res, tt.err)
+		}
+	}
+}